@@ -0,0 +1,85 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BitwardenProjectStatus describes a Secrets Manager project that the machine account of a BitwardenSecret in the
+// namespace can access
+type BitwardenProjectStatus struct {
+	// The ID of the project, to be used in spec.projects of a BitwardenSecret
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	ProjectId string `json:"projectId"`
+
+	// The name of the project
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	ProjectName string `json:"projectName"`
+
+	// The ID of the organization owning the project
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	OrganizationId string `json:"organizationId"`
+
+	// When the project was created in Secrets Manager
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	CreationDate *metav1.Time `json:"creationDate,omitempty"`
+
+	// When the project was last changed in Secrets Manager
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	RevisionDate *metav1.Time `json:"revisionDate,omitempty"`
+
+	// The names of the BitwardenSecrets of the namespace whose machine account can access the project
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	BitwardenSecrets []string `json:"bitwardenSecrets,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Project",type=string,JSONPath=`.status.projectName`
+//+kubebuilder:printcolumn:name="Project ID",type=string,JSONPath=`.status.projectId`
+//+kubebuilder:printcolumn:name="Organization",type=string,JSONPath=`.status.organizationId`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BitwardenProject is the Schema for the bitwardenprojects API.  BitwardenProjects are maintained by the operator
+// and mirror the Secrets Manager projects that the machine accounts of the namespace can access.
+type BitwardenProject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status BitwardenProjectStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BitwardenProjectList contains a list of BitwardenProject
+type BitwardenProjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BitwardenProject `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BitwardenProject{}, &BitwardenProjectList{})
+}
@@ -22,6 +22,7 @@ Trademark Guidelines
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -45,15 +46,418 @@ type BitwardenSecretSpec struct {
 	// The secret key reference for the authorization token used to connect to Secrets Manager
 	// +kubebuilder:Required
 	AuthToken AuthToken `json:"authToken"`
+	// Write every change to a new immutable Kubernetes secret instead of updating secretName in place.  The secret
+	// named secretName then becomes a stable alias that holds the name of the active version.
+	// +kubebuilder:Optional
+	Versioning *SecretVersioning `json:"versioning,omitempty"`
+	// Keep snapshots of the previous data of the Kubernetes secret, named secretName-v1, secretName-v2, and so on, that
+	// the secret can be rolled back to with the k8s.bitwarden.com/rollback-to annotation.  Not used with versioning,
+	// whose previous versions serve the same purpose.
+	// +kubebuilder:Optional
+	History *SecretHistory `json:"history,omitempty"`
+	// Assemble a kubeconfig from Secrets Manager secrets holding the connection details of a cluster and write it to
+	// the Kubernetes secret in addition to the mapped keys
+	// +kubebuilder:Optional
+	Kubeconfig *KubeconfigTemplate `json:"kubeconfig,omitempty"`
+	// The name of the ConfigMap that map entries classified as non-sensitive are written to.  Defaults to secretName.
+	// +kubebuilder:Optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// Restrict the times at which changes may be applied to the Kubernetes secret, for applications that only tolerate
+	// credential changes off-peak.  Changes found outside of the window are applied once it opens.
+	// +kubebuilder:Optional
+	SyncWindow *SyncWindow `json:"syncWindow,omitempty"`
+	// How often this BitwardenSecret is synced, for example 30s for rotating database credentials or 1h for static API
+	// keys.  Defaults to the operator refresh interval.  Intervals below 30s are raised to 30s.
+	// +kubebuilder:Optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+	// How long logging in to Secrets Manager and pulling the secrets may take each before the sync is abandoned and
+	// retried, so that a hung call does not block syncing.  Defaults to the operator SDK timeout.
+	// +kubebuilder:Optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// The IDs or names of the projects to sync secrets from.  Defaults to every secret the machine account can access.
+	// +kubebuilder:Optional
+	Projects []string `json:"projects,omitempty"`
+	// Compose keys of the Kubernetes secret, such as connection strings or config snippets, from the pulled values
+	// +kubebuilder:Optional
+	Template *SecretTemplate `json:"template,omitempty"`
+	// The type of the Kubernetes secret, either a built-in type such as kubernetes.io/basic-auth or a custom type such
+	// as example.com/credentials.  The data of the built-in types is checked for the keys they require before it is
+	// written.  Changing the type replaces the secret, since the type of an existing secret cannot be changed.
+	// +kubebuilder:Optional
+	// +kubebuilder:default=Opaque
+	SecretType corev1.SecretType `json:"secretType,omitempty"`
+	// Assemble a .dockerconfigjson from Secrets Manager secrets holding registry credentials, for use as an image pull
+	// secret
+	// +kubebuilder:Optional
+	DockerConfig *DockerConfigTemplate `json:"dockerConfig,omitempty"`
+	// Populate tls.crt, tls.key, and optionally ca.crt from Secrets Manager secrets holding PEM encoded certificates
+	// and keys
+	// +kubebuilder:Optional
+	TLS *TLSTemplate `json:"tls,omitempty"`
+	// Populate username and password from Secrets Manager secrets, for ingress controllers and sidecars that expect a
+	// kubernetes.io/basic-auth secret.  Requires spec.secretType kubernetes.io/basic-auth.
+	// +kubebuilder:Optional
+	BasicAuth *BasicAuthTemplate `json:"basicAuth,omitempty"`
+	// Populate ssh-privatekey, and identity and known_hosts as read by Flux, from Secrets Manager secrets holding an SSH
+	// private key, for git clones.  Requires spec.secretType kubernetes.io/ssh-auth.
+	// +kubebuilder:Optional
+	SSHAuth *SSHAuthTemplate `json:"sshAuth,omitempty"`
+	// Pull and render the secrets as a sync would, but write nothing.  The names of the keys a sync would create,
+	// update, and remove are published in status.dryRun instead, to preview the changes before enabling the sync.
+	// +kubebuilder:Optional
+	DryRun bool `json:"dryRun,omitempty"`
+	// Suspend syncing, for example during maintenance or an incident.  The Kubernetes secret is left as it is until
+	// the BitwardenSecret is resumed.
+	// +kubebuilder:Optional
+	Paused bool `json:"paused,omitempty"`
+	// Mark the Kubernetes secret immutable, which protects it from accidental changes and reduces the load of
+	// watching it on the API server.  Changes from Secrets Manager replace the secret instead of updating it.
+	// +kubebuilder:Optional
+	Immutable bool `json:"immutable,omitempty"`
+	// Labels and annotations written to the Kubernetes secret on every sync, for tooling that selects secrets by
+	// their metadata
+	// +kubebuilder:Optional
+	SecretMetadata *SecretMetadata `json:"secretMetadata,omitempty"`
+	// Annotate the Kubernetes secret with non-sensitive metadata of the secrets its keys come from: the organization
+	// ID, and the Secrets Manager keys and project names of the secrets, to trace the keys back to Secrets Manager
+	// during audits
+	// +kubebuilder:Optional
+	SourceMetadata bool `json:"sourceMetadata,omitempty"`
+	// The Bitwarden API URL of the server holding the secrets, for example a self-hosted server when the operator
+	// syncs from Bitwarden cloud.  Must be set together with identityUrl.  Defaults to the operator API URL.
+	// +kubebuilder:Optional
+	ApiUrl string `json:"apiUrl,omitempty"`
+	// The Bitwarden identity URL of the server holding the secrets.  Must be set together with apiUrl.  Defaults to
+	// the operator identity URL.
+	// +kubebuilder:Optional
+	IdentityUrl string `json:"identityUrl,omitempty"`
+	// A Kubernetes secret in the namespace of the BitwardenSecret holding the PEM encoded CA bundle that issued the
+	// certificate of a self-hosted server.  Requires the REST client backend; use the --ca-bundle flag of the
+	// operator with the native SDK.
+	// +kubebuilder:Optional
+	CABundleSecretRef *CABundleSecretRef `json:"caBundleSecretRef,omitempty"`
+	// Add a prefix or suffix to every key written from Secrets Manager values, mapped or not, for example DB_
+	// +kubebuilder:Optional
+	KeyTransform *KeyTransform `json:"keyTransform,omitempty"`
+	// Only sync the secrets whose key, the secret name in Secrets Manager, passes the filter, so that a machine
+	// account with broad access can still produce a narrowly scoped Kubernetes secret
+	// +kubebuilder:Optional
+	Filter *SecretFilter `json:"filter,omitempty"`
+	// Render the secrets, mapped or not, into a single key in dotenv or Java properties format instead of one key
+	// per secret, for applications that read one environment file
+	// +kubebuilder:Optional
+	Output *SecretOutput `json:"output,omitempty"`
+	// Additional Kubernetes secrets or ConfigMaps written from the same pull, each shaped by its own type and map, for
+	// example an image pull secret next to the application secret.  Targets removed from the list are deleted.
+	// +kubebuilder:Optional
+	Targets []SecretTarget `json:"targets,omitempty"`
+	// Owner writes the whole Kubernetes secret and owns it, so it is deleted along with the BitwardenSecret.  Merge
+	// only writes the keys synced from Secrets Manager into the secret, which may already exist and be maintained by
+	// another tool, and only removes keys it wrote itself.  Merge cannot be combined with versioning.
+	// +kubebuilder:Optional
+	// +kubebuilder:default=Owner
+	// +kubebuilder:validation:Enum=Owner;Merge
+	CreationPolicy CreationPolicy `json:"creationPolicy,omitempty"`
+	// Delete removes the Kubernetes secrets and ConfigMap written by the BitwardenSecret along with it.  Retain keeps
+	// them, for example while moving secrets to another BitwardenSecret or tool.
+	// +kubebuilder:Optional
+	// +kubebuilder:default=Delete
+	// +kubebuilder:validation:Enum=Retain;Delete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Whether the Kubernetes secret gets an owner reference to the BitwardenSecret.  Set to false to provision a
+	// standalone secret that is not garbage collected with the BitwardenSecret, which requires deletionPolicy Retain.
+	// +kubebuilder:Optional
+	// +kubebuilder:default=true
+	OwnerReference *bool `json:"ownerReference,omitempty"`
+	// Strict fails the whole sync when a mapped secret cannot be read or decoded or a template fails to render.
+	// Partial still writes the healthy keys, keeps the previous values of the failed ones, and lists them in
+	// status.failedKeys.
+	// +kubebuilder:Optional
+	// +kubebuilder:default=Strict
+	// +kubebuilder:validation:Enum=Strict;Partial
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+	// Restarts the Deployments and StatefulSets using the Kubernetes secret after a sync changed its data, so that
+	// their pods pick up the new values right away
+	// +kubebuilder:Optional
+	RolloutRestart *RolloutRestart `json:"rolloutRestart,omitempty"`
 }
 
-type AuthToken struct {
-	// The name of the Kubernetes secret where the authorization token is stored
+type RolloutRestart struct {
+	// Whether workloads are restarted
+	// +kubebuilder:Optional
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// DeletionPolicy selects what happens to the written secrets when the BitwardenSecret is deleted
+type DeletionPolicy string
+
+const (
+	// The secrets are kept
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// The secrets are garbage collected
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+)
+
+// FailurePolicy selects what happens when some keys of the Kubernetes secret fail to render
+type FailurePolicy string
+
+const (
+	// The sync fails and nothing is written
+	FailurePolicyStrict FailurePolicy = "Strict"
+	// The healthy keys are written and the failed ones keep their previous values
+	FailurePolicyPartial FailurePolicy = "Partial"
+)
+
+// CreationPolicy selects how the operator writes to the Kubernetes secret
+type CreationPolicy string
+
+const (
+	// The operator owns the secret and writes all of its data
+	CreationPolicyOwner CreationPolicy = "Owner"
+	// The operator merges the keys it manages into the data of the secret
+	CreationPolicyMerge CreationPolicy = "Merge"
+)
+
+type SecretTarget struct {
+	// The name of the Kubernetes secret, or of the ConfigMap for ConfigMap targets.  Must differ from secretName and
+	// from the other targets of the same kind.
 	// +kubebuilder:Required
 	SecretName string `json:"secretName"`
-	// The key of the Kubernetes secret where the authorization token is stored
+	// The kind of object written.  ConfigMap targets hold values that are not secret, such as feature flags or
+	// endpoints, in plain text readable by everyone allowed to read the ConfigMaps of the namespace.
+	// +kubebuilder:Optional
+	// +kubebuilder:default=Secret
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind TargetKind `json:"kind,omitempty"`
+	// The type of the Kubernetes secret, a built-in or a custom type.  ConfigMap targets only accept Opaque.
+	// +kubebuilder:Optional
+	// +kubebuilder:default=Opaque
+	SecretType corev1.SecretType `json:"secretType,omitempty"`
+	// The mapping of secret IDs to keys of this secret.  Defaults to every pulled secret keyed by its ID.
+	// +kubebuilder:Optional
+	SecretMap []SecretMap `json:"map,omitempty"`
+	// The cluster the target is written to, for operators in a management cluster pushing secrets into workload
+	// clusters.  Defaults to the cluster of the BitwardenSecret.
+	// +kubebuilder:Optional
+	ClusterRef *ClusterRef `json:"clusterRef,omitempty"`
+}
+
+// ClusterRef references the kubeconfig of another cluster
+type ClusterRef struct {
+	// The name of the secret in the namespace of the BitwardenSecret holding the kubeconfig.  Credentials and
+	// certificates must be embedded: kubeconfigs reading files or running commands are rejected.
+	// +kubebuilder:Required
+	SecretName string `json:"secretName"`
+	// The key of the kubeconfig in the secret
+	// +kubebuilder:Optional
+	// +kubebuilder:default=kubeconfig
+	Key string `json:"key,omitempty"`
+	// The namespace of the cluster the target is written to.  Defaults to the namespace of the BitwardenSecret.
+	// +kubebuilder:Optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// TargetKind is the kind of object a target of a BitwardenSecret writes
+type TargetKind string
+
+const (
+	// The target is written to a Kubernetes secret
+	TargetKindSecret TargetKind = "Secret"
+	// The target is written to a ConfigMap
+	TargetKindConfigMap TargetKind = "ConfigMap"
+)
+
+type SecretOutput struct {
+	// The format of the rendered file
+	// +kubebuilder:Required
+	// +kubebuilder:validation:Enum=dotenv;properties
+	Format OutputFormat `json:"format"`
+	// The key the file is written to.  Defaults to .env for dotenv and application.properties for properties.
+	// +kubebuilder:Optional
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	Key string `json:"key,omitempty"`
+}
+
+// OutputFormat is the format secrets are rendered in by spec.output
+type OutputFormat string
+
+const (
+	// KEY="value" lines, as read by docker compose and most dotenv libraries
+	OutputFormatDotenv OutputFormat = "dotenv"
+	// key=value lines escaped for java.util.Properties
+	OutputFormatProperties OutputFormat = "properties"
+)
+
+type SecretFilter struct {
+	// Keep only the secrets whose key matches this regular expression
+	// +kubebuilder:Optional
+	IncludeRegex string `json:"includeRegex,omitempty"`
+	// Leave out the secrets whose key matches this regular expression, even if they match includeRegex
+	// +kubebuilder:Optional
+	ExcludeRegex string `json:"excludeRegex,omitempty"`
+}
+
+type KeyTransform struct {
+	// Prepended to every key
+	// +kubebuilder:Optional
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]*$`
+	Prefix string `json:"prefix,omitempty"`
+	// Appended to every key
+	// +kubebuilder:Optional
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]*$`
+	Suffix string `json:"suffix,omitempty"`
+}
+
+type CABundleSecretRef struct {
+	// The name of the Kubernetes secret holding the CA bundle
+	// +kubebuilder:Required
+	Name string `json:"name"`
+	// The key holding the CA bundle.  Defaults to ca.crt.
+	// +kubebuilder:Optional
+	Key string `json:"key,omitempty"`
+}
+
+type SecretMetadata struct {
+	// Labels added to the Kubernetes secret
+	// +kubebuilder:Optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations added to the Kubernetes secret
+	// +kubebuilder:Optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type TLSTemplate struct {
+	// The ID of the secret in Secrets Manager holding the PEM encoded certificate, followed by any intermediates
+	// +kubebuilder:Required
+	CertificateSecretId string `json:"certificateSecretId"`
+	// The ID of the secret in Secrets Manager holding the PEM encoded private key of the certificate
+	// +kubebuilder:Required
+	PrivateKeySecretId string `json:"privateKeySecretId"`
+	// The ID of the secret in Secrets Manager holding the PEM encoded certificate authority written to ca.crt
+	// +kubebuilder:Optional
+	CertificateAuthoritySecretId string `json:"certificateAuthoritySecretId,omitempty"`
+}
+
+type BasicAuthTemplate struct {
+	// The ID of the secret in Secrets Manager holding the username
+	// +kubebuilder:Required
+	UsernameSecretId string `json:"usernameSecretId"`
+	// The ID of the secret in Secrets Manager holding the password
+	// +kubebuilder:Required
+	PasswordSecretId string `json:"passwordSecretId"`
+}
+
+type SSHAuthTemplate struct {
+	// The ID of the secret in Secrets Manager holding the unencrypted SSH private key
+	// +kubebuilder:Required
+	PrivateKeySecretId string `json:"privateKeySecretId"`
+	// The ID of the secret in Secrets Manager holding the known_hosts entries of the SSH servers
+	// +kubebuilder:Optional
+	KnownHostsSecretId string `json:"knownHostsSecretId,omitempty"`
+}
+
+type DockerConfigTemplate struct {
+	// The registry server, for example ghcr.io.  Either registry or registrySecretId must be set.
+	// +kubebuilder:Optional
+	Registry string `json:"registry,omitempty"`
+	// The ID of the secret in Secrets Manager holding the registry server
+	// +kubebuilder:Optional
+	RegistrySecretId string `json:"registrySecretId,omitempty"`
+	// The ID of the secret in Secrets Manager holding the registry username
+	// +kubebuilder:Required
+	UsernameSecretId string `json:"usernameSecretId"`
+	// The ID of the secret in Secrets Manager holding the registry password or access token
+	// +kubebuilder:Required
+	PasswordSecretId string `json:"passwordSecretId"`
+	// The ID of the secret in Secrets Manager holding the email address of the registry account
+	// +kubebuilder:Optional
+	EmailSecretId string `json:"emailSecretId,omitempty"`
+}
+
+type SecretTemplate struct {
+	// Go templates rendered into the key of the same name.  A template reads pulled values by secret ID with
+	// {{ secret "<secret ID>" }} and keys written by the map with {{ .Data.KEY_NAME }}.
+	// +kubebuilder:Optional
+	Data map[string]string `json:"data,omitempty"`
+}
+
+type SyncWindow struct {
+	// The time of day the window opens, as HH:MM
+	// +kubebuilder:Required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// The time of day the window closes, as HH:MM.  A window ending before it starts spans midnight.
 	// +kubebuilder:Required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+	// The IANA time zone of start and end, for example Europe/Berlin
+	// +kubebuilder:Optional
+	// +kubebuilder:default=UTC
+	TimeZone string `json:"timeZone,omitempty"`
+	// The days of the week the window opens on.  The window opens every day when empty.
+	// +kubebuilder:Optional
+	// +kubebuilder:validation:items:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+	Days []string `json:"days,omitempty"`
+}
+
+type SecretVersioning struct {
+	// The number of previous versions to keep in addition to the active one
+	// +kubebuilder:Optional
+	// +kubebuilder:default=2
+	// +kubebuilder:validation:Minimum=0
+	Keep int `json:"keep,omitempty"`
+}
+
+type SecretHistory struct {
+	// The number of snapshots of previous data to keep
+	// +kubebuilder:Optional
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	Count int `json:"count,omitempty"`
+}
+
+type KubeconfigTemplate struct {
+	// The key of the Kubernetes secret the kubeconfig is written to
+	// +kubebuilder:Optional
+	// +kubebuilder:default=kubeconfig
+	Key string `json:"key,omitempty"`
+	// The name of the cluster, user, and context entries in the kubeconfig
+	// +kubebuilder:Optional
+	// +kubebuilder:default=default
+	Name string `json:"name,omitempty"`
+	// The ID of the secret in Secrets Manager holding the URL of the cluster's API server
+	// +kubebuilder:Required
+	ServerSecretId string `json:"serverSecretId"`
+	// The ID of the secret in Secrets Manager holding the PEM encoded certificate authority of the cluster.  When
+	// omitted the system trust store is used.
+	// +kubebuilder:Optional
+	CertificateAuthoritySecretId string `json:"certificateAuthoritySecretId,omitempty"`
+	// The ID of the secret in Secrets Manager holding the bearer token used to authenticate to the cluster
+	// +kubebuilder:Required
+	TokenSecretId string `json:"tokenSecretId"`
+	// The default namespace of the context
+	// +kubebuilder:Optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type AuthToken struct {
+	// The name of the Kubernetes secret where the authorization token is stored.  When neither secretName nor filePath
+	// is set, the token file of the operator is used.
+	// +kubebuilder:Optional
+	SecretName string `json:"secretName,omitempty"`
+	// The namespace of the Kubernetes secret where the authorization token is stored.  Defaults to the namespace of
+	// the BitwardenSecret.  Secrets of other namespaces can only be used when a BitwardenTokenGrant in that namespace
+	// allows it.
+	// +kubebuilder:Optional
+	Namespace string `json:"namespace,omitempty"`
+	// The key of the Kubernetes secret where the authorization token is stored
+	// +kubebuilder:Optional
+	// +kubebuilder:default=token
 	SecretKey string `json:"secretKey"`
+	// The path of a file holding the authorization token, relative to the token directory mounted into the operator
+	// pod with --auth-token-dir
+	// +kubebuilder:Optional
+	FilePath string `json:"filePath,omitempty"`
 }
 
 type SecretMap struct {
@@ -63,6 +467,71 @@ type SecretMap struct {
 	// The name of the mapped key in the created Kubernetes secret
 	// +kubebuilder:Required
 	SecretKeyName string `json:"secretKeyName"`
+	// Which field of the secret is written to the key: its value, or its note, which often holds auxiliary
+	// configuration.  Defaults to Value.
+	// +kubebuilder:Optional
+	// +kubebuilder:validation:Enum=Value;Note
+	Source SecretSource `json:"source,omitempty"`
+	// Additional keys the same value is written to, for example when several consumers expect different names
+	// +kubebuilder:Optional
+	Aliases []string `json:"aliases,omitempty"`
+	// A JSONPath expression, such as user or db.hosts[0], selecting the value of the key from a secret holding JSON,
+	// so that one secret can be split into several keys
+	// +kubebuilder:Optional
+	Property string `json:"property,omitempty"`
+	// How the value is decoded before it is written to the key.  Base64 decodes binary content, such as keystores or
+	// PKCS#12 bundles, stored base64 encoded in Secrets Manager.
+	// +kubebuilder:Optional
+	// +kubebuilder:validation:Enum=None;Base64
+	DecodingStrategy DecodingStrategy `json:"decodingStrategy,omitempty"`
+	// Whether the value is sensitive.  Non-sensitive values are written to a ConfigMap next to the Kubernetes secret
+	// instead of the secret itself, keeping plain configuration out of Secret objects.
+	// +kubebuilder:Optional
+	// +kubebuilder:default=true
+	Sensitive *bool `json:"sensitive,omitempty"`
+	// The revision date of the secret, as recorded in the k8s.bitwarden.com/revisions annotation, that the keys are
+	// pinned to.  Newer revisions are not written until the pin is changed, and the PinnedRevisionUnavailable condition
+	// is set when neither Secrets Manager nor the Kubernetes secret holds the pinned revision.  Pins are not applied by
+	// ClusterBitwardenSecrets.
+	// +kubebuilder:Optional
+	Revision string `json:"revision,omitempty"`
+}
+
+// DecodingStrategy selects how a secret value is decoded before it is written to the Kubernetes secret
+type DecodingStrategy string
+
+const (
+	// The value is written as it is
+	DecodingStrategyNone DecodingStrategy = "None"
+	// The value is base64 decoded, with or without padding
+	DecodingStrategyBase64 DecodingStrategy = "Base64"
+)
+
+// SecretSource selects the field of a Secrets Manager secret that a map entry writes
+type SecretSource string
+
+const (
+	// The value of the secret is written
+	SecretSourceValue SecretSource = "Value"
+	// The note of the secret is written
+	SecretSourceNote SecretSource = "Note"
+)
+
+// ClusterTargetStatus reports the targets written to another cluster
+type ClusterTargetStatus struct {
+	// The kubeconfig reference of the cluster
+	ClusterRef ClusterRef `json:"clusterRef"`
+	// The names of the targets written to the cluster
+	// +optional
+	Targets []string `json:"targets,omitempty"`
+	// Whether the last sync reached the cluster and wrote its targets
+	Connected bool `json:"connected"`
+	// Why the last sync failed to write the targets to the cluster
+	// +optional
+	Message string `json:"message,omitempty"`
+	// When the targets were last written to the cluster
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
 }
 
 // BitwardenSecretStatus defines the observed state of BitwardenSecret
@@ -77,10 +546,153 @@ type BitwardenSecretStatus struct {
 	// Conditions store the status conditions of the BitwardenSecret instances
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// A short explanation of the decision made by the last reconcile, such as why a secret was not updated or why a
+	// Secrets Manager secret is missing from the Kubernetes secret
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	LastSyncTrace string `json:"lastSyncTrace,omitempty"`
+
+	// The clusters referenced by the clusterRef of targets, and whether the last sync reached them
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Clusters []ClusterTargetStatus `json:"clusters,omitempty"`
+
+	// The resourceVersion of the Kubernetes secret after the operator last wrote it.  A different resourceVersion
+	// means the secret was modified outside of the operator.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	SecretResourceVersion string `json:"secretResourceVersion,omitempty"`
+
+	// The UID of the Kubernetes secret the operator last wrote
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	SecretUID string `json:"secretUID,omitempty"`
+
+	// The resourceVersion of the authorization token secret read by the last sync attempt.  A BitwardenSecret stalled
+	// by a permanent error is synced again right away once the secret changes.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	AuthTokenResourceVersion string `json:"authTokenResourceVersion,omitempty"`
+
+	// The name of the Kubernetes secret the operator last wrote.  When spec.secretName changes, the secret of the
+	// previous name is cleaned up according to the creation and deletion policies.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// The name of the active versioned Kubernetes secret when spec.versioning is set
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	CurrentVersion string `json:"currentVersion,omitempty"`
+
+	// The generation of the BitwardenSecret last written to the Kubernetes secret.  A newer generation, for example
+	// after a map entry was removed, is written with a full sync so that keys no longer in the spec are pruned.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The map last written to the Kubernetes secret
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	AppliedSecretMap []SecretMap `json:"appliedMap,omitempty"`
+
+	// The SHA-256 hash of the applied map, to tell at a glance whether two BitwardenSecrets apply the same map
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	AppliedSecretMapHash string `json:"appliedMapHash,omitempty"`
+
+	// The value of the k8s.bitwarden.com/force-sync annotation handled by the last successful sync
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	LastForceSync string `json:"lastForceSync,omitempty"`
+
+	// The number of secrets left out by spec.filter in the last sync that wrote the Kubernetes secret
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	FilteredSecrets int `json:"filteredSecrets,omitempty"`
+
+	// The number of keys the last successful sync wrote to the Kubernetes secret, not counting keys split off into
+	// the ConfigMap or kept from other tools by the Merge creation policy
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	SyncedKeyCount int `json:"syncedKeyCount,omitempty"`
+
+	// The sorted names of the keys the last successful sync wrote to the Kubernetes secret, counted by syncedKeyCount.
+	// Values are never published.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	SyncedKeys []string `json:"syncedKeys,omitempty"`
+
+	// The error of the last sync attempt, truncated, or empty if it did not fail
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// The most recent sync attempts, newest last, so that intermittent failures stay visible after a successful sync
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	History []SyncAttempt `json:"history,omitempty"`
+
+	// The keys that failed to render in the last sync with the Partial failure policy, sorted by key
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	FailedKeys []KeyFailure `json:"failedKeys,omitempty"`
+
+	// The changes the last dry run would have made to the Kubernetes secret while spec.dryRun is set
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	DryRun *DryRunSummary `json:"dryRun,omitempty"`
+}
+
+// KeyFailure records why a key of the Kubernetes secret failed to render
+type KeyFailure struct {
+	// The key of the Kubernetes secret
+	Key string `json:"key"`
+	// Why the key failed to render
+	Reason string `json:"reason"`
+}
+
+// DryRunSummary lists the keys a sync would change.  Only key names are published, never values.
+type DryRunSummary struct {
+	// When the dry run finished
+	Time metav1.Time `json:"time"`
+	// The keys a sync would add to the Kubernetes secret
+	// +optional
+	KeysCreated []string `json:"keysCreated,omitempty"`
+	// The keys whose values a sync would change
+	// +optional
+	KeysUpdated []string `json:"keysUpdated,omitempty"`
+	// The keys a sync would remove from the Kubernetes secret
+	// +optional
+	KeysRemoved []string `json:"keysRemoved,omitempty"`
+	// The keys that would fail to render with the Partial failure policy
+	// +optional
+	KeysFailed []string `json:"keysFailed,omitempty"`
+}
+
+// SyncAttempt records the outcome of one sync attempt
+type SyncAttempt struct {
+	// When the attempt finished
+	Time metav1.Time `json:"time"`
+	// Succeeded, NoChanges, DryRun, or Failed
+	Result string `json:"result"`
+	// How long the attempt took
+	// +optional
+	Duration metav1.Duration `json:"duration,omitempty"`
+	// Why the attempt failed
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// How many consecutive NoChanges attempts this entry stands for, when more than one.  Time and duration are those
+	// of the last one.
+	// +optional
+	Count int `json:"count,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // BitwardenSecret is the Schema for the bitwardensecrets API
 type BitwardenSecret struct {
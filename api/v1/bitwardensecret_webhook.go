@@ -0,0 +1,395 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SecretNameIndexField indexes BitwardenSecrets by the Kubernetes secret they write to
+const SecretNameIndexField = "spec.secretName"
+
+// TargetSecretNameIndexField indexes BitwardenSecrets by the Kubernetes secrets their targets write to in their own
+// namespace and cluster
+const TargetSecretNameIndexField = "spec.targets.secretName"
+
+var bitwardensecretlog = logf.Log.WithName("bitwardensecret-resource")
+
+// SetupWebhookWithManager registers the BitwardenSecret admission webhooks.  The manager's cache must be indexed
+// with IndexSecretName.  BitwardenSecrets without their own refresh interval are defaulted to refreshInterval.
+func SetupWebhookWithManager(mgr ctrl.Manager, refreshInterval time.Duration) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&BitwardenSecret{}).
+		WithDefaulter(&BitwardenSecretDefaulter{RefreshInterval: refreshInterval}).
+		WithValidator(&BitwardenSecretValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// IndexSecretName adds the SecretNameIndexField and TargetSecretNameIndexField indexes to the indexer.
+func IndexSecretName(ctx context.Context, indexer client.FieldIndexer) error {
+	err := indexer.IndexField(ctx, &BitwardenSecret{}, SecretNameIndexField, func(obj client.Object) []string {
+		return []string{obj.(*BitwardenSecret).Spec.SecretName}
+	})
+	if err != nil {
+		return err
+	}
+
+	return indexer.IndexField(ctx, &BitwardenSecret{}, TargetSecretNameIndexField, TargetSecretNames)
+}
+
+// TargetSecretNames returns the names of the Kubernetes secrets the targets of a BitwardenSecret write to in its own
+// namespace and cluster.  ConfigMap targets and targets in other clusters are left out.
+func TargetSecretNames(obj client.Object) []string {
+	names := []string{}
+	for _, target := range obj.(*BitwardenSecret).Spec.Targets {
+		if target.Kind != TargetKindConfigMap && target.ClusterRef == nil {
+			names = append(names, target.SecretName)
+		}
+	}
+
+	return names
+}
+
+// Default key of the authorization token in its Kubernetes secret
+const DefaultAuthTokenSecretKey = "token"
+
+//+kubebuilder:webhook:path=/mutate-k8s-bitwarden-com-v1-bitwardensecret,mutating=true,failurePolicy=fail,sideEffects=None,groups=k8s.bitwarden.com,resources=bitwardensecrets,verbs=create;update,versions=v1,name=mbitwardensecret.kb.io,admissionReviewVersions=v1
+
+// BitwardenSecretDefaulter fills in the defaults of omitted spec fields at admission, so that manifests can stay
+// minimal and every BitwardenSecret shows the settings it is synced with.
+// +kubebuilder:object:generate=false
+type BitwardenSecretDefaulter struct {
+	// The operator refresh interval
+	RefreshInterval time.Duration
+}
+
+var _ webhook.CustomDefaulter = &BitwardenSecretDefaulter{}
+
+// Default sets the refresh interval, secret type, authorization token key, and the defaults of the optional templates.
+func (d *BitwardenSecretDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	bwSecret := obj.(*BitwardenSecret)
+	bitwardensecretlog.V(1).Info("default", "name", bwSecret.Name)
+
+	spec := &bwSecret.Spec
+	if spec.RefreshInterval == nil && d.RefreshInterval > 0 {
+		spec.RefreshInterval = &metav1.Duration{Duration: d.RefreshInterval}
+	}
+
+	if spec.SecretType == "" {
+		spec.SecretType = corev1.SecretTypeOpaque
+	}
+
+	if spec.AuthToken.SecretName != "" && spec.AuthToken.SecretKey == "" {
+		spec.AuthToken.SecretKey = DefaultAuthTokenSecretKey
+	}
+
+	if spec.Kubeconfig != nil {
+		if spec.Kubeconfig.Key == "" {
+			spec.Kubeconfig.Key = "kubeconfig"
+		}
+		if spec.Kubeconfig.Name == "" {
+			spec.Kubeconfig.Name = "default"
+		}
+	}
+
+	if spec.SyncWindow != nil && spec.SyncWindow.TimeZone == "" {
+		spec.SyncWindow.TimeZone = "UTC"
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-k8s-bitwarden-com-v1-bitwardensecret,mutating=false,failurePolicy=fail,sideEffects=None,groups=k8s.bitwarden.com,resources=bitwardensecrets,verbs=create;update,versions=v1,name=vbitwardensecret.kb.io,admissionReviewVersions=v1
+
+// BitwardenSecretValidator stops two BitwardenSecrets from writing to the same Kubernetes secret, where they would
+// endlessly overwrite each other.
+// +kubebuilder:object:generate=false
+type BitwardenSecretValidator struct {
+	// Must support listing by SecretNameIndexField and TargetSecretNameIndexField
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &BitwardenSecretValidator{}
+
+// ValidateCreate rejects a BitwardenSecret whose target secret is already claimed.
+func (v *BitwardenSecretValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	bwSecret := obj.(*BitwardenSecret)
+	bitwardensecretlog.V(1).Info("validate create", "name", bwSecret.Name)
+
+	if err := v.validateTargets(bwSecret); err != nil {
+		return nil, err
+	}
+
+	if err := validateSecretTypes(bwSecret); err != nil {
+		return nil, err
+	}
+
+	if err := validateOwnerReference(bwSecret); err != nil {
+		return nil, err
+	}
+
+	warnings, err := v.validateSecretName(ctx, nil, bwSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(configMapTargetWarnings(bwSecret), warnings...), nil
+}
+
+// ValidateUpdate rejects moving a BitwardenSecret or one of its targets to a secret that is already claimed.
+// BitwardenSecrets that already share a secret, for example because they were created before the webhook was enabled,
+// only get a warning so that they can still be updated and fixed.
+func (v *BitwardenSecretValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldBwSecret := oldObj.(*BitwardenSecret)
+	bwSecret := newObj.(*BitwardenSecret)
+	bitwardensecretlog.V(1).Info("validate update", "name", bwSecret.Name)
+
+	if err := v.validateTargets(bwSecret); err != nil {
+		return nil, err
+	}
+
+	if err := validateSecretTypes(bwSecret); err != nil {
+		return nil, err
+	}
+
+	if err := validateOwnerReference(bwSecret); err != nil {
+		return nil, err
+	}
+
+	warnings, err := v.validateSecretName(ctx, oldBwSecret, bwSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(configMapTargetWarnings(bwSecret), warnings...), nil
+}
+
+func (v *BitwardenSecretValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateTargets rejects targets that write to secretName, to the ConfigMap of the non-sensitive map entries, or to
+// the object of another target in the same cluster and namespace.
+func (v *BitwardenSecretValidator) validateTargets(bwSecret *BitwardenSecret) error {
+	// Objects are identified by the cluster, the namespace, and the kind they are written to
+	scope := func(target SecretTarget) string {
+		kind := target.Kind
+		if kind != TargetKindConfigMap {
+			kind = TargetKindSecret
+		}
+		if target.ClusterRef == nil {
+			return string(kind)
+		}
+		return fmt.Sprintf("%s/%s/%s/%s", kind, target.ClusterRef.SecretName, target.ClusterRef.Key, target.ClusterRef.Namespace)
+	}
+
+	seen := map[string]bool{string(TargetKindSecret) + "/" + bwSecret.Spec.SecretName: true}
+	for _, m := range bwSecret.Spec.SecretMap {
+		if m.Sensitive != nil && !*m.Sensitive {
+			configMapName := bwSecret.Spec.ConfigMapName
+			if configMapName == "" {
+				configMapName = bwSecret.Spec.SecretName
+			}
+			seen[string(TargetKindConfigMap)+"/"+configMapName] = true
+		}
+	}
+
+	errs := field.ErrorList{}
+	for i, target := range bwSecret.Spec.Targets {
+		name := scope(target) + "/" + target.SecretName
+		if seen[name] {
+			errs = append(errs, field.Duplicate(field.NewPath("spec", "targets").Index(i).Child("secretName"), target.SecretName))
+		}
+		seen[name] = true
+
+		if target.ClusterRef != nil && target.ClusterRef.SecretName == "" {
+			errs = append(errs, field.Required(field.NewPath("spec", "targets").Index(i).Child("clusterRef", "secretName"), "the secret holding the kubeconfig of the cluster"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("BitwardenSecret").GroupKind(), bwSecret.Name, errs)
+}
+
+// Format of secret types, which are either built-in types or custom types such as example.com/credentials
+var secretTypeFormat = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]*[A-Za-z0-9])?$`)
+
+// ValidateSecretTypeName checks that the type can be given to a Kubernetes secret written by the operator.  Service
+// account tokens are issued by Kubernetes and cannot be written.
+func ValidateSecretTypeName(secretType corev1.SecretType) error {
+	if secretType == corev1.SecretTypeServiceAccountToken {
+		return fmt.Errorf("secrets of type %s are populated by Kubernetes and cannot be written by the operator", secretType)
+	}
+
+	if len(secretType) > 253 || !secretTypeFormat.MatchString(string(secretType)) {
+		return fmt.Errorf("%q is not a valid secret type, which consists of letters, digits, '-', '_', '.', and '/'", secretType)
+	}
+
+	return nil
+}
+
+// validateSecretTypes rejects types of secretName and of the targets that cannot be written, and basic-auth and SSH
+// templates whose secret is not of the matching type.
+func validateSecretTypes(bwSecret *BitwardenSecret) error {
+	errs := field.ErrorList{}
+	check := func(path *field.Path, secretType corev1.SecretType) {
+		if secretType == "" {
+			return
+		}
+		if err := ValidateSecretTypeName(secretType); err != nil {
+			errs = append(errs, field.Invalid(path, secretType, err.Error()))
+		}
+	}
+
+	check(field.NewPath("spec", "secretType"), bwSecret.Spec.SecretType)
+	for i, target := range bwSecret.Spec.Targets {
+		path := field.NewPath("spec", "targets").Index(i).Child("secretType")
+		check(path, target.SecretType)
+		if target.Kind == TargetKindConfigMap && target.SecretType != "" && target.SecretType != corev1.SecretTypeOpaque {
+			errs = append(errs, field.Invalid(path, target.SecretType, "ConfigMap targets have no secret type"))
+		}
+	}
+
+	if bwSecret.Spec.BasicAuth != nil && bwSecret.Spec.SecretType != corev1.SecretTypeBasicAuth {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "secretType"), bwSecret.Spec.SecretType, fmt.Sprintf("spec.basicAuth requires the %s secret type", corev1.SecretTypeBasicAuth)))
+	}
+	if bwSecret.Spec.SSHAuth != nil && bwSecret.Spec.SecretType != corev1.SecretTypeSSHAuth {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "secretType"), bwSecret.Spec.SecretType, fmt.Sprintf("spec.sshAuth requires the %s secret type", corev1.SecretTypeSSHAuth)))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("BitwardenSecret").GroupKind(), bwSecret.Name, errs)
+}
+
+// configMapTargetWarnings warns that ConfigMap targets write their values in plain text, readable by everyone who can
+// read the ConfigMaps of the namespace.
+func configMapTargetWarnings(bwSecret *BitwardenSecret) admission.Warnings {
+	var warnings admission.Warnings
+	for i, target := range bwSecret.Spec.Targets {
+		if target.Kind == TargetKindConfigMap {
+			warnings = append(warnings, fmt.Sprintf("spec.targets[%d] writes its values in plain text to ConfigMap %s, which everyone allowed to read ConfigMaps in namespace %s can read; only map values that are not secret", i, target.SecretName, bwSecret.Namespace))
+		}
+	}
+
+	return warnings
+}
+
+// validateOwnerReference rejects standalone secrets that would have to be deleted with the BitwardenSecret.
+func validateOwnerReference(bwSecret *BitwardenSecret) error {
+	if bwSecret.Spec.OwnerReference == nil || *bwSecret.Spec.OwnerReference || bwSecret.Spec.DeletionPolicy == DeletionPolicyRetain {
+		return nil
+	}
+
+	errs := field.ErrorList{field.Invalid(field.NewPath("spec", "ownerReference"), false, fmt.Sprintf("requires deletionPolicy %s", DeletionPolicyRetain))}
+	return apierrors.NewInvalid(GroupVersion.WithKind("BitwardenSecret").GroupKind(), bwSecret.Name, errs)
+}
+
+// validateSecretName rejects secretName and the targets of the BitwardenSecret when another BitwardenSecret in the
+// namespace already writes to the same secret, either as its secretName or as one of its targets.  Secrets that the
+// old BitwardenSecret of an update already wrote to only get a warning.
+func (v *BitwardenSecretValidator) validateSecretName(ctx context.Context, oldBwSecret *BitwardenSecret, bwSecret *BitwardenSecret) (admission.Warnings, error) {
+	written := map[string]bool{}
+	if oldBwSecret != nil {
+		written[oldBwSecret.Spec.SecretName] = true
+		for _, name := range TargetSecretNames(oldBwSecret) {
+			written[name] = true
+		}
+	}
+
+	claimed := func(name string) ([]string, error) {
+		others := []string{}
+		for _, indexField := range []string{SecretNameIndexField, TargetSecretNameIndexField} {
+			claims := &BitwardenSecretList{}
+			err := v.Client.List(ctx, claims, client.InNamespace(bwSecret.Namespace), client.MatchingFields{indexField: name})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, claim := range claims.Items {
+				if claim.Name != bwSecret.Name && !slices.Contains(others, claim.Name) {
+					others = append(others, claim.Name)
+				}
+			}
+		}
+
+		return others, nil
+	}
+
+	paths := map[string]*field.Path{bwSecret.Spec.SecretName: field.NewPath("spec", "secretName")}
+	names := []string{bwSecret.Spec.SecretName}
+	for i, target := range bwSecret.Spec.Targets {
+		if target.Kind == TargetKindConfigMap || target.ClusterRef != nil || paths[target.SecretName] != nil {
+			continue
+		}
+
+		paths[target.SecretName] = field.NewPath("spec", "targets").Index(i).Child("secretName")
+		names = append(names, target.SecretName)
+	}
+
+	var warnings admission.Warnings
+	errs := field.ErrorList{}
+	for _, name := range names {
+		others, err := claimed(name)
+		if err != nil {
+			return nil, apierrors.NewInternalError(err)
+		}
+
+		if len(others) == 0 {
+			continue
+		}
+
+		detail := fmt.Sprintf("secret %s/%s is already written by BitwardenSecret %s", bwSecret.Namespace, name, strings.Join(others, ", "))
+		if written[name] {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", paths[name], detail))
+			continue
+		}
+
+		errs = append(errs, field.Forbidden(paths[name], detail))
+	}
+
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+
+	return nil, apierrors.NewInvalid(GroupVersion.WithKind("BitwardenSecret").GroupKind(), bwSecret.Name, errs)
+}
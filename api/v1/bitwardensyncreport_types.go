@@ -0,0 +1,101 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Name of the BitwardenSyncReport maintained by the operator
+const SyncReportName = "cluster"
+
+// BitwardenSyncReportSpec defines the desired state of BitwardenSyncReport
+type BitwardenSyncReportSpec struct {
+	// How long after its last successful sync a BitwardenSecret counts as stale.  Defaults to three refresh intervals of the BitwardenSecret.
+	// +kubebuilder:Optional
+	StaleAfter *metav1.Duration `json:"staleAfter,omitempty"`
+}
+
+// BitwardenSyncReportStatus summarizes the sync health of every BitwardenSecret in the cluster
+type BitwardenSyncReportStatus struct {
+	// The number of BitwardenSecrets in the cluster
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Total int `json:"total"`
+
+	// The number of BitwardenSecrets that are neither degraded, failing, nor stale
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Healthy int `json:"healthy"`
+
+	// The number of BitwardenSecrets with a Degraded condition
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Degraded int `json:"degraded"`
+
+	// The number of BitwardenSecrets whose last sync attempt failed
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Failing int `json:"failing"`
+
+	// The number of BitwardenSecrets that have not synced successfully within staleAfter
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Stale int `json:"stale"`
+
+	// The oldest last successful sync time of any BitwardenSecret.  BitwardenSecrets that never synced are counted
+	// as stale instead.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	OldestSyncTime *metav1.Time `json:"oldestSyncTime,omitempty"`
+
+	// The namespace/name of the BitwardenSecret with the oldest sync
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	OldestSyncBitwardenSecret string `json:"oldestSyncBitwardenSecret,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.total`
+//+kubebuilder:printcolumn:name="Healthy",type=integer,JSONPath=`.status.healthy`
+//+kubebuilder:printcolumn:name="Degraded",type=integer,JSONPath=`.status.degraded`
+//+kubebuilder:printcolumn:name="Failing",type=integer,JSONPath=`.status.failing`
+//+kubebuilder:printcolumn:name="Stale",type=integer,JSONPath=`.status.stale`
+//+kubebuilder:printcolumn:name="Oldest Sync",type=date,JSONPath=`.status.oldestSyncTime`
+
+// BitwardenSyncReport is the Schema for the bitwardensyncreports API
+type BitwardenSyncReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BitwardenSyncReportSpec   `json:"spec,omitempty"`
+	Status BitwardenSyncReportStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BitwardenSyncReportList contains a list of BitwardenSyncReport
+type BitwardenSyncReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BitwardenSyncReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BitwardenSyncReport{}, &BitwardenSyncReportList{})
+}
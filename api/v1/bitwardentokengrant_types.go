@@ -0,0 +1,77 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BitwardenTokenGrantSpec defines which namespaces may use an authorization token secret
+type BitwardenTokenGrantSpec struct {
+	// The name of the Kubernetes secret in the namespace of the grant holding the authorization token
+	// +kubebuilder:Required
+	SecretName string `json:"secretName"`
+	// The namespaces whose BitwardenSecrets may reference the secret with spec.authToken.namespace
+	// +kubebuilder:Required
+	Namespaces []string `json:"namespaces"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.spec.secretName`
+//+kubebuilder:printcolumn:name="Namespaces",type=string,JSONPath=`.spec.namespaces`
+
+// BitwardenTokenGrant allows BitwardenSecrets of other namespaces to use an authorization token secret in the
+// namespace of the grant.  Without a grant, BitwardenSecrets can only use the secrets of their own namespace, so that
+// the operator cannot be used to read secrets from namespaces the creator of the BitwardenSecret has no access to.
+type BitwardenTokenGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BitwardenTokenGrantSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BitwardenTokenGrantList contains a list of BitwardenTokenGrant
+type BitwardenTokenGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BitwardenTokenGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BitwardenTokenGrant{}, &BitwardenTokenGrantList{})
+}
+
+// Grants reports whether the grant allows BitwardenSecrets of the namespace to use the secret.
+func (g *BitwardenTokenGrant) Grants(secretName string, namespace string) bool {
+	if g.Spec.SecretName != secretName {
+		return false
+	}
+
+	for _, granted := range g.Spec.Namespaces {
+		if granted == namespace {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,115 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterBitwardenSecretSpec defines the desired state of ClusterBitwardenSecret
+type ClusterBitwardenSecretSpec struct {
+	// The organization ID for your organization
+	// +kubebuilder:Optional
+	OrganizationId string `json:"organizationId"`
+	// The name of the secret created in every selected namespace
+	// +kubebuilder:Required
+	SecretName string `json:"secretName"`
+	// The mapping of organization secret IDs to K8s secret keys.  This helps improve readability and mapping to environment variables.
+	// +kubebuilder:Optional
+	SecretMap []SecretMap `json:"map,omitempty"`
+	// The secret key reference for the authorization token used to connect to Secrets Manager
+	// +kubebuilder:Required
+	AuthToken ClusterAuthToken `json:"authToken"`
+	// The IDs or names of the projects to sync secrets from.  Defaults to every secret the machine account can access.
+	// +kubebuilder:Optional
+	Projects []string `json:"projects,omitempty"`
+	// The namespaces the secret is created in.  An empty selector selects every namespace.
+	// +kubebuilder:Required
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+}
+
+type ClusterAuthToken struct {
+	// The namespace of the Kubernetes secret where the authorization token is stored
+	// +kubebuilder:Required
+	Namespace string `json:"namespace"`
+	// The name of the Kubernetes secret where the authorization token is stored
+	// +kubebuilder:Required
+	SecretName string `json:"secretName"`
+	// The key of the Kubernetes secret where the authorization token is stored
+	// +kubebuilder:Required
+	SecretKey string `json:"secretKey"`
+}
+
+// ClusterBitwardenSecretStatus defines the observed state of ClusterBitwardenSecret
+type ClusterBitwardenSecretStatus struct {
+	// The namespaces holding an up to date copy of the secret
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// The selected namespaces whose secret of the same name is not managed by this ClusterBitwardenSecret and was
+	// left untouched
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	ConflictingNamespaces []string `json:"conflictingNamespaces,omitempty"`
+
+	// Conditions store the status conditions of the ClusterBitwardenSecret instances
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// LastSuccessfulSyncTime is the time of the last successful sync to every selected namespace
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	LastSuccessfulSyncTime metav1.Time `json:"lastSuccessfulSyncTime,omitempty"`
+
+	// The generation of the ClusterBitwardenSecret last synced successfully
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.spec.secretName`
+//+kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSuccessfulSyncTime`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+
+// ClusterBitwardenSecret is the Schema for the clusterbitwardensecrets API
+type ClusterBitwardenSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterBitwardenSecretSpec   `json:"spec,omitempty"`
+	Status ClusterBitwardenSecretStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterBitwardenSecretList contains a list of ClusterBitwardenSecret
+type ClusterBitwardenSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterBitwardenSecret `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterBitwardenSecret{}, &ClusterBitwardenSecretList{})
+}
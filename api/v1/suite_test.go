@@ -0,0 +1,306 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWebhooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Webhook Suite")
+}
+
+func newBitwardenSecret(name string, secretName string) *BitwardenSecret {
+	return &BitwardenSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: BitwardenSecretSpec{
+			OrganizationId: "org",
+			SecretName:     secretName,
+			AuthToken:      AuthToken{SecretName: "auth", SecretKey: "token"},
+		},
+	}
+}
+
+var _ = Describe("BitwardenSecret validating webhook", func() {
+	var ctx context.Context
+	var validator *BitwardenSecretValidator
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(AddToScheme(scheme)).Should(Succeed())
+
+		existing := newBitwardenSecret("existing", "app-secrets")
+		existing.Spec.Targets = []SecretTarget{
+			{SecretName: "app-pull"},
+			{SecretName: "app-config", Kind: TargetKindConfigMap},
+			{SecretName: "app-remote", ClusterRef: &ClusterRef{SecretName: "workload-kubeconfig"}},
+		}
+		k8sClient := fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(existing).
+			WithIndex(&BitwardenSecret{}, SecretNameIndexField, func(obj client.Object) []string {
+				return []string{obj.(*BitwardenSecret).Spec.SecretName}
+			}).
+			WithIndex(&BitwardenSecret{}, TargetSecretNameIndexField, TargetSecretNames).
+			Build()
+
+		validator = &BitwardenSecretValidator{Client: k8sClient}
+	})
+
+	It("Allows BitwardenSecrets with unclaimed target secrets", func() {
+		warnings, err := validator.ValidateCreate(ctx, newBitwardenSecret("other", "other-secrets"))
+		Expect(err).Should(BeNil())
+		Expect(warnings).Should(BeEmpty())
+	})
+
+	It("Rejects BitwardenSecrets whose target secret is already claimed", func() {
+		_, err := validator.ValidateCreate(ctx, newBitwardenSecret("duplicate", "app-secrets"))
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("already written by BitwardenSecret existing"))
+	})
+
+	It("Allows the claiming BitwardenSecret to be updated", func() {
+		existing := newBitwardenSecret("existing", "app-secrets")
+		warnings, err := validator.ValidateUpdate(ctx, existing, existing)
+		Expect(err).Should(BeNil())
+		Expect(warnings).Should(BeEmpty())
+	})
+
+	It("Rejects moving to a claimed target secret", func() {
+		_, err := validator.ValidateUpdate(ctx, newBitwardenSecret("other", "other-secrets"), newBitwardenSecret("other", "app-secrets"))
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("Only warns about existing duplicates", func() {
+		duplicate := newBitwardenSecret("duplicate", "app-secrets")
+		warnings, err := validator.ValidateUpdate(ctx, duplicate, duplicate)
+		Expect(err).Should(BeNil())
+		Expect(warnings).Should(HaveLen(1))
+	})
+
+	It("Rejects targets and secrets claimed by another BitwardenSecret", func() {
+		// A target writing to the secretName of another BitwardenSecret
+		bwSecret := newBitwardenSecret("other", "other-secrets")
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "other-pull"}, {SecretName: "app-secrets"}}
+		_, err := validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.targets[1].secretName"))
+		Expect(err.Error()).Should(ContainSubstring("already written by BitwardenSecret existing"))
+
+		// A target writing to the target of another BitwardenSecret
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "app-pull"}}
+		_, err = validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.targets[0].secretName"))
+
+		// A secretName writing to the target of another BitwardenSecret
+		_, err = validator.ValidateUpdate(ctx, newBitwardenSecret("other", "other-secrets"), newBitwardenSecret("other", "app-pull"))
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.secretName"))
+
+		// ConfigMaps and secrets in other clusters are not claimed
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "app-config"}, {SecretName: "app-remote"}}
+		_, err = validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).Should(BeNil())
+
+		// Existing duplicates only get a warning
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "app-pull"}}
+		warnings, err := validator.ValidateUpdate(ctx, bwSecret, bwSecret)
+		Expect(err).Should(BeNil())
+		Expect(warnings).Should(HaveLen(1))
+		Expect(warnings[0]).Should(ContainSubstring("spec.targets[0].secretName"))
+	})
+
+	It("Rejects targets sharing a secret", func() {
+		bwSecret := newBitwardenSecret("other", "other-secrets")
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "other-pull"}, {SecretName: "other-secrets"}}
+		_, err := validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.targets[1].secretName"))
+
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "other-pull"}, {SecretName: "other-pull"}}
+		_, err = validator.ValidateUpdate(ctx, bwSecret, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "other-pull"}}
+		_, err = validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).Should(BeNil())
+	})
+
+	It("Warns about ConfigMap targets", func() {
+		bwSecret := newBitwardenSecret("other", "other-secrets")
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "other-secrets", Kind: TargetKindConfigMap}}
+		warnings, err := validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).Should(BeNil())
+		Expect(warnings).Should(HaveLen(1))
+		Expect(warnings[0]).Should(ContainSubstring("plain text"))
+
+		// The ConfigMap of the non-sensitive map entries is already taken
+		sensitive := false
+		bwSecret.Spec.SecretMap = []SecretMap{{BwSecretId: "flag", SecretKeyName: "FLAG", Sensitive: &sensitive}}
+		_, err = validator.ValidateUpdate(ctx, bwSecret, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.targets[0].secretName"))
+
+		bwSecret.Spec.SecretMap = nil
+		bwSecret.Spec.Targets[0].SecretType = corev1.SecretTypeTLS
+		_, err = validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.targets[0].secretType"))
+	})
+
+	It("Scopes target names to their cluster", func() {
+		bwSecret := newBitwardenSecret("other", "other-secrets")
+		bwSecret.Spec.Targets = []SecretTarget{
+			{SecretName: "other-secrets", ClusterRef: &ClusterRef{SecretName: "workload-kubeconfig"}},
+			{SecretName: "other-secrets", ClusterRef: &ClusterRef{SecretName: "other-kubeconfig"}},
+		}
+		_, err := validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).Should(BeNil())
+
+		bwSecret.Spec.Targets = append(bwSecret.Spec.Targets, SecretTarget{SecretName: "other-secrets", ClusterRef: &ClusterRef{SecretName: "other-kubeconfig"}})
+		_, err = validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.targets[2].secretName"))
+
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "other-remote", ClusterRef: &ClusterRef{}}}
+		_, err = validator.ValidateUpdate(ctx, bwSecret, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.targets[0].clusterRef.secretName"))
+	})
+
+	It("Accepts built-in and custom secret types", func() {
+		bwSecret := newBitwardenSecret("other", "other-secrets")
+		for _, secretType := range []corev1.SecretType{corev1.SecretTypeBasicAuth, "example.com/credentials", "helm.sh/release.v1"} {
+			bwSecret.Spec.SecretType = secretType
+			_, err := validator.ValidateCreate(ctx, bwSecret)
+			Expect(err).Should(BeNil())
+		}
+
+		bwSecret.Spec.SecretType = corev1.SecretTypeServiceAccountToken
+		_, err := validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.secretType"))
+
+		bwSecret.Spec.SecretType = corev1.SecretTypeOpaque
+		bwSecret.Spec.Targets = []SecretTarget{{SecretName: "other-pull", SecretType: "not a type"}}
+		_, err = validator.ValidateUpdate(ctx, bwSecret, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.targets[0].secretType"))
+	})
+
+	It("Requires the Retain deletion policy for standalone secrets", func() {
+		bwSecret := newBitwardenSecret("standalone", "standalone-secrets")
+		ownerReference := false
+		bwSecret.Spec.OwnerReference = &ownerReference
+
+		bwSecret.Spec.DeletionPolicy = DeletionPolicyDelete
+		_, err := validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.ownerReference"))
+
+		bwSecret.Spec.DeletionPolicy = DeletionPolicyRetain
+		_, err = validator.ValidateUpdate(ctx, bwSecret, bwSecret)
+		Expect(err).Should(BeNil())
+	})
+
+	It("Requires the basic-auth secret type for basic-auth templates", func() {
+		bwSecret := newBitwardenSecret("ingress-auth", "ingress-auth")
+		bwSecret.Spec.BasicAuth = &BasicAuthTemplate{UsernameSecretId: "username", PasswordSecretId: "password"}
+
+		bwSecret.Spec.SecretType = corev1.SecretTypeOpaque
+		_, err := validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.basicAuth"))
+
+		bwSecret.Spec.SecretType = corev1.SecretTypeBasicAuth
+		_, err = validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).Should(BeNil())
+	})
+
+	It("Requires the ssh-auth secret type for SSH templates", func() {
+		bwSecret := newBitwardenSecret("git-credentials", "git-credentials")
+		bwSecret.Spec.SSHAuth = &SSHAuthTemplate{PrivateKeySecretId: "key"}
+
+		bwSecret.Spec.SecretType = corev1.SecretTypeBasicAuth
+		_, err := validator.ValidateUpdate(ctx, bwSecret, bwSecret)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("spec.sshAuth"))
+
+		bwSecret.Spec.SecretType = corev1.SecretTypeSSHAuth
+		_, err = validator.ValidateCreate(ctx, bwSecret)
+		Expect(err).Should(BeNil())
+	})
+})
+
+var _ = Describe("BitwardenSecret defaulting webhook", func() {
+	defaulter := &BitwardenSecretDefaulter{RefreshInterval: 5 * time.Minute}
+
+	It("Fills in omitted fields", func() {
+		bwSecret := newBitwardenSecret("minimal", "app-secrets")
+		bwSecret.Spec.AuthToken.SecretKey = ""
+		bwSecret.Spec.Kubeconfig = &KubeconfigTemplate{ServerSecretId: "server", TokenSecretId: "token"}
+		bwSecret.Spec.SyncWindow = &SyncWindow{Start: "22:00", End: "04:00"}
+
+		Expect(defaulter.Default(context.Background(), bwSecret)).Should(Succeed())
+		Expect(bwSecret.Spec.RefreshInterval.Duration).Should(Equal(5 * time.Minute))
+		Expect(bwSecret.Spec.SecretType).Should(Equal(corev1.SecretTypeOpaque))
+		Expect(bwSecret.Spec.AuthToken.SecretKey).Should(Equal(DefaultAuthTokenSecretKey))
+		Expect(bwSecret.Spec.Kubeconfig.Key).Should(Equal("kubeconfig"))
+		Expect(bwSecret.Spec.Kubeconfig.Name).Should(Equal("default"))
+		Expect(bwSecret.Spec.SyncWindow.TimeZone).Should(Equal("UTC"))
+	})
+
+	It("Does not default the secret key of mounted tokens", func() {
+		bwSecret := newBitwardenSecret("mounted", "app-secrets")
+		bwSecret.Spec.AuthToken = AuthToken{FilePath: "payments/token"}
+
+		Expect(defaulter.Default(context.Background(), bwSecret)).Should(Succeed())
+		Expect(bwSecret.Spec.AuthToken.SecretKey).Should(BeEmpty())
+	})
+
+	It("Keeps fields that are set", func() {
+		bwSecret := newBitwardenSecret("custom", "app-secrets")
+		bwSecret.Spec.AuthToken.SecretKey = "access-token"
+		bwSecret.Spec.SecretType = corev1.SecretTypeTLS
+		bwSecret.Spec.RefreshInterval = &metav1.Duration{Duration: time.Hour}
+
+		Expect(defaulter.Default(context.Background(), bwSecret)).Should(Succeed())
+		Expect(bwSecret.Spec.RefreshInterval.Duration).Should(Equal(time.Hour))
+		Expect(bwSecret.Spec.SecretType).Should(Equal(corev1.SecretTypeTLS))
+		Expect(bwSecret.Spec.AuthToken.SecretKey).Should(Equal("access-token"))
+	})
+})
@@ -45,6 +45,107 @@ func (in *AuthToken) DeepCopy() *AuthToken {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BasicAuthTemplate) DeepCopyInto(out *BasicAuthTemplate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BasicAuthTemplate.
+func (in *BasicAuthTemplate) DeepCopy() *BasicAuthTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(BasicAuthTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitwardenProject) DeepCopyInto(out *BitwardenProject) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenProject.
+func (in *BitwardenProject) DeepCopy() *BitwardenProject {
+	if in == nil {
+		return nil
+	}
+	out := new(BitwardenProject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BitwardenProject) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitwardenProjectList) DeepCopyInto(out *BitwardenProjectList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BitwardenProject, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenProjectList.
+func (in *BitwardenProjectList) DeepCopy() *BitwardenProjectList {
+	if in == nil {
+		return nil
+	}
+	out := new(BitwardenProjectList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BitwardenProjectList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitwardenProjectStatus) DeepCopyInto(out *BitwardenProjectStatus) {
+	*out = *in
+	if in.CreationDate != nil {
+		in, out := &in.CreationDate, &out.CreationDate
+		*out = (*in).DeepCopy()
+	}
+	if in.RevisionDate != nil {
+		in, out := &in.RevisionDate, &out.RevisionDate
+		*out = (*in).DeepCopy()
+	}
+	if in.BitwardenSecrets != nil {
+		in, out := &in.BitwardenSecrets, &out.BitwardenSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenProjectStatus.
+func (in *BitwardenProjectStatus) DeepCopy() *BitwardenProjectStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BitwardenProjectStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BitwardenSecret) DeepCopyInto(out *BitwardenSecret) {
 	*out = *in
@@ -110,9 +211,113 @@ func (in *BitwardenSecretSpec) DeepCopyInto(out *BitwardenSecretSpec) {
 	if in.SecretMap != nil {
 		in, out := &in.SecretMap, &out.SecretMap
 		*out = make([]SecretMap, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	out.AuthToken = in.AuthToken
+	if in.Versioning != nil {
+		in, out := &in.Versioning, &out.Versioning
+		*out = new(SecretVersioning)
+		**out = **in
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = new(SecretHistory)
+		**out = **in
+	}
+	if in.Kubeconfig != nil {
+		in, out := &in.Kubeconfig, &out.Kubeconfig
+		*out = new(KubeconfigTemplate)
+		**out = **in
+	}
+	if in.SyncWindow != nil {
+		in, out := &in.SyncWindow, &out.SyncWindow
+		*out = new(SyncWindow)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(SecretTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DockerConfig != nil {
+		in, out := &in.DockerConfig, &out.DockerConfig
+		*out = new(DockerConfigTemplate)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSTemplate)
+		**out = **in
+	}
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(BasicAuthTemplate)
+		**out = **in
+	}
+	if in.SSHAuth != nil {
+		in, out := &in.SSHAuth, &out.SSHAuth
+		*out = new(SSHAuthTemplate)
+		**out = **in
+	}
+	if in.SecretMetadata != nil {
+		in, out := &in.SecretMetadata, &out.SecretMetadata
+		*out = new(SecretMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CABundleSecretRef != nil {
+		in, out := &in.CABundleSecretRef, &out.CABundleSecretRef
+		*out = new(CABundleSecretRef)
+		**out = **in
+	}
+	if in.KeyTransform != nil {
+		in, out := &in.KeyTransform, &out.KeyTransform
+		*out = new(KeyTransform)
+		**out = **in
+	}
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(SecretFilter)
+		**out = **in
+	}
+	if in.Output != nil {
+		in, out := &in.Output, &out.Output
+		*out = new(SecretOutput)
+		**out = **in
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]SecretTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OwnerReference != nil {
+		in, out := &in.OwnerReference, &out.OwnerReference
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RolloutRestart != nil {
+		in, out := &in.RolloutRestart, &out.RolloutRestart
+		*out = new(RolloutRestart)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenSecretSpec.
@@ -136,6 +341,42 @@ func (in *BitwardenSecretStatus) DeepCopyInto(out *BitwardenSecretStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]ClusterTargetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AppliedSecretMap != nil {
+		in, out := &in.AppliedSecretMap, &out.AppliedSecretMap
+		*out = make([]SecretMap, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SyncedKeys != nil {
+		in, out := &in.SyncedKeys, &out.SyncedKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]SyncAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FailedKeys != nil {
+		in, out := &in.FailedKeys, &out.FailedKeys
+		*out = make([]KeyFailure, len(*in))
+		copy(*out, *in)
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(DryRunSummary)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenSecretStatus.
@@ -149,16 +390,709 @@ func (in *BitwardenSecretStatus) DeepCopy() *BitwardenSecretStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretMap) DeepCopyInto(out *SecretMap) {
+func (in *BitwardenSyncReport) DeepCopyInto(out *BitwardenSyncReport) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretMap.
-func (in *SecretMap) DeepCopy() *SecretMap {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenSyncReport.
+func (in *BitwardenSyncReport) DeepCopy() *BitwardenSyncReport {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretMap)
+	out := new(BitwardenSyncReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BitwardenSyncReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitwardenSyncReportList) DeepCopyInto(out *BitwardenSyncReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BitwardenSyncReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenSyncReportList.
+func (in *BitwardenSyncReportList) DeepCopy() *BitwardenSyncReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(BitwardenSyncReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BitwardenSyncReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitwardenSyncReportSpec) DeepCopyInto(out *BitwardenSyncReportSpec) {
+	*out = *in
+	if in.StaleAfter != nil {
+		in, out := &in.StaleAfter, &out.StaleAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenSyncReportSpec.
+func (in *BitwardenSyncReportSpec) DeepCopy() *BitwardenSyncReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BitwardenSyncReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitwardenSyncReportStatus) DeepCopyInto(out *BitwardenSyncReportStatus) {
+	*out = *in
+	if in.OldestSyncTime != nil {
+		in, out := &in.OldestSyncTime, &out.OldestSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenSyncReportStatus.
+func (in *BitwardenSyncReportStatus) DeepCopy() *BitwardenSyncReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BitwardenSyncReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitwardenTokenGrant) DeepCopyInto(out *BitwardenTokenGrant) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenTokenGrant.
+func (in *BitwardenTokenGrant) DeepCopy() *BitwardenTokenGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(BitwardenTokenGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BitwardenTokenGrant) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitwardenTokenGrantList) DeepCopyInto(out *BitwardenTokenGrantList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BitwardenTokenGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenTokenGrantList.
+func (in *BitwardenTokenGrantList) DeepCopy() *BitwardenTokenGrantList {
+	if in == nil {
+		return nil
+	}
+	out := new(BitwardenTokenGrantList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BitwardenTokenGrantList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitwardenTokenGrantSpec) DeepCopyInto(out *BitwardenTokenGrantSpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitwardenTokenGrantSpec.
+func (in *BitwardenTokenGrantSpec) DeepCopy() *BitwardenTokenGrantSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BitwardenTokenGrantSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CABundleSecretRef) DeepCopyInto(out *CABundleSecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CABundleSecretRef.
+func (in *CABundleSecretRef) DeepCopy() *CABundleSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CABundleSecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAuthToken) DeepCopyInto(out *ClusterAuthToken) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAuthToken.
+func (in *ClusterAuthToken) DeepCopy() *ClusterAuthToken {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAuthToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterBitwardenSecret) DeepCopyInto(out *ClusterBitwardenSecret) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterBitwardenSecret.
+func (in *ClusterBitwardenSecret) DeepCopy() *ClusterBitwardenSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterBitwardenSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterBitwardenSecret) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterBitwardenSecretList) DeepCopyInto(out *ClusterBitwardenSecretList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterBitwardenSecret, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterBitwardenSecretList.
+func (in *ClusterBitwardenSecretList) DeepCopy() *ClusterBitwardenSecretList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterBitwardenSecretList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterBitwardenSecretList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterBitwardenSecretSpec) DeepCopyInto(out *ClusterBitwardenSecretSpec) {
+	*out = *in
+	if in.SecretMap != nil {
+		in, out := &in.SecretMap, &out.SecretMap
+		*out = make([]SecretMap, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.AuthToken = in.AuthToken
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterBitwardenSecretSpec.
+func (in *ClusterBitwardenSecretSpec) DeepCopy() *ClusterBitwardenSecretSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterBitwardenSecretSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterBitwardenSecretStatus) DeepCopyInto(out *ClusterBitwardenSecretStatus) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConflictingNamespaces != nil {
+		in, out := &in.ConflictingNamespaces, &out.ConflictingNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.LastSuccessfulSyncTime.DeepCopyInto(&out.LastSuccessfulSyncTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterBitwardenSecretStatus.
+func (in *ClusterBitwardenSecretStatus) DeepCopy() *ClusterBitwardenSecretStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterBitwardenSecretStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterRef) DeepCopyInto(out *ClusterRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRef.
+func (in *ClusterRef) DeepCopy() *ClusterRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterTargetStatus) DeepCopyInto(out *ClusterTargetStatus) {
+	*out = *in
+	out.ClusterRef = in.ClusterRef
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTargetStatus.
+func (in *ClusterTargetStatus) DeepCopy() *ClusterTargetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterTargetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DockerConfigTemplate) DeepCopyInto(out *DockerConfigTemplate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DockerConfigTemplate.
+func (in *DockerConfigTemplate) DeepCopy() *DockerConfigTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(DockerConfigTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunSummary) DeepCopyInto(out *DryRunSummary) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.KeysCreated != nil {
+		in, out := &in.KeysCreated, &out.KeysCreated
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeysUpdated != nil {
+		in, out := &in.KeysUpdated, &out.KeysUpdated
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeysRemoved != nil {
+		in, out := &in.KeysRemoved, &out.KeysRemoved
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeysFailed != nil {
+		in, out := &in.KeysFailed, &out.KeysFailed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunSummary.
+func (in *DryRunSummary) DeepCopy() *DryRunSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyFailure) DeepCopyInto(out *KeyFailure) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyFailure.
+func (in *KeyFailure) DeepCopy() *KeyFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyTransform) DeepCopyInto(out *KeyTransform) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyTransform.
+func (in *KeyTransform) DeepCopy() *KeyTransform {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyTransform)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeconfigTemplate) DeepCopyInto(out *KubeconfigTemplate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeconfigTemplate.
+func (in *KubeconfigTemplate) DeepCopy() *KubeconfigTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeconfigTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutRestart) DeepCopyInto(out *RolloutRestart) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutRestart.
+func (in *RolloutRestart) DeepCopy() *RolloutRestart {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutRestart)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSHAuthTemplate) DeepCopyInto(out *SSHAuthTemplate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHAuthTemplate.
+func (in *SSHAuthTemplate) DeepCopy() *SSHAuthTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(SSHAuthTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretFilter) DeepCopyInto(out *SecretFilter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretFilter.
+func (in *SecretFilter) DeepCopy() *SecretFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretHistory) DeepCopyInto(out *SecretHistory) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretHistory.
+func (in *SecretHistory) DeepCopy() *SecretHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretMap) DeepCopyInto(out *SecretMap) {
+	*out = *in
+	if in.Aliases != nil {
+		in, out := &in.Aliases, &out.Aliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Sensitive != nil {
+		in, out := &in.Sensitive, &out.Sensitive
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretMap.
+func (in *SecretMap) DeepCopy() *SecretMap {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretMetadata) DeepCopyInto(out *SecretMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretMetadata.
+func (in *SecretMetadata) DeepCopy() *SecretMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretOutput) DeepCopyInto(out *SecretOutput) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretOutput.
+func (in *SecretOutput) DeepCopy() *SecretOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretTarget) DeepCopyInto(out *SecretTarget) {
+	*out = *in
+	if in.SecretMap != nil {
+		in, out := &in.SecretMap, &out.SecretMap
+		*out = make([]SecretMap, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClusterRef != nil {
+		in, out := &in.ClusterRef, &out.ClusterRef
+		*out = new(ClusterRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretTarget.
+func (in *SecretTarget) DeepCopy() *SecretTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretTemplate) DeepCopyInto(out *SecretTemplate) {
+	*out = *in
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretTemplate.
+func (in *SecretTemplate) DeepCopy() *SecretTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretVersioning) DeepCopyInto(out *SecretVersioning) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretVersioning.
+func (in *SecretVersioning) DeepCopy() *SecretVersioning {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretVersioning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncAttempt) DeepCopyInto(out *SyncAttempt) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncAttempt.
+func (in *SyncAttempt) DeepCopy() *SyncAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncWindow) DeepCopyInto(out *SyncWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncWindow.
+func (in *SyncWindow) DeepCopy() *SyncWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSTemplate) DeepCopyInto(out *TLSTemplate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSTemplate.
+func (in *TLSTemplate) DeepCopy() *TLSTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSTemplate)
 	in.DeepCopyInto(out)
 	return out
 }
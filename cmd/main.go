@@ -22,28 +22,42 @@ Trademark Guidelines
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/audit"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
 	"github.com/bitwarden/sm-kubernetes/internal/controller"
+	"github.com/bitwarden/sm-kubernetes/internal/export"
+	"github.com/bitwarden/sm-kubernetes/internal/injector"
+	"github.com/bitwarden/sm-kubernetes/internal/logging"
+	"github.com/bitwarden/sm-kubernetes/internal/profiling"
+	"github.com/bitwarden/sm-kubernetes/internal/tracing"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -63,34 +77,331 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var fipsMode bool
+	var clientBackend string
+	var clientCacheEnabled bool
+	var clientResetThreshold int
+	var clientIdleTimeout time.Duration
+	var clientSessionTTL time.Duration
+	var pullWorkers int
+	var maxConcurrentReconciles int
+	var targetWriteParallelism int
+	var traceFile string
+	var replayTrace string
+	var enableWebhooks bool
+	var pprofAddr string
+	var profileDir string
+	var profileInterval time.Duration
+	var profileCPUDuration time.Duration
+	var profileKeep int
+	var failoverRetryPrimaryAfter time.Duration
+	var caBundle string
+	var authTokenFile string
+	var authTokenNamespaces string
+	var authTokenDir string
+	var otlpEndpoint string
+	var otlpInsecure bool
+	var apiUnreachableAfter time.Duration
+	var apiQPS float64
+	var injectorImage string
+	var apiBurst int
+	var circuitBreakerThreshold int
+	var circuitBreakerCoolDown time.Duration
+	var auditSinkTarget string
+	var statePersistence string
+	var stateKeySecret string
+	var allowedNamespaces string
+	var deniedNamespaces string
+	var watchNamespaces string
+	var logLevelConfigMap string
+	var syncTriggerAddr string
+	var syncTriggerTokenFile string
+	var eventsAPIKeySecret string
+	var eventsPollInterval time.Duration
+	var sdkTimeout time.Duration
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&fipsMode, "fips-mode", false,
+		"Require FIPS 140 validated cryptography. "+
+			"The operator refuses to start unless it was built with \"make build-fips\" and uses the \"rest\" client backend.")
+	flag.StringVar(&clientBackend, "client-backend", defaultClientBackend(),
+		"The Bitwarden client backend. Available backends: "+strings.Join(bwclient.Backends(), ", ")+". "+
+			"\"sdk\" uses the native Secrets Manager SDK (requires cgo) and \"rest\" calls the Secrets Manager REST API directly.")
+	flag.BoolVar(&clientCacheEnabled, "client-cache", true,
+		"Reuse Bitwarden clients across reconciles. Disabling this creates a new client for every sync.")
+	flag.IntVar(&clientResetThreshold, "client-reset-threshold", 3,
+		"The number of consecutive failed syncs after which a cached Bitwarden client is treated as wedged and rebuilt.")
+	flag.DurationVar(&clientIdleTimeout, "client-idle-timeout", time.Hour,
+		"How long a cached Bitwarden client may go unused before it is closed.")
+	flag.DurationVar(&clientSessionTTL, "client-session-ttl", 30*time.Minute,
+		"How long a cached Bitwarden client reuses its authenticated session before logging in again. 0 logs in on every sync.")
+	flag.Float64Var(&apiQPS, "api-qps", 0,
+		"The maximum number of Secrets Manager and identity API calls per second, shared by all BitwardenSecrets. 0 disables rate limiting.")
+	flag.IntVar(&apiBurst, "api-burst", 10,
+		"The number of API calls that may exceed --api-qps in a short burst.")
+	flag.IntVar(&circuitBreakerThreshold, "api-circuit-breaker-threshold", 5,
+		"The number of calls in a row to a Bitwarden server that must fail because it is unreachable or failing before its calls are paused for --api-circuit-breaker-cool-down. 0 disables the circuit breaker.")
+	flag.DurationVar(&circuitBreakerCoolDown, "api-circuit-breaker-cool-down", 30*time.Second,
+		"How long Bitwarden API calls are paused once the circuit breaker opens, before a single call probes whether the API is back.")
+	flag.IntVar(&pullWorkers, "pull-workers", 4,
+		"The maximum number of Secrets Manager pulls that run at the same time, independent of --max-concurrent-reconciles. 0 runs pulls directly on the controller workers.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The number of BitwardenSecrets, and separately of ClusterBitwardenSecrets, reconciled in parallel.")
+	flag.IntVar(&targetWriteParallelism, "target-write-parallelism", 4,
+		"The number of spec.targets secrets of one BitwardenSecret written in parallel.")
+	flag.StringVar(&traceFile, "trace-file", "",
+		"Debug: append redacted metadata of every Bitwarden client call to this file. Secret values, keys, notes, and access tokens are never recorded.")
+	flag.StringVar(&replayTrace, "replay-trace", "",
+		"Debug: answer Bitwarden client calls from a trace recorded with --trace-file instead of contacting Secrets Manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", os.Getenv("ENABLE_WEBHOOKS") == "true",
+		"Serve the BitwardenSecret admission webhooks. Requires a serving certificate, see config/default/manager_webhook_patch.yaml.")
+	flag.StringVar(&pprofAddr, "pprof-bind-address", "",
+		"The address the pprof endpoints bind to, for example localhost:6060. Disabled when empty.")
+	flag.StringVar(&profileDir, "profile-dir", "",
+		"Periodically write heap and CPU profiles to this directory, typically a mounted volume. Disabled when empty.")
+	flag.DurationVar(&profileInterval, "profile-interval", 15*time.Minute,
+		"Time between profile captures written to --profile-dir.")
+	flag.DurationVar(&profileCPUDuration, "profile-cpu-duration", 30*time.Second,
+		"How long each captured CPU profile samples for. Zero captures heap profiles only.")
+	flag.IntVar(&profileKeep, "profile-keep", 8,
+		"Number of captures of each profile kind kept in --profile-dir. Zero keeps all of them.")
+	flag.DurationVar(&failoverRetryPrimaryAfter, "failover-retry-primary-after", 5*time.Minute,
+		"How long clients stay on the secondary Bitwarden endpoint after a failover before trying the primary again.")
+	flag.StringVar(&caBundle, "ca-bundle", "",
+		"Path to a PEM encoded CA bundle trusted by the Bitwarden clients, for self-hosted servers with certificates issued by a private CA.")
+	flag.StringVar(&authTokenFile, "auth-token-file", "",
+		"Path to a file holding the machine account access token of BitwardenSecrets that do not reference a token of their own, for example from a projected volume.")
+	flag.StringVar(&authTokenDir, "auth-token-dir", "",
+		"Directory of mounted access token files that BitwardenSecrets may reference with spec.authToken.filePath. Disabled when empty.")
+	flag.StringVar(&authTokenNamespaces, "auth-token-namespaces", "",
+		"Comma separated namespaces, or shell patterns such as team-*, whose BitwardenSecrets may use the tokens of --auth-token-file and --auth-token-dir. No namespace may when empty.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"host:port of an OTLP/HTTP collector that OpenTelemetry spans of the syncs are exported to, for example otel-collector:4318. Tracing is disabled unless this or OTEL_EXPORTER_OTLP_ENDPOINT is set.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false,
+		"Export spans to the OTLP collector over plain HTTP instead of HTTPS.")
+	flag.DurationVar(&apiUnreachableAfter, "api-unreachable-after", 5*time.Minute,
+		"How long every pull must have failed to reach the Bitwarden API before the readiness check fails. Zero disables the check.")
+	flag.StringVar(&injectorImage, "injector-image", os.Getenv("INJECTOR_IMAGE"),
+		"Image of the init container that renders secrets to files for pods with the k8s.bitwarden.com/inject-mode: files annotation, normally the operator image. File injection is disabled when empty.")
+	flag.StringVar(&auditSinkTarget, "audit-sink", "",
+		"Where audit records of every Kubernetes secret the operator creates, updates, or deletes are written: \"stdout\" for JSON lines or an http(s) URL they are posted to. Auditing is disabled when empty.")
+	flag.StringVar(&statePersistence, "state-persistence", controller.StatePersistencePlain,
+		"How the Secrets Manager SDK login state is kept on the state path: \"plain\" as written by the SDK, \"encrypted\" with the key of --state-encryption-key-secret, or \"none\" to never persist it.")
+	flag.StringVar(&stateKeySecret, "state-encryption-key-secret", "",
+		"Namespace and name of the Kubernetes secret, as namespace/name, whose \"key\" entry holds the 32 byte key, raw or base64 encoded, that encrypts the login state.")
+	flag.StringVar(&allowedNamespaces, "allowed-namespaces", "",
+		"Comma separated namespaces, or shell patterns such as team-*, that BitwardenSecrets may sync in. Every namespace is allowed when empty.")
+	flag.StringVar(&deniedNamespaces, "denied-namespaces", "",
+		"Comma separated namespaces, or shell patterns, that BitwardenSecrets never sync in, even when allowed. BitwardenSecrets in them are marked Denied.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma separated namespaces whose BitwardenSecrets this operator caches and reconciles, so that several operators can be installed side by side, one per tenant. ClusterBitwardenSecrets are not reconciled when set. Every namespace is watched when empty.")
+	flag.StringVar(&logLevelConfigMap, "log-level-configmap", "",
+		"Namespace and name of a ConfigMap, as namespace/name, whose \"level\" entry overrides the log level at runtime, optionally for the \"duration\" entry only. Disabled when empty.")
+	flag.StringVar(&syncTriggerAddr, "sync-trigger-bind-address", "",
+		"The address of the HTTP endpoint that CI/CD or rotation tooling can POST {\"namespace\", \"name\"} to for an immediate sync of a BitwardenSecret, for example :8082. Disabled when empty.")
+	flag.StringVar(&syncTriggerTokenFile, "sync-trigger-token-file", "",
+		"Path to a file holding the bearer token that requests to --sync-trigger-bind-address must carry, for example from a mounted secret. Required with --sync-trigger-bind-address.")
+	flag.StringVar(&eventsAPIKeySecret, "events-api-key-secret", "",
+		"Namespace and name of the Kubernetes secret, as namespace/name, whose \"clientId\" and \"clientSecret\" entries hold an organization API key. The event log of the organization is polled and BitwardenSecrets are synced right after their secrets change. Disabled when empty.")
+	flag.DurationVar(&eventsPollInterval, "events-poll-interval", time.Minute,
+		"Time between polls of the organization event log.")
+	flag.DurationVar(&sdkTimeout, "sdk-timeout", controller.DefaultSDKTimeout,
+		"How long each AccessTokenLogin and Secrets().Sync call of a BitwardenSecret without spec.timeout may take before the sync is abandoned and retried. Zero waits indefinitely.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	logLevel := logging.DynamicLevel(&opts)
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	// "manager render-files ..." is run by the init containers of pods that secrets are injected into as files
+	if flag.Arg(0) == "render-files" {
+		if err := injector.RunRenderFiles(flag.Args()[1:]); err != nil {
+			setupLog.Error(err, "unable to render secret files")
+			os.Exit(1)
+		}
+		return
+	}
+
+	if fipsMode {
+		if err := controller.EnableFIPSMode(clientBackend); err != nil {
+			setupLog.Error(err, "unable to enable FIPS mode")
+			os.Exit(1)
+		}
+		setupLog.Info("FIPS mode enabled")
+	}
+
+	var shutdownTracing func(context.Context) error
+	if otlpEndpoint != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		var err error
+		shutdownTracing, err = tracing.Setup(context.Background(), otlpEndpoint, otlpInsecure)
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+		setupLog.Info("Exporting traces", "endpoint", otlpEndpoint)
+	}
+
+	if caBundle != "" {
+		if err := bwclient.UseCABundle(caBundle); err != nil {
+			setupLog.Error(err, "unable to load CA bundle", "path", caBundle)
+			os.Exit(1)
+		}
+		setupLog.Info("Trusting the Bitwarden server CAs of the CA bundle", "path", caBundle)
+	}
+
 	bwApiUrl, identApiUrl, statePath, refreshIntervalSeconds, err := GetSettings()
 
 	if err != nil {
 		panic(err)
 	}
 
-	bwClientFactory := controller.NewBitwardenClientFactory(*bwApiUrl, *identApiUrl)
+	var bwClientFactory controller.BitwardenClientFactory
+	if replayTrace != "" {
+		bwClientFactory, err = newReplayClientFactory(replayTrace, *bwApiUrl, *identApiUrl)
+	} else {
+		bwClientFactory, err = controller.NewBitwardenClientFactoryForBackend(clientBackend, *bwApiUrl, *identApiUrl)
+	}
+	if err != nil {
+		setupLog.Error(err, "unable to create Bitwarden client factory")
+		os.Exit(1)
+	}
+
+	secondaryApiUrl, secondaryIdentApiUrl, err := GetSecondarySettings()
+	if err != nil {
+		setupLog.Error(err, "invalid secondary Bitwarden endpoint")
+		os.Exit(1)
+	}
+
+	var secondaryClientFactory controller.BitwardenClientFactory
+	if secondaryApiUrl != nil && replayTrace == "" {
+		secondaryClientFactory, err = controller.NewBitwardenClientFactoryForBackend(clientBackend, *secondaryApiUrl, *secondaryIdentApiUrl)
+		if err != nil {
+			setupLog.Error(err, "unable to create secondary Bitwarden client factory")
+			os.Exit(1)
+		}
+	}
+
+	if traceFile != "" {
+		trace, err := os.OpenFile(traceFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			setupLog.Error(err, "unable to open trace file", "path", traceFile)
+			os.Exit(1)
+		}
+		defer trace.Close()
+
+		recorder := bwclient.NewTraceRecorder(trace)
+		bwClientFactory = &controller.RecordingClientFactory{
+			BitwardenClientFactory: bwClientFactory,
+			Recorder:               recorder,
+		}
+		if secondaryClientFactory != nil {
+			secondaryClientFactory = &controller.RecordingClientFactory{
+				BitwardenClientFactory: secondaryClientFactory,
+				Recorder:               recorder,
+			}
+		}
+		setupLog.Info("Recording Bitwarden client calls", "path", traceFile)
+	}
+
+	if secondaryClientFactory != nil {
+		bwClientFactory = controller.NewFailoverClientFactory(bwClientFactory, secondaryClientFactory, failoverRetryPrimaryAfter)
+		setupLog.Info("Failing over to the secondary Bitwarden endpoint when the primary is unreachable", "api", *secondaryApiUrl, "identity", *secondaryIdentApiUrl)
+	}
+
+	if err := setStatePersistence(statePersistence, stateKeySecret); err != nil {
+		setupLog.Error(err, "unable to configure state persistence")
+		os.Exit(1)
+	}
+
+	// "manager export ..." prints a BitwardenSecret's rendered secret for GitOps instead of running the operator
+	if flag.Arg(0) == "export" {
+		if err := runExport(flag.Args()[1:], bwClientFactory, *statePath); err != nil {
+			setupLog.Error(err, "unable to export BitwardenSecret")
+			os.Exit(1)
+		}
+		return
+	}
+
+	if targetWriteParallelism < 1 {
+		setupLog.Error(fmt.Errorf("invalid value %d", targetWriteParallelism), "target write parallelism must be at least 1")
+		os.Exit(1)
+	}
+
+	if maxConcurrentReconciles < 1 {
+		setupLog.Error(fmt.Errorf("invalid value %d", maxConcurrentReconciles), "max concurrent reconciles must be at least 1")
+		os.Exit(1)
+	}
+
+	if apiQPS > 0 {
+		if apiBurst < 1 {
+			setupLog.Error(fmt.Errorf("invalid value %d", apiBurst), "api burst must be at least 1")
+			os.Exit(1)
+		}
+		controller.SetAPIRateLimit(apiQPS, apiBurst)
+		setupLog.Info("Rate limiting Bitwarden API calls", "qps", apiQPS, "burst", apiBurst)
+	}
+
+	if circuitBreakerThreshold > 0 {
+		if circuitBreakerCoolDown <= 0 {
+			setupLog.Error(fmt.Errorf("invalid value %s", circuitBreakerCoolDown), "api circuit breaker cool-down must be positive")
+			os.Exit(1)
+		}
+		controller.SetAPICircuitBreaker(circuitBreakerThreshold, circuitBreakerCoolDown)
+		setupLog.Info("Pausing Bitwarden API calls during outages", "threshold", circuitBreakerThreshold, "coolDown", circuitBreakerCoolDown.String())
+	}
+
+	namespacePolicy, err := controller.ParseNamespacePolicy(allowedNamespaces, deniedNamespaces)
+	if err != nil {
+		setupLog.Error(err, "invalid namespace policy")
+		os.Exit(1)
+	}
+	if namespacePolicy != nil {
+		setupLog.Info("Restricting the namespaces BitwardenSecrets sync in", "allowed", namespacePolicy.Allowed, "denied", namespacePolicy.Denied)
+	}
+
+	authTokenFiles := controller.AuthTokenFiles{File: authTokenFile, Dir: authTokenDir}
+	if authTokenFiles.Namespaces, err = controller.ParseAuthTokenNamespaces(authTokenNamespaces); err != nil {
+		setupLog.Error(err, "invalid --auth-token-namespaces")
+		os.Exit(1)
+	}
+	if (authTokenFile != "" || authTokenDir != "") && len(authTokenFiles.Namespaces) == 0 {
+		setupLog.Info("No namespace may use the mounted authorization tokens until --auth-token-namespaces is set")
+	}
+
+	var clientCache *controller.BitwardenClientCache
+	if clientCacheEnabled {
+		if clientResetThreshold < 1 {
+			setupLog.Error(fmt.Errorf("invalid value %d", clientResetThreshold), "client reset threshold must be at least 1")
+			os.Exit(1)
+		}
+		clientCache = controller.NewBitwardenClientCache(clientResetThreshold, clientIdleTimeout, clientSessionTTL)
+	}
+
+	// Served next to the metrics, so that dashboards can list the failing BitwardenSecrets
+	failedSyncs := &controller.FailedSyncsHandler{}
+
+	// Restricting the cache also restricts what the controllers see
+	cacheOptions := GetWatchNamespaces(watchNamespaces)
+	if cacheOptions.DefaultNamespaces != nil {
+		setupLog.Info("Watching only the listed namespaces", "namespaces", watchNamespaces)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
+		Cache:  cacheOptions,
 		Metrics: server.Options{
-			BindAddress: metricsAddr,
+			BindAddress:   metricsAddr,
+			ExtraHandlers: map[string]http.Handler{"/failed-syncs": failedSyncs},
 		},
 		HealthProbeBindAddress: probeAddr,
+		PprofBindAddress:       pprofAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "479cde60.bitwarden.com",
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
@@ -110,14 +421,189 @@ func main() {
 		os.Exit(1)
 	}
 
+	failedSyncs.Reader = mgr.GetClient()
+
+	if err = operatorsv1.IndexSecretName(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to index BitwardenSecrets")
+		os.Exit(1)
+	}
+
+	if profileDir != "" {
+		if err := mgr.Add(&profiling.Capturer{
+			Dir:         profileDir,
+			Interval:    profileInterval,
+			CPUDuration: profileCPUDuration,
+			Keep:        profileKeep,
+		}); err != nil {
+			setupLog.Error(err, "unable to add profile capture")
+			os.Exit(1)
+		}
+	}
+
+	if logLevelConfigMap != "" {
+		namespace, name, ok := strings.Cut(logLevelConfigMap, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Error(fmt.Errorf("%q is not of the form namespace/name", logLevelConfigMap), "invalid log level ConfigMap")
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(&logging.LevelWatcher{
+			Reader:    mgr.GetAPIReader(),
+			ConfigMap: types.NamespacedName{Namespace: namespace, Name: name},
+			Level:     logLevel,
+			Default:   logLevel.Level(),
+			Interval:  30 * time.Second,
+		}); err != nil {
+			setupLog.Error(err, "unable to add log level watcher")
+			os.Exit(1)
+		}
+	}
+
+	if syncTriggerAddr != "" {
+		if syncTriggerTokenFile == "" {
+			setupLog.Error(fmt.Errorf("--sync-trigger-token-file is not set"), "the sync trigger endpoint requires a token")
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(&controller.SyncTriggerServer{
+			Addr:    syncTriggerAddr,
+			Handler: &controller.SyncTriggerHandler{Client: mgr.GetClient(), TokenFile: syncTriggerTokenFile},
+		}); err != nil {
+			setupLog.Error(err, "unable to add sync trigger endpoint")
+			os.Exit(1)
+		}
+	}
+
+	if eventsAPIKeySecret != "" {
+		eventsClient, err := newEventsClient(eventsAPIKeySecret, *bwApiUrl, *identApiUrl)
+		if err != nil {
+			setupLog.Error(err, "unable to set up the organization event log client")
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(&controller.EventSyncer{
+			Client:         mgr.GetClient(),
+			Source:         eventsClient,
+			OrganizationID: eventsClient.OrganizationID(),
+			Interval:       eventsPollInterval,
+		}); err != nil {
+			setupLog.Error(err, "unable to add organization event sync")
+			os.Exit(1)
+		}
+		setupLog.Info("Syncing BitwardenSecrets on organization events", "organization", eventsClient.OrganizationID(), "interval", eventsPollInterval)
+	}
+
+	var pullPool *controller.PullWorkerPool
+	if pullWorkers > 0 {
+		pullPool = controller.NewPullWorkerPool(pullWorkers)
+		if err := mgr.Add(pullPool); err != nil {
+			setupLog.Error(err, "unable to add pull worker pool")
+			os.Exit(1)
+		}
+	}
+
+	if err := mgr.Add(&controller.StateDirCleaner{
+		Reader:         mgr.GetClient(),
+		StatePath:      *statePath,
+		AuthTokenFiles: authTokenFiles,
+		Interval:       time.Hour,
+	}); err != nil {
+		setupLog.Error(err, "unable to add state directory cleanup")
+		os.Exit(1)
+	}
+
+	var apiHealth *controller.APIHealth
+	if apiUnreachableAfter > 0 {
+		apiHealth = &controller.APIHealth{UnreachableAfter: apiUnreachableAfter}
+	}
+
+	// Writes are traced as part of the sync that makes them.  Secret values are scrubbed from their errors before
+	// anything else sees them.
+	k8sClient := controller.RedactingClient(mgr.GetClient())
+	if shutdownTracing != nil {
+		k8sClient = tracing.WrapClient(k8sClient)
+	}
+
+	auditSink, err := audit.NewSink(auditSinkTarget)
+	if err != nil {
+		setupLog.Error(err, "unable to set up the audit sink")
+		os.Exit(1)
+	}
+	if auditSink != nil {
+		// The pod name tells which replica made a change
+		actor, _ := os.Hostname()
+		k8sClient = audit.WrapClient(k8sClient, auditSink, actor)
+		setupLog.Info("Auditing secret changes", "sink", auditSinkTarget)
+	}
+
 	if err = (&controller.BitwardenSecretReconciler{
+		Client:                  k8sClient,
+		Scheme:                  mgr.GetScheme(),
+		BitwardenClientFactory:  bwClientFactory,
+		StatePath:               *statePath,
+		RefreshIntervalSeconds:  *refreshIntervalSeconds,
+		SDKTimeout:              sdkTimeout,
+		ClientCache:             clientCache,
+		PullPool:                pullPool,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		TargetWriteParallelism:  targetWriteParallelism,
+		Recorder:                mgr.GetEventRecorderFor("bitwardensecret-controller"),
+		AuthTokenFiles:          authTokenFiles,
+		APIHealth:               apiHealth,
+		NamespacePolicy:         namespacePolicy,
+		RemoteClusters:          controller.NewRemoteClusterCache(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BitwardenSecret")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		if err = operatorsv1.SetupWebhookWithManager(mgr, time.Duration(*refreshIntervalSeconds)*time.Second); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "BitwardenSecret")
+			os.Exit(1)
+		}
+		if err = injector.SetupWebhookWithManager(mgr, injectorImage); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
+			os.Exit(1)
+		}
+	}
+	if err = (&controller.TargetCollisionReconciler{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TargetCollision")
+		os.Exit(1)
+	}
+	// ClusterBitwardenSecrets write to namespaces across the cluster and belong to a cluster wide install
+	if cacheOptions.DefaultNamespaces != nil {
+		setupLog.Info("Not reconciling ClusterBitwardenSecrets, since only some namespaces are watched")
+	} else if err = (&controller.ClusterBitwardenSecretReconciler{
+		Client:                  k8sClient,
+		Scheme:                  mgr.GetScheme(),
+		BitwardenClientFactory:  bwClientFactory,
+		StatePath:               *statePath,
+		RefreshIntervalSeconds:  *refreshIntervalSeconds,
+		ClientCache:             clientCache,
+		SDKTimeout:              sdkTimeout,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterBitwardenSecret")
+		os.Exit(1)
+	}
+	if err = (&controller.SyncReportReconciler{
 		Client:                 mgr.GetClient(),
-		Scheme:                 mgr.GetScheme(),
+		RefreshIntervalSeconds: *refreshIntervalSeconds,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BitwardenSyncReport")
+		os.Exit(1)
+	}
+	if err = (&controller.ProjectInventoryReconciler{
+		Client:                 k8sClient,
 		BitwardenClientFactory: bwClientFactory,
 		StatePath:              *statePath,
 		RefreshIntervalSeconds: *refreshIntervalSeconds,
+		AuthTokenFiles:         authTokenFiles,
+		NamespacePolicy:        namespacePolicy,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "BitwardenSecret")
+		setupLog.Error(err, "unable to create controller", "controller", "BitwardenProject")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
@@ -130,14 +616,161 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if apiHealth != nil {
+		if err := mgr.AddReadyzCheck("bitwarden-api", apiHealth.Check); err != nil {
+			setupLog.Error(err, "unable to set up Bitwarden API ready check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	if shutdownTracing != nil {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "unable to flush traces")
+		}
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
 
+// GetSecondarySettings returns the optional secondary endpoint pair clients fail over to, or nil when it is not
+// configured.  Both URLs must be set together.
+func GetSecondarySettings() (*string, *string, error) {
+	bwApiUrl := strings.TrimSpace(os.Getenv("BW_SECONDARY_API_URL"))
+	identApiUrl := strings.TrimSpace(os.Getenv("BW_SECONDARY_IDENTITY_API_URL"))
+
+	if bwApiUrl == "" && identApiUrl == "" {
+		return nil, nil, nil
+	}
+
+	if bwApiUrl == "" || identApiUrl == "" {
+		return nil, nil, fmt.Errorf("BW_SECONDARY_API_URL and BW_SECONDARY_IDENTITY_API_URL must be set together")
+	}
+
+	for _, value := range []string{bwApiUrl, identApiUrl} {
+		u, err := url.ParseRequestURI(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, nil, fmt.Errorf("secondary Bitwarden URL is not valid.  Value supplied: %s", value)
+		}
+	}
+
+	return &bwApiUrl, &identApiUrl, nil
+}
+
+// GetWatchNamespaces returns the cache options watching the comma separated namespaces, or every namespace when none
+// are listed.
+func GetWatchNamespaces(list string) cache.Options {
+	options := cache.Options{}
+	for _, namespace := range strings.Split(list, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			if options.DefaultNamespaces == nil {
+				options.DefaultNamespaces = map[string]cache.Config{}
+			}
+			options.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
+
+	return options
+}
+
+func runExport(args []string, bwClientFactory controller.BitwardenClientFactory, statePath string) error {
+	exportOpts, err := export.ParseArgs(args)
+	if err != nil {
+		return err
+	}
+
+	k8sClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	return export.Export(context.Background(), k8sClient, bwClientFactory, statePath, exportOpts, os.Stdout)
+}
+
+// setStatePersistence configures the SDK state persistence, reading the encryption key from keySecret.
+func setStatePersistence(mode string, keySecret string) error {
+	var key []byte
+	if keySecret != "" {
+		namespace, name, ok := strings.Cut(keySecret, "/")
+		if !ok || namespace == "" || name == "" {
+			return fmt.Errorf("state encryption key secret %q is not of the form namespace/name", keySecret)
+		}
+
+		k8sClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+		if err != nil {
+			return err
+		}
+
+		secret := &corev1.Secret{}
+		if err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+			return err
+		}
+
+		key = secret.Data["key"]
+		if len(key) == 0 {
+			return fmt.Errorf("state encryption key secret %s has no \"key\" entry", keySecret)
+		}
+	} else if mode == controller.StatePersistenceEncrypted {
+		return fmt.Errorf("--state-encryption-key-secret is required with the %q state persistence", mode)
+	}
+
+	if err := controller.SetStatePersistence(mode, key); err != nil {
+		return err
+	}
+
+	if mode != controller.StatePersistencePlain {
+		setupLog.Info("Persisting Secrets Manager login state", "mode", mode)
+	}
+	return nil
+}
+
+// newEventsClient creates a client for the organization event log with the API key of keySecret.
+func newEventsClient(keySecret string, bwApiUrl string, identApiUrl string) (*bwclient.EventsClient, error) {
+	namespace, name, ok := strings.Cut(keySecret, "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, fmt.Errorf("organization API key secret %q is not of the form namespace/name", keySecret)
+	}
+
+	k8sClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, err
+	}
+
+	return bwclient.NewEventsClient(bwApiUrl, identApiUrl, strings.TrimSpace(string(secret.Data["clientId"])), strings.TrimSpace(string(secret.Data["clientSecret"])), nil)
+}
+
+func newReplayClientFactory(path string, bwApiUrl string, identApiUrl string) (controller.BitwardenClientFactory, error) {
+	trace, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer trace.Close()
+
+	replayer, err := bwclient.NewTraceReplayer(trace)
+	if err != nil {
+		return nil, err
+	}
+
+	setupLog.Info("Replaying Bitwarden client calls", "path", path, "events", replayer.Remaining())
+	return controller.NewReplayClientFactory(replayer, bwApiUrl, identApiUrl), nil
+}
+
+func defaultClientBackend() string {
+	if bwclient.SDKAvailable {
+		return "sdk"
+	}
+
+	return "rest"
+}
+
 func GetSettings() (*string, *string, *string, *int, error) {
 	bwApiUrl := strings.TrimSpace(os.Getenv("BW_API_URL"))
 	identApiUrl := strings.TrimSpace(os.Getenv("BW_IDENTITY_API_URL"))
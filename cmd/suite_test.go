@@ -149,3 +149,57 @@ var _ = Describe("Get settings", Ordered, func() {
 		Expect(err).Should(BeNil())
 	})
 })
+
+var _ = Describe("Get secondary settings", Ordered, func() {
+	AfterAll(func() {
+		os.Unsetenv("BW_SECONDARY_API_URL")
+		os.Unsetenv("BW_SECONDARY_IDENTITY_API_URL")
+	})
+
+	It("Is disabled by default", func() {
+		os.Setenv("BW_SECONDARY_API_URL", "")
+		os.Setenv("BW_SECONDARY_IDENTITY_API_URL", "")
+		apiUri, identityUri, err := GetSecondarySettings()
+		Expect(apiUri).Should(BeNil())
+		Expect(identityUri).Should(BeNil())
+		Expect(err).Should(BeNil())
+	})
+
+	It("Pulls the secondary endpoint", func() {
+		os.Setenv("BW_SECONDARY_API_URL", "https://api.dr.example.com")
+		os.Setenv("BW_SECONDARY_IDENTITY_API_URL", "https://identity.dr.example.com")
+		apiUri, identityUri, err := GetSecondarySettings()
+		Expect(*apiUri).Should(Equal("https://api.dr.example.com"))
+		Expect(*identityUri).Should(Equal("https://identity.dr.example.com"))
+		Expect(err).Should(BeNil())
+	})
+
+	It("Fails when only one URL is set", func() {
+		os.Setenv("BW_SECONDARY_API_URL", "https://api.dr.example.com")
+		os.Setenv("BW_SECONDARY_IDENTITY_API_URL", "")
+		_, _, err := GetSecondarySettings()
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("Fails on a bad URL", func() {
+		os.Setenv("BW_SECONDARY_API_URL", "https://api.dr.example.com")
+		os.Setenv("BW_SECONDARY_IDENTITY_API_URL", "https:/identity.dr.example.com")
+		_, _, err := GetSecondarySettings()
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("https:/identity.dr.example.com"))
+	})
+})
+
+var _ = Describe("Get watch namespaces", func() {
+	It("Watches every namespace by default", func() {
+		Expect(GetWatchNamespaces("").DefaultNamespaces).Should(BeNil())
+		Expect(GetWatchNamespaces(" , ").DefaultNamespaces).Should(BeNil())
+	})
+
+	It("Watches the listed namespaces", func() {
+		options := GetWatchNamespaces("team-a, team-b,")
+		Expect(options.DefaultNamespaces).Should(HaveLen(2))
+		Expect(options.DefaultNamespaces).Should(HaveKey("team-a"))
+		Expect(options.DefaultNamespaces).Should(HaveKey("team-b"))
+	})
+})
@@ -0,0 +1,136 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+// Package audit records every change the operator makes to Kubernetes secrets: which secret was created, updated, or
+// deleted, when, for which BitwardenSecret, and the names of its keys.  Values are never recorded.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Record describes one write of a Kubernetes secret.
+type Record struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	// The operator instance that made the change, normally the name of its pod
+	Actor     string `json:"actor"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// The resource the secret is written for, such as BitwardenSecret/app or ClusterBitwardenSecret/shared
+	Source string `json:"source,omitempty"`
+	// The keys of the secret after the change, or before it when it was deleted
+	Keys []string `json:"keys,omitempty"`
+	// The error returned by the Kubernetes API if the change failed
+	Error string `json:"error,omitempty"`
+}
+
+// Sink delivers audit records, for example to a log collector or a SIEM.
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+}
+
+// NewSink returns the sink for target, "stdout" for JSON lines on standard output or an http(s) URL that records are
+// posted to.  An empty target disables auditing and returns nil.
+func NewSink(target string) (Sink, error) {
+	switch {
+	case target == "":
+		return nil, nil
+	case target == "stdout":
+		return NewJSONSink(os.Stdout), nil
+	}
+
+	u, err := url.ParseRequestURI(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("audit sink must be \"stdout\" or an http(s) URL.  Value supplied: %s", target)
+	}
+
+	return &HTTPSink{URL: target, Client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// JSONSink writes every record as a line of JSON.
+type JSONSink struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewJSONSink(out io.Writer) *JSONSink {
+	return &JSONSink{out: out}
+}
+
+func (s *JSONSink) Write(ctx context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.out.Write(append(line, '\n'))
+	return err
+}
+
+// HTTPSink posts every record as a JSON object to URL.  Any status other than 2xx is an error.
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *HTTPSink) Write(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit sink %s answered with status %d", s.URL, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// WrapClient returns a client that writes a record to sink for every Secret it creates, updates, patches, or deletes.
+// Writes of other kinds are not audited.  A record that cannot be delivered is logged and does not fail the write.
+func WrapClient(c client.Client, sink Sink, actor string) client.Client {
+	return &auditingClient{Client: c, sink: sink, actor: actor}
+}
+
+type auditingClient struct {
+	client.Client
+	sink  Sink
+	actor string
+}
+
+func (c *auditingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	err := c.Client.Create(ctx, obj, opts...)
+	c.record(ctx, ActionCreate, obj, err)
+	return err
+}
+
+func (c *auditingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	err := c.Client.Update(ctx, obj, opts...)
+	c.record(ctx, ActionUpdate, obj, err)
+	return err
+}
+
+func (c *auditingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	c.record(ctx, ActionUpdate, obj, err)
+	return err
+}
+
+func (c *auditingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	err := c.Client.Delete(ctx, obj, opts...)
+	c.record(ctx, ActionDelete, obj, err)
+	return err
+}
+
+func (c *auditingClient) record(ctx context.Context, action string, obj client.Object, err error) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return
+	}
+
+	record := Record{
+		Time:      time.Now().UTC(),
+		Action:    action,
+		Actor:     c.actor,
+		Namespace: secret.Namespace,
+		Name:      secret.Name,
+		Keys:      SecretKeys(secret),
+	}
+	if owner := metav1.GetControllerOf(secret); owner != nil {
+		record.Source = fmt.Sprintf("%s/%s", owner.Kind, owner.Name)
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if err := c.sink.Write(ctx, record); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to write audit record", "action", action, "secret", fmt.Sprintf("%s/%s", secret.Namespace, secret.Name))
+	}
+}
+
+// SecretKeys returns the sorted names of the keys of the secret.
+func SecretKeys(secret *corev1.Secret) []string {
+	keys := make([]string, 0, len(secret.Data)+len(secret.StringData))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	for key := range secret.StringData {
+		if _, ok := secret.Data[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,161 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAudit(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Audit Suite")
+}
+
+type recordingSink struct {
+	records []Record
+	err     error
+}
+
+func (s *recordingSink) Write(ctx context.Context, record Record) error {
+	s.records = append(s.records, record)
+	return s.err
+}
+
+var _ = Describe("Audit", func() {
+	It("Records secret writes with key names but no values", func() {
+		sink := &recordingSink{}
+		k8sClient := WrapClient(fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(), sink, "sm-operator-0")
+		controller := true
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-secrets", OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "k8s.bitwarden.com/v1", Kind: "BitwardenSecret", Name: "app", UID: "uid", Controller: &controller},
+			}},
+			Data: map[string][]byte{"PASSWORD": []byte("hunter2"), "USER": []byte("admin")},
+		}
+
+		Expect(k8sClient.Create(context.Background(), secret)).Should(Succeed())
+		secret.Data["TOKEN"] = []byte("t")
+		Expect(k8sClient.Update(context.Background(), secret)).Should(Succeed())
+		Expect(k8sClient.Delete(context.Background(), secret)).Should(Succeed())
+		Expect(k8sClient.Delete(context.Background(), secret)).ShouldNot(Succeed())
+
+		// Other kinds are not audited
+		Expect(k8sClient.Create(context.Background(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"}})).Should(Succeed())
+
+		Expect(sink.records).Should(HaveLen(4))
+		created := sink.records[0]
+		Expect(created.Time).ShouldNot(BeZero())
+		created.Time = time.Time{}
+		Expect(created).Should(Equal(Record{
+			Action: ActionCreate, Actor: "sm-operator-0", Namespace: "default", Name: "app-secrets", Source: "BitwardenSecret/app", Keys: []string{"PASSWORD", "USER"},
+		}))
+		Expect(sink.records[1].Action).Should(Equal(ActionUpdate))
+		Expect(sink.records[1].Keys).Should(Equal([]string{"PASSWORD", "TOKEN", "USER"}))
+		Expect(sink.records[2].Action).Should(Equal(ActionDelete))
+		Expect(sink.records[2].Error).Should(BeEmpty())
+		Expect(sink.records[3].Error).Should(ContainSubstring("not found"))
+
+		line, err := json.Marshal(sink.records)
+		Expect(err).Should(BeNil())
+		Expect(string(line)).ShouldNot(ContainSubstring("hunter2"))
+	})
+
+	It("Does not fail writes when the sink fails", func() {
+		sink := &recordingSink{err: fmt.Errorf("sink unavailable")}
+		k8sClient := WrapClient(fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(), sink, "sm-operator-0")
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-secrets"}}
+		Expect(k8sClient.Create(context.Background(), secret)).Should(Succeed())
+		Expect(k8sClient.Patch(context.Background(), secret, client.MergeFrom(secret.DeepCopy()))).Should(Succeed())
+		Expect(k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "app-secrets"}, secret)).Should(Succeed())
+		Expect(sink.records).Should(HaveLen(2))
+	})
+
+	It("Writes JSON lines", func() {
+		out := &bytes.Buffer{}
+		sink := NewJSONSink(out)
+
+		Expect(sink.Write(context.Background(), Record{Action: ActionCreate, Namespace: "default", Name: "a"})).Should(Succeed())
+		Expect(sink.Write(context.Background(), Record{Action: ActionDelete, Namespace: "default", Name: "b"})).Should(Succeed())
+
+		lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+		Expect(lines).Should(HaveLen(2))
+		record := Record{}
+		Expect(json.Unmarshal(lines[1], &record)).Should(Succeed())
+		Expect(record.Action).Should(Equal(ActionDelete))
+		Expect(record.Name).Should(Equal("b"))
+	})
+
+	It("Posts records to an HTTP endpoint", func() {
+		received := []Record{}
+		status := http.StatusAccepted
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Method).Should(Equal(http.MethodPost))
+			Expect(req.Header.Get("Content-Type")).Should(Equal("application/json"))
+			body, _ := io.ReadAll(req.Body)
+			record := Record{}
+			Expect(json.Unmarshal(body, &record)).Should(Succeed())
+			received = append(received, record)
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		sink, err := NewSink(server.URL)
+		Expect(err).Should(BeNil())
+		Expect(sink.Write(context.Background(), Record{Action: ActionUpdate, Name: "app-secrets"})).Should(Succeed())
+		Expect(received).Should(HaveLen(1))
+		Expect(received[0].Name).Should(Equal("app-secrets"))
+
+		status = http.StatusInternalServerError
+		Expect(sink.Write(context.Background(), Record{Action: ActionUpdate})).Should(MatchError(ContainSubstring("answered with status 500")))
+	})
+
+	It("Selects the sink from its target", func() {
+		sink, err := NewSink("")
+		Expect(err).Should(BeNil())
+		Expect(sink).Should(BeNil())
+
+		sink, err = NewSink("stdout")
+		Expect(err).Should(BeNil())
+		Expect(sink).Should(BeAssignableToTypeOf(&JSONSink{}))
+
+		_, err = NewSink("syslog")
+		Expect(err).Should(MatchError(ContainSubstring("must be \"stdout\" or an http(s) URL")))
+	})
+})
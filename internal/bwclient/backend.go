@@ -0,0 +1,77 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Backend creates an unauthenticated client for the given API and identity URLs.  Alternative server
+// implementations (self-hosted variants, Vaultwarden compatible endpoints, mock servers) can be supported by
+// registering a Backend rather than changing the controller.
+type Backend func(apiUrl string, identityUrl string) (BitwardenClientInterface, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{}
+)
+
+// RegisterBackend makes a backend available under the given name.  It is intended to be called from init
+// functions and panics if the name is empty, the backend is nil, or the name is already registered.
+func RegisterBackend(name string, backend Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if name == "" || backend == nil {
+		panic("bwclient: RegisterBackend requires a name and a backend")
+	}
+
+	if _, exists := backends[name]; exists {
+		panic(fmt.Sprintf("bwclient: backend %q is already registered", name))
+	}
+
+	backends[name] = backend
+}
+
+// LookupBackend returns the backend registered under name.
+func LookupBackend(name string) (Backend, bool) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	backend, ok := backends[name]
+	return backend, ok
+}
+
+// Backends returns the sorted names of all registered backends.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,164 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CABackend creates an unauthenticated client that trusts the certificate authorities of a PEM encoded bundle in
+// addition to the system roots, for self-hosted servers with certificates issued by a private CA.
+type CABackend func(apiUrl string, identityUrl string, caBundle []byte) (BitwardenClientInterface, error)
+
+var (
+	caBackendsMu sync.RWMutex
+	caBackends   = map[string]CABackend{}
+
+	// The roots trusted by REST clients created without an HTTP client, set by UseCABundle
+	defaultRootCAs *x509.CertPool
+)
+
+func init() {
+	RegisterCABackend("rest", NewRestClientWithCABundle)
+}
+
+// RegisterCABackend makes a backend that supports per-client CA bundles available under the name of the backend.
+func RegisterCABackend(name string, backend CABackend) {
+	caBackendsMu.Lock()
+	defer caBackendsMu.Unlock()
+
+	if name == "" || backend == nil {
+		panic("bwclient: RegisterCABackend requires a name and a backend")
+	}
+
+	if _, exists := caBackends[name]; exists {
+		panic(fmt.Sprintf("bwclient: CA backend %q is already registered", name))
+	}
+
+	caBackends[name] = backend
+}
+
+// LookupCABackend returns the per-client CA bundle support of the backend registered under name.
+func LookupCABackend(name string) (CABackend, bool) {
+	caBackendsMu.RLock()
+	defer caBackendsMu.RUnlock()
+
+	backend, ok := caBackends[name]
+	return backend, ok
+}
+
+// CertPool returns the system roots extended with the certificates of a PEM encoded bundle.
+func CertPool(caBundle []byte) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, fmt.Errorf("the CA bundle does not contain any PEM encoded certificates")
+	}
+
+	return pool, nil
+}
+
+// NewRestClientWithCABundle creates a REST backed client that trusts the certificates of caBundle.
+func NewRestClientWithCABundle(apiUrl string, identityUrl string, caBundle []byte) (BitwardenClientInterface, error) {
+	pool, err := CertPool(caBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRestClient(apiUrl, identityUrl, newHTTPClient(pool)), nil
+}
+
+// newHTTPClient returns an HTTP client with a 30 second timeout that trusts the given roots, or the system roots if
+// nil.
+func newHTTPClient(rootCAs *x509.CertPool) *http.Client {
+	if rootCAs == nil {
+		return &http.Client{Timeout: 30 * time.Second}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+
+	return &http.Client{Timeout: 30 * time.Second, Transport: transport}
+}
+
+// UseCABundle makes every client of the operator trust the certificates of the PEM encoded bundle at path.  It must
+// be called before any client is created.  The
+// native SDK reads its trusted certificates from SSL_CERT_FILE, which then replaces the system roots, so the bundle
+// must hold every CA the operator connects to.
+func UseCABundle(path string) error {
+	caBundle, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	pool, err := CertPool(caBundle)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	defaultRootCAs = pool
+	return os.Setenv("SSL_CERT_FILE", path)
+}
+
+// Fragments of native SDK error messages that indicate the server certificate could not be verified.
+var sdkCertificateErrorFragments = []string{
+	"certificate verify failed",
+	"invalid peer certificate",
+	"unknownissuer",
+	"self signed certificate",
+	"self-signed certificate",
+}
+
+// IsCertificateError reports whether err indicates that the certificate of the Bitwarden server could not be
+// verified, typically because it was issued by a CA the operator does not trust.
+func IsCertificateError(err error) bool {
+	if err == nil || IsPanic(err) {
+		return false
+	}
+
+	var verificationErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &verificationErr) || errors.As(err, &authorityErr) || errors.As(err, &invalidErr) || errors.As(err, &hostnameErr) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, fragment := range sdkCertificateErrorFragments {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,32 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+// Package bwclient contains the Bitwarden Secrets Manager client backends used by the operator.
+//
+// The client interfaces and response types mirror those of the native Bitwarden SDK.  When the
+// operator is built with cgo they are aliases of the SDK types, so either backend can be handed
+// to the controller interchangeably.  Without cgo only the REST backend is available.
+package bwclient
+
+// DeviceType is the Bitwarden device type identifier reported by SDK based clients.
+const DeviceType = "21"
+
+// UserAgent is sent with every request made by the REST backend.
+const UserAgent = "Bitwarden sm-operator"
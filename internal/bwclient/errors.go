@@ -0,0 +1,214 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Patterns of HTTP status codes in native SDK error messages, such as "status 429", "status code: 404", or
+// "[401 Unauthorized]".  A bare number is never taken for a status code, since the IDs, ports, and counts in a
+// message may contain one.
+var (
+	sdkStatusPattern     = regexp.MustCompile(`(?:\bstatus(?: code)?:? ?|\[)([1-5][0-9]{2})\b`)
+	sdkStatusTextPattern = regexp.MustCompile(`\b([1-5][0-9]{2}) ([a-z][a-z' -]*)`)
+)
+
+// sdkStatusCodes returns the HTTP status codes named in the lowercase message of a native SDK error.
+func sdkStatusCodes(message string) []int {
+	var codes []int
+	for _, match := range sdkStatusPattern.FindAllStringSubmatch(message, -1) {
+		code, _ := strconv.Atoi(match[1])
+		codes = append(codes, code)
+	}
+	for _, match := range sdkStatusTextPattern.FindAllStringSubmatch(message, -1) {
+		code, _ := strconv.Atoi(match[1])
+		if text := strings.ToLower(http.StatusText(code)); text != "" && strings.HasPrefix(match[2], text) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// hasSdkStatusCode reports whether the lowercase message of a native SDK error names a status code that matches.
+func hasSdkStatusCode(message string, matches func(code int) bool) bool {
+	for _, code := range sdkStatusCodes(message) {
+		if matches(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fragments of native SDK error messages that indicate the access token or session was rejected.  The SDK only
+// returns plain string errors, so these are matched against the message.
+var sdkAuthErrorFragments = []string{
+	"unauthorized",
+	"invalid_client",
+	"invalid_grant",
+}
+
+// IsAuthError reports whether err indicates that Bitwarden rejected the credentials or session used for a call,
+// meaning a new AccessTokenLogin is required before retrying.  A 403 response is not an auth error: the session is
+// valid, but the machine account lacks access, which a new login does not change.
+func IsAuthError(err error) bool {
+	if err == nil || IsPanic(err) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusUnauthorized
+	}
+
+	message := strings.ToLower(err.Error())
+	if hasSdkStatusCode(message, func(code int) bool { return code == http.StatusUnauthorized }) {
+		return true
+	}
+	for _, fragment := range sdkAuthErrorFragments {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Fragments of native SDK error messages that indicate the server could not be reached at all.
+var sdkUnreachableErrorFragments = []string{
+	"error sending request",
+	"error trying to connect",
+	"connection refused",
+	"dns error",
+	"timed out",
+}
+
+// IsUnreachableError reports whether err indicates that the Bitwarden endpoint could not be reached or is
+// unavailable, as opposed to rejecting the call.
+func IsUnreachableError(err error) bool {
+	if err == nil || IsPanic(err) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusBadGateway || apiErr.StatusCode == http.StatusServiceUnavailable || apiErr.StatusCode == http.StatusGatewayTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, fragment := range sdkUnreachableErrorFragments {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Fragments of native SDK error messages that indicate the requested organization or secret does not exist.
+var sdkNotFoundErrorFragments = []string{
+	"not found",
+}
+
+// IsNotFoundError reports whether err indicates that Bitwarden has no resource by the requested ID, or none the
+// machine account can access.
+func IsNotFoundError(err error) bool {
+	if err == nil || IsPanic(err) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+
+	message := strings.ToLower(err.Error())
+	if hasSdkStatusCode(message, func(code int) bool { return code == http.StatusNotFound }) {
+		return true
+	}
+	for _, fragment := range sdkNotFoundErrorFragments {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsForbiddenError reports whether err indicates that Bitwarden accepted the session, but the machine account may not
+// access the requested resource.
+func IsForbiddenError(err error) bool {
+	if err == nil || IsPanic(err) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusForbidden
+	}
+
+	return hasSdkStatusCode(strings.ToLower(err.Error()), func(code int) bool { return code == http.StatusForbidden })
+}
+
+// Fragments of native SDK error messages that indicate the server failed or throttled the call.
+var sdkServerErrorFragments = []string{
+	"too many requests",
+}
+
+// IsServerError reports whether err indicates that Bitwarden failed to handle or throttled a valid call, which
+// usually succeeds when retried.
+func IsServerError(err error) bool {
+	if err == nil || IsPanic(err) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return isServerStatus(apiErr.StatusCode)
+	}
+
+	message := strings.ToLower(err.Error())
+	if hasSdkStatusCode(message, isServerStatus) {
+		return true
+	}
+	for _, fragment := range sdkServerErrorFragments {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isServerStatus reports whether an HTTP status code means the server failed or throttled the call.
+func isServerStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
@@ -0,0 +1,176 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of the organization events that change a Secrets Manager secret.  Secret events range from 2100 to 2199, and
+// 2100 is the retrieval of a secret, which every sync of the operator records itself.
+const (
+	EventSecretRetrieved = 2100
+	EventSecretCreated   = 2101
+	EventSecretEdited    = 2102
+	EventSecretDeleted   = 2103
+)
+
+// IsSecretChange reports whether the event type is a change to a secret, as opposed to the retrieval of one.
+func IsSecretChange(eventType int) bool {
+	return eventType > EventSecretRetrieved && eventType < 2200
+}
+
+// OrganizationEvent is an event of the Bitwarden public API event log.
+type OrganizationEvent struct {
+	Type      int       `json:"type"`
+	ItemID    string    `json:"itemId,omitempty"`
+	SecretID  string    `json:"secretId,omitempty"`
+	ProjectID string    `json:"projectId,omitempty"`
+	Date      time.Time `json:"date"`
+}
+
+type eventListModel struct {
+	Data              []OrganizationEvent `json:"data"`
+	ContinuationToken string              `json:"continuationToken"`
+}
+
+type organizationTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// EventsClient reads the event log of an organization from the Bitwarden public API, authenticating with the API key
+// of the organization, whose client ID is organization.<organization ID>.  Machine account access tokens cannot read
+// the event log.
+type EventsClient struct {
+	rest         *RestClient
+	clientId     string
+	clientSecret string
+
+	mu          sync.Mutex
+	bearerToken string
+	expires     time.Time
+}
+
+// NewEventsClient creates a client for the event log.  If httpClient is nil a client with a 30 second timeout is used.
+func NewEventsClient(apiUrl string, identityUrl string, clientId string, clientSecret string, httpClient *http.Client) (*EventsClient, error) {
+	if !strings.HasPrefix(clientId, "organization.") {
+		return nil, fmt.Errorf("the client ID %q is not the client ID of an organization API key", clientId)
+	}
+
+	return &EventsClient{
+		rest:         NewRestClient(apiUrl, identityUrl, httpClient).(*RestClient),
+		clientId:     clientId,
+		clientSecret: clientSecret,
+	}, nil
+}
+
+// OrganizationID returns the ID of the organization the API key belongs to.
+func (c *EventsClient) OrganizationID() string {
+	return strings.TrimPrefix(c.clientId, "organization.")
+}
+
+// Events returns the events of the organization between start and end, following continuation tokens.
+func (c *EventsClient) Events(start time.Time, end time.Time) ([]OrganizationEvent, error) {
+	events := []OrganizationEvent{}
+	continuationToken := ""
+
+	for {
+		query := url.Values{}
+		query.Set("start", start.UTC().Format(time.RFC3339Nano))
+		query.Set("end", end.UTC().Format(time.RFC3339Nano))
+		if continuationToken != "" {
+			query.Set("continuationToken", continuationToken)
+		}
+
+		var page eventListModel
+		if err := c.get("/public/events?"+query.Encode(), &page); err != nil {
+			return nil, err
+		}
+		events = append(events, page.Data...)
+
+		if page.ContinuationToken == "" {
+			return events, nil
+		}
+		continuationToken = page.ContinuationToken
+	}
+}
+
+// get sends an authenticated request, logging in again once when the bearer token was rejected.
+func (c *EventsClient) get(path string, target interface{}) error {
+	for attempt := 0; ; attempt++ {
+		token, err := c.token(attempt > 0)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(http.MethodGet, c.rest.apiUrl+path, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		err = c.rest.do(req, target)
+		var apiErr *APIError
+		if attempt == 0 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			continue
+		}
+		return err
+	}
+}
+
+// token returns the bearer token of the organization, logging in when there is none, it expires within a minute, or
+// renew is set.
+func (c *EventsClient) token(renew bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !renew && c.bearerToken != "" && time.Now().Add(time.Minute).Before(c.expires) {
+		return c.bearerToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("scope", "api.organization")
+	form.Set("client_id", c.clientId)
+	form.Set("client_secret", c.clientSecret)
+	form.Set("grant_type", "client_credentials")
+
+	req, err := http.NewRequest(http.MethodPost, c.rest.identityUrl+"/connect/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	var response organizationTokenResponse
+	if err := c.rest.do(req, &response); err != nil {
+		return "", err
+	}
+
+	c.bearerToken = response.AccessToken
+	c.expires = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	return c.bearerToken, nil
+}
@@ -0,0 +1,164 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError is returned when a client call panicked in Go code, for example in the bindings of the native
+// SDK.  Aborts inside the native library itself cannot be recovered.  The client that produced it is in an
+// unknown state and must be discarded rather than reused.
+type PanicError struct {
+	Operation string
+	Value     interface{}
+	Stack     []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("Bitwarden client panicked during %s: %v", e.Operation, e.Value)
+}
+
+// IsPanic reports whether err was produced by a recovered client panic.
+func IsPanic(err error) bool {
+	var panicErr *PanicError
+	return errors.As(err, &panicErr)
+}
+
+// Recover runs fn and converts a panic into a *PanicError.
+func Recover(operation string, fn func() error) (err error) {
+	defer recoverPanic(operation, &err)
+	return fn()
+}
+
+func recoverPanic(operation string, err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Operation: operation, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// NewRecoveringClient wraps a client so that panics from any call are returned as a *PanicError instead of
+// unwinding into the caller.
+func NewRecoveringClient(inner BitwardenClientInterface) BitwardenClientInterface {
+	return &recoveringClient{
+		inner:    inner,
+		secrets:  &recoveringSecrets{inner: inner},
+		projects: &recoveringProjects{inner: inner},
+	}
+}
+
+type recoveringClient struct {
+	inner    BitwardenClientInterface
+	secrets  *recoveringSecrets
+	projects *recoveringProjects
+}
+
+func (c *recoveringClient) AccessTokenLogin(accessToken string, statePath *string) (err error) {
+	defer recoverPanic("AccessTokenLogin", &err)
+	return c.inner.AccessTokenLogin(accessToken, statePath)
+}
+
+func (c *recoveringClient) Projects() ProjectsInterface {
+	return c.projects
+}
+
+func (c *recoveringClient) Secrets() SecretsInterface {
+	return c.secrets
+}
+
+// Close releases the inner client.  A panic while closing cannot be reported, so it is discarded.
+func (c *recoveringClient) Close() {
+	defer func() {
+		_ = recover()
+	}()
+	c.inner.Close()
+}
+
+type recoveringSecrets struct {
+	inner BitwardenClientInterface
+}
+
+func (s *recoveringSecrets) Create(key, value, note string, organizationID string, projectIDs []string) (res *SecretResponse, err error) {
+	defer recoverPanic("Secrets().Create", &err)
+	return s.inner.Secrets().Create(key, value, note, organizationID, projectIDs)
+}
+
+func (s *recoveringSecrets) List(organizationID string) (res *SecretIdentifiersResponse, err error) {
+	defer recoverPanic("Secrets().List", &err)
+	return s.inner.Secrets().List(organizationID)
+}
+
+func (s *recoveringSecrets) Get(secretID string) (res *SecretResponse, err error) {
+	defer recoverPanic("Secrets().Get", &err)
+	return s.inner.Secrets().Get(secretID)
+}
+
+func (s *recoveringSecrets) GetByIDS(secretIDs []string) (res *SecretsResponse, err error) {
+	defer recoverPanic("Secrets().GetByIDS", &err)
+	return s.inner.Secrets().GetByIDS(secretIDs)
+}
+
+func (s *recoveringSecrets) Update(secretID string, key, value, note string, organizationID string, projectIDs []string) (res *SecretResponse, err error) {
+	defer recoverPanic("Secrets().Update", &err)
+	return s.inner.Secrets().Update(secretID, key, value, note, organizationID, projectIDs)
+}
+
+func (s *recoveringSecrets) Delete(secretIDs []string) (res *SecretsDeleteResponse, err error) {
+	defer recoverPanic("Secrets().Delete", &err)
+	return s.inner.Secrets().Delete(secretIDs)
+}
+
+func (s *recoveringSecrets) Sync(organizationID string, lastSyncedDate *time.Time) (res *SecretsSyncResponse, err error) {
+	defer recoverPanic("Secrets().Sync", &err)
+	return s.inner.Secrets().Sync(organizationID, lastSyncedDate)
+}
+
+type recoveringProjects struct {
+	inner BitwardenClientInterface
+}
+
+func (p *recoveringProjects) Create(organizationID string, name string) (res *ProjectResponse, err error) {
+	defer recoverPanic("Projects().Create", &err)
+	return p.inner.Projects().Create(organizationID, name)
+}
+
+func (p *recoveringProjects) List(organizationID string) (res *ProjectsResponse, err error) {
+	defer recoverPanic("Projects().List", &err)
+	return p.inner.Projects().List(organizationID)
+}
+
+func (p *recoveringProjects) Get(projectID string) (res *ProjectResponse, err error) {
+	defer recoverPanic("Projects().Get", &err)
+	return p.inner.Projects().Get(projectID)
+}
+
+func (p *recoveringProjects) Update(projectID string, organizationID string, name string) (res *ProjectResponse, err error) {
+	defer recoverPanic("Projects().Update", &err)
+	return p.inner.Projects().Update(projectID, organizationID, name)
+}
+
+func (p *recoveringProjects) Delete(projectIDs []string) (res *ProjectsDeleteResponse, err error) {
+	defer recoverPanic("Projects().Delete", &err)
+	return p.inner.Projects().Delete(projectIDs)
+}
@@ -0,0 +1,208 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// APIError is returned by the REST backend when the Bitwarden API responds with a non-success status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %d %s", e.StatusCode, e.Message)
+}
+
+// RestClient talks to the Secrets Manager REST API directly, without the native SDK.  It only supports
+// the read operations the operator needs; write operations return an error.
+type RestClient struct {
+	apiUrl      string
+	identityUrl string
+	httpClient  *http.Client
+
+	bearerToken string
+	orgKey      *symmetricKey
+	secrets     *restSecrets
+	projects    *restProjects
+}
+
+func init() {
+	RegisterBackend("rest", func(apiUrl string, identityUrl string) (BitwardenClientInterface, error) {
+		return NewRestClient(apiUrl, identityUrl, nil), nil
+	})
+}
+
+// NewRestClient creates a REST backed client.  If httpClient is nil a client with a 30 second timeout is used.
+func NewRestClient(apiUrl string, identityUrl string, httpClient *http.Client) BitwardenClientInterface {
+	if httpClient == nil {
+		httpClient = newHTTPClient(defaultRootCAs)
+	}
+
+	c := &RestClient{
+		apiUrl:      strings.TrimRight(apiUrl, "/"),
+		identityUrl: strings.TrimRight(identityUrl, "/"),
+		httpClient:  httpClient,
+	}
+	c.secrets = &restSecrets{client: c}
+	c.projects = &restProjects{client: c}
+
+	return c
+}
+
+type identityTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	EncryptedPayload string `json:"encrypted_payload"`
+}
+
+type accessTokenPayload struct {
+	EncryptionKey string `json:"encryptionKey"`
+}
+
+// AccessTokenLogin exchanges the machine account access token for a bearer token and decrypts the organization
+// key.  The REST backend keeps its session in memory, so statePath is ignored.
+func (c *RestClient) AccessTokenLogin(accessToken string, statePath *string) error {
+	token, err := parseAccessToken(accessToken)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("scope", "api.secrets")
+	form.Set("client_id", token.ClientId)
+	form.Set("client_secret", token.ClientSecret)
+	form.Set("grant_type", "client_credentials")
+
+	req, err := http.NewRequest(http.MethodPost, c.identityUrl+"/connect/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	req.Header.Set("Device-Type", DeviceType)
+
+	var response identityTokenResponse
+	if err := c.do(req, &response); err != nil {
+		return err
+	}
+
+	payload, err := token.EncryptionKey.decryptString(response.EncryptedPayload)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt login payload: %w", err)
+	}
+
+	var decoded accessTokenPayload
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return fmt.Errorf("failed to parse login payload: %w", err)
+	}
+
+	rawKey, err := base64.StdEncoding.DecodeString(decoded.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("organization encryption key is not valid base64")
+	}
+
+	orgKey, err := newSymmetricKey(rawKey)
+	if err != nil {
+		return err
+	}
+
+	c.bearerToken = response.AccessToken
+	c.orgKey = orgKey
+	return nil
+}
+
+func (c *RestClient) Projects() ProjectsInterface {
+	return c.projects
+}
+
+func (c *RestClient) Secrets() SecretsInterface {
+	return c.secrets
+}
+
+// Close drops the in-memory session.
+func (c *RestClient) Close() {
+	c.bearerToken = ""
+	c.orgKey = nil
+}
+
+func (c *RestClient) apiRequest(method string, path string, body interface{}, target interface{}) error {
+	if c.orgKey == nil {
+		return fmt.Errorf("client is not authenticated")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.apiUrl+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.do(req, target)
+}
+
+func (c *RestClient) do(req *http.Request, target interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	}
+
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
+}
+
+func errNotSupported(operation string) error {
+	return fmt.Errorf("%s is not supported by the REST client backend", operation)
+}
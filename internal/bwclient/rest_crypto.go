@@ -0,0 +1,190 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// newHash is the digest of the key derivation and MACs of the REST backend.  SHA-256 is what Bitwarden uses; the
+// operator replaces it with SetHash so that all of its hashing goes through one FIPS-aware implementation.
+var newHash func() hash.Hash = sha256.New
+
+// SetHash replaces the SHA-256 implementation of the REST backend.  It must be called before any client is created.
+func SetHash(sha256 func() hash.Hash) {
+	newHash = sha256
+}
+
+// accessToken is a parsed Secrets Manager machine account access token of the form
+// "0.<client id>.<client secret>:<base64 encryption key>".
+type accessToken struct {
+	ClientId     string
+	ClientSecret string
+	// Key used to decrypt the payload returned by the identity service on login
+	EncryptionKey *symmetricKey
+}
+
+// symmetricKey is a Bitwarden AES-256-CBC + HMAC-SHA256 key pair.
+type symmetricKey struct {
+	EncKey []byte
+	MacKey []byte
+}
+
+func parseAccessToken(token string) (*accessToken, error) {
+	first, encodedKey, found := strings.Cut(token, ":")
+	if !found {
+		return nil, fmt.Errorf("access token is malformed")
+	}
+
+	parts := strings.Split(first, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("access token is malformed")
+	}
+
+	if parts[0] != "0" {
+		return nil, fmt.Errorf("access token version %q is not supported", parts[0])
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("access token encryption key is not valid base64")
+	}
+
+	if len(seed) != 16 {
+		return nil, fmt.Errorf("access token encryption key must be 16 bytes, got %d", len(seed))
+	}
+
+	return &accessToken{
+		ClientId:      parts[1],
+		ClientSecret:  parts[2],
+		EncryptionKey: deriveShareableKey(seed, "accesstoken", "sm-access-token"),
+	}, nil
+}
+
+// deriveShareableKey matches the SDK key derivation: HMAC-SHA256 keyed by "bitwarden-<name>" produces
+// the pseudo random key which is then expanded to 64 bytes with HKDF-SHA256.
+func deriveShareableKey(secret []byte, name string, info string) *symmetricKey {
+	mac := hmac.New(newHash, []byte("bitwarden-"+name))
+	mac.Write(secret)
+	prk := mac.Sum(nil)
+
+	key := hkdfExpand(prk, []byte(info), 64)
+	return &symmetricKey{EncKey: key[:32], MacKey: key[32:]}
+}
+
+func hkdfExpand(prk []byte, info []byte, length int) []byte {
+	out := make([]byte, 0, length)
+	var previous []byte
+
+	for counter := byte(1); len(out) < length; counter++ {
+		mac := hmac.New(newHash, prk)
+		mac.Write(previous)
+		mac.Write(info)
+		mac.Write([]byte{counter})
+		previous = mac.Sum(nil)
+		out = append(out, previous...)
+	}
+
+	return out[:length]
+}
+
+func newSymmetricKey(raw []byte) (*symmetricKey, error) {
+	if len(raw) != 64 {
+		return nil, fmt.Errorf("organization encryption key must be 64 bytes, got %d", len(raw))
+	}
+
+	return &symmetricKey{EncKey: raw[:32], MacKey: raw[32:]}, nil
+}
+
+// decryptString decrypts a Bitwarden EncString.  Only type 2 (AesCbc256_HmacSha256_B64), which is
+// what Secrets Manager uses for all secret and project fields, is supported.
+func (k *symmetricKey) decryptString(encString string) ([]byte, error) {
+	encType, rest, found := strings.Cut(encString, ".")
+	if !found {
+		return nil, fmt.Errorf("encrypted value is malformed")
+	}
+
+	if encType != "2" {
+		return nil, fmt.Errorf("encryption type %s is not supported", encType)
+	}
+
+	parts := strings.Split(rest, "|")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("encrypted value is malformed")
+	}
+
+	var decoded [3][]byte
+	for i, part := range parts {
+		value, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return nil, fmt.Errorf("encrypted value is not valid base64")
+		}
+		decoded[i] = value
+	}
+	iv, data, tag := decoded[0], decoded[1], decoded[2]
+
+	mac := hmac.New(newHash, k.MacKey)
+	mac.Write(iv)
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return nil, fmt.Errorf("encrypted value failed MAC validation")
+	}
+
+	block, err := aes.NewCipher(k.EncKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iv) != aes.BlockSize || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted value has an invalid length")
+	}
+
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("encrypted value has invalid padding")
+	}
+
+	return plain[:len(plain)-padding], nil
+}
+
+func (k *symmetricKey) decryptToString(encString string) (string, error) {
+	if encString == "" {
+		return "", nil
+	}
+
+	value, err := k.decryptString(encString)
+	if err != nil {
+		return "", err
+	}
+
+	return string(value), nil
+}
@@ -0,0 +1,96 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"net/http"
+	"net/url"
+)
+
+type restProjects struct {
+	client *RestClient
+}
+
+type projectModel struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organizationId"`
+	Name           string `json:"name"`
+	CreationDate   string `json:"creationDate"`
+	RevisionDate   string `json:"revisionDate"`
+}
+
+type projectListModel struct {
+	Data []projectModel `json:"data"`
+}
+
+func (p *restProjects) decrypt(model projectModel) (*ProjectResponse, error) {
+	name, err := p.client.orgKey.decryptToString(model.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProjectResponse{
+		ID:             model.ID,
+		OrganizationID: model.OrganizationID,
+		Name:           name,
+		CreationDate:   model.CreationDate,
+		RevisionDate:   model.RevisionDate,
+	}, nil
+}
+
+func (p *restProjects) Create(organizationID string, name string) (*ProjectResponse, error) {
+	return nil, errNotSupported("Creating projects")
+}
+
+func (p *restProjects) List(organizationID string) (*ProjectsResponse, error) {
+	var model projectListModel
+	if err := p.client.apiRequest(http.MethodGet, "/organizations/"+url.PathEscape(organizationID)+"/projects", nil, &model); err != nil {
+		return nil, err
+	}
+
+	projects := make([]ProjectResponse, 0, len(model.Data))
+	for _, item := range model.Data {
+		project, err := p.decrypt(item)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, *project)
+	}
+
+	return &ProjectsResponse{Data: projects}, nil
+}
+
+func (p *restProjects) Get(projectID string) (*ProjectResponse, error) {
+	var model projectModel
+	if err := p.client.apiRequest(http.MethodGet, "/projects/"+url.PathEscape(projectID), nil, &model); err != nil {
+		return nil, err
+	}
+
+	return p.decrypt(model)
+}
+
+func (p *restProjects) Update(projectID string, organizationID string, name string) (*ProjectResponse, error) {
+	return nil, errNotSupported("Updating projects")
+}
+
+func (p *restProjects) Delete(projectIDs []string) (*ProjectsDeleteResponse, error) {
+	return nil, errNotSupported("Deleting projects")
+}
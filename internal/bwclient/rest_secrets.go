@@ -0,0 +1,184 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type restSecrets struct {
+	client *RestClient
+}
+
+type secretProjectModel struct {
+	ID string `json:"id"`
+}
+
+type secretModel struct {
+	ID             string               `json:"id"`
+	OrganizationID string               `json:"organizationId"`
+	Key            string               `json:"key"`
+	Value          string               `json:"value"`
+	Note           string               `json:"note"`
+	CreationDate   string               `json:"creationDate"`
+	RevisionDate   string               `json:"revisionDate"`
+	Projects       []secretProjectModel `json:"projects"`
+}
+
+type secretListModel struct {
+	Data []secretModel `json:"data"`
+}
+
+type secretsSyncModel struct {
+	HasChanges bool             `json:"hasChanges"`
+	Secrets    *secretListModel `json:"secrets"`
+}
+
+type secretIdentifiersModel struct {
+	Secrets []secretModel `json:"secrets"`
+}
+
+func (s *restSecrets) decrypt(model secretModel) (*SecretResponse, error) {
+	key := s.client.orgKey
+
+	name, err := key.decryptToString(model.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := key.decryptToString(model.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := key.decryptToString(model.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SecretResponse{
+		ID:             model.ID,
+		OrganizationID: model.OrganizationID,
+		Key:            name,
+		Value:          value,
+		Note:           note,
+		CreationDate:   model.CreationDate,
+		RevisionDate:   model.RevisionDate,
+	}
+
+	if len(model.Projects) > 0 {
+		projectId := model.Projects[0].ID
+		response.ProjectID = &projectId
+	}
+
+	return response, nil
+}
+
+func (s *restSecrets) decryptAll(models []secretModel) ([]SecretResponse, error) {
+	secrets := make([]SecretResponse, 0, len(models))
+	for _, model := range models {
+		secret, err := s.decrypt(model)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, *secret)
+	}
+
+	return secrets, nil
+}
+
+func (s *restSecrets) Create(key, value, note string, organizationID string, projectIDs []string) (*SecretResponse, error) {
+	return nil, errNotSupported("Creating secrets")
+}
+
+func (s *restSecrets) List(organizationID string) (*SecretIdentifiersResponse, error) {
+	var model secretIdentifiersModel
+	if err := s.client.apiRequest(http.MethodGet, "/organizations/"+url.PathEscape(organizationID)+"/secrets", nil, &model); err != nil {
+		return nil, err
+	}
+
+	identifiers := make([]SecretIdentifierResponse, 0, len(model.Secrets))
+	for _, secret := range model.Secrets {
+		name, err := s.client.orgKey.decryptToString(secret.Key)
+		if err != nil {
+			return nil, err
+		}
+		identifiers = append(identifiers, SecretIdentifierResponse{ID: secret.ID, Key: name, OrganizationID: secret.OrganizationID})
+	}
+
+	return &SecretIdentifiersResponse{Data: identifiers}, nil
+}
+
+func (s *restSecrets) Get(secretID string) (*SecretResponse, error) {
+	var model secretModel
+	if err := s.client.apiRequest(http.MethodGet, "/secrets/"+url.PathEscape(secretID), nil, &model); err != nil {
+		return nil, err
+	}
+
+	return s.decrypt(model)
+}
+
+func (s *restSecrets) GetByIDS(secretIDs []string) (*SecretsResponse, error) {
+	var model secretListModel
+	if err := s.client.apiRequest(http.MethodPost, "/secrets/get-by-ids", map[string][]string{"ids": secretIDs}, &model); err != nil {
+		return nil, err
+	}
+
+	secrets, err := s.decryptAll(model.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretsResponse{Data: secrets}, nil
+}
+
+func (s *restSecrets) Update(secretID string, key, value, note string, organizationID string, projectIDs []string) (*SecretResponse, error) {
+	return nil, errNotSupported("Updating secrets")
+}
+
+func (s *restSecrets) Delete(secretIDs []string) (*SecretsDeleteResponse, error) {
+	return nil, errNotSupported("Deleting secrets")
+}
+
+func (s *restSecrets) Sync(organizationID string, lastSyncedDate *time.Time) (*SecretsSyncResponse, error) {
+	path := "/organizations/" + url.PathEscape(organizationID) + "/secrets/sync"
+	if lastSyncedDate != nil {
+		path += "?lastSyncedDate=" + url.QueryEscape(lastSyncedDate.UTC().Format(time.RFC3339))
+	}
+
+	var model secretsSyncModel
+	if err := s.client.apiRequest(http.MethodGet, path, nil, &model); err != nil {
+		return nil, err
+	}
+
+	response := &SecretsSyncResponse{HasChanges: model.HasChanges}
+	if model.Secrets != nil {
+		secrets, err := s.decryptAll(model.Secrets.Data)
+		if err != nil {
+			return nil, err
+		}
+		response.Secrets = secrets
+	}
+
+	return response, nil
+}
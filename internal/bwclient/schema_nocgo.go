@@ -0,0 +1,112 @@
+//go:build !cgo
+
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import "time"
+
+// The definitions below match the Bitwarden SDK for builds where the native library is unavailable.
+
+type BitwardenClientInterface interface {
+	AccessTokenLogin(accessToken string, statePath *string) error
+	Projects() ProjectsInterface
+	Secrets() SecretsInterface
+	Close()
+}
+
+type ProjectsInterface interface {
+	Create(organizationID string, name string) (*ProjectResponse, error)
+	List(organizationID string) (*ProjectsResponse, error)
+	Get(projectID string) (*ProjectResponse, error)
+	Update(projectID string, organizationID string, name string) (*ProjectResponse, error)
+	Delete(projectIDs []string) (*ProjectsDeleteResponse, error)
+}
+
+type SecretsInterface interface {
+	Create(key, value, note string, organizationID string, projectIDs []string) (*SecretResponse, error)
+	List(organizationID string) (*SecretIdentifiersResponse, error)
+	Get(secretID string) (*SecretResponse, error)
+	GetByIDS(secretIDs []string) (*SecretsResponse, error)
+	Update(secretID string, key, value, note string, organizationID string, projectIDs []string) (*SecretResponse, error)
+	Delete(secretIDs []string) (*SecretsDeleteResponse, error)
+	Sync(organizationID string, lastSyncedDate *time.Time) (*SecretsSyncResponse, error)
+}
+
+type SecretResponse struct {
+	CreationDate   string  `json:"creationDate"`
+	ID             string  `json:"id"`
+	Key            string  `json:"key"`
+	Note           string  `json:"note"`
+	OrganizationID string  `json:"organizationId"`
+	ProjectID      *string `json:"projectId,omitempty"`
+	RevisionDate   string  `json:"revisionDate"`
+	Value          string  `json:"value"`
+}
+
+type SecretsResponse struct {
+	Data []SecretResponse `json:"data"`
+}
+
+type SecretIdentifierResponse struct {
+	ID             string `json:"id"`
+	Key            string `json:"key"`
+	OrganizationID string `json:"organizationId"`
+}
+
+type SecretIdentifiersResponse struct {
+	Data []SecretIdentifierResponse `json:"data"`
+}
+
+type SecretDeleteResponse struct {
+	Error *string `json:"error,omitempty"`
+	ID    string  `json:"id"`
+}
+
+type SecretsDeleteResponse struct {
+	Data []SecretDeleteResponse `json:"data"`
+}
+
+type SecretsSyncResponse struct {
+	HasChanges bool             `json:"hasChanges"`
+	Secrets    []SecretResponse `json:"secrets,omitempty"`
+}
+
+type ProjectResponse struct {
+	CreationDate   string `json:"creationDate"`
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	OrganizationID string `json:"organizationId"`
+	RevisionDate   string `json:"revisionDate"`
+}
+
+type ProjectsResponse struct {
+	Data []ProjectResponse `json:"data"`
+}
+
+type ProjectDeleteResponse struct {
+	Error *string `json:"error,omitempty"`
+	ID    string  `json:"id"`
+}
+
+type ProjectsDeleteResponse struct {
+	Data []ProjectDeleteResponse `json:"data"`
+}
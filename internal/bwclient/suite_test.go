@@ -0,0 +1,593 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBitwardenClients(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Bitwarden Client Suite")
+}
+
+func encryptString(key *symmetricKey, plain []byte) string {
+	block, err := aes.NewCipher(key.EncKey)
+	Expect(err).Should(BeNil())
+
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	padded := append(append([]byte{}, plain...), make([]byte, padding)...)
+	for i := len(plain); i < len(padded); i++ {
+		padded[i] = byte(padding)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	_, err = rand.Read(iv)
+	Expect(err).Should(BeNil())
+
+	data := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, padded)
+
+	mac := hmac.New(sha256.New, key.MacKey)
+	mac.Write(iv)
+	mac.Write(data)
+
+	return fmt.Sprintf("2.%s|%s|%s", base64.StdEncoding.EncodeToString(iv), base64.StdEncoding.EncodeToString(data), base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+var _ = Describe("REST client backend", func() {
+	var (
+		server      *httptest.Server
+		orgId       string
+		clientId    string
+		token       string
+		orgKey      *symmetricKey
+		bearer      string
+		secretId    string
+		projectId   string
+		lastSyncArg string
+	)
+
+	BeforeEach(func() {
+		orgId = uuid.NewString()
+		clientId = uuid.NewString()
+		secretId = uuid.NewString()
+		projectId = uuid.NewString()
+		bearer = uuid.NewString()
+		lastSyncArg = ""
+
+		seed := make([]byte, 16)
+		_, err := rand.Read(seed)
+		Expect(err).Should(BeNil())
+		token = fmt.Sprintf("0.%s.client-secret:%s", clientId, base64.StdEncoding.EncodeToString(seed))
+		tokenKey := deriveShareableKey(seed, "accesstoken", "sm-access-token")
+
+		rawOrgKey := make([]byte, 64)
+		_, err = rand.Read(rawOrgKey)
+		Expect(err).Should(BeNil())
+		orgKey, err = newSymmetricKey(rawOrgKey)
+		Expect(err).Should(BeNil())
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/identity/connect/token", func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.ParseForm()).Should(Succeed())
+			if r.PostForm.Get("client_id") != clientId || r.PostForm.Get("client_secret") != "client-secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			payload, _ := json.Marshal(accessTokenPayload{EncryptionKey: base64.StdEncoding.EncodeToString(rawOrgKey)})
+			json.NewEncoder(w).Encode(identityTokenResponse{AccessToken: bearer, EncryptedPayload: encryptString(tokenKey, payload)})
+		})
+		mux.HandleFunc(fmt.Sprintf("/api/organizations/%s/secrets/sync", orgId), func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+bearer {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			lastSyncArg = r.URL.Query().Get("lastSyncedDate")
+
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"hasChanges": true,
+				"secrets": map[string]interface{}{
+					"data": []map[string]interface{}{{
+						"id":             secretId,
+						"organizationId": orgId,
+						"key":            encryptString(orgKey, []byte("db-password")),
+						"value":          encryptString(orgKey, []byte("hunter2")),
+						"note":           encryptString(orgKey, []byte("a note")),
+						"projects":       []map[string]string{{"id": projectId}},
+					}},
+				},
+			})
+		})
+		mux.HandleFunc(fmt.Sprintf("/api/organizations/%s/projects", orgId), func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{{"id": projectId, "organizationId": orgId, "name": encryptString(orgKey, []byte("payments"))}},
+			})
+		})
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Reports an endpoint that is down as unreachable", func() {
+		client := NewRestClient(server.URL+"/api", server.URL+"/identity", nil)
+		server.Close()
+
+		Expect(IsUnreachableError(client.AccessTokenLogin(token, nil))).Should(BeTrue())
+	})
+
+	It("Logs in and decrypts synced secrets", func() {
+		client := NewRestClient(server.URL+"/api", server.URL+"/identity", nil)
+		Expect(client.AccessTokenLogin(token, nil)).Should(Succeed())
+
+		lastSync := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		response, err := client.Secrets().Sync(orgId, &lastSync)
+		Expect(err).Should(BeNil())
+		Expect(lastSyncArg).Should(Equal("2024-05-01T10:00:00Z"))
+		Expect(response.HasChanges).Should(BeTrue())
+		Expect(response.Secrets).Should(HaveLen(1))
+		Expect(response.Secrets[0].ID).Should(Equal(secretId))
+		Expect(response.Secrets[0].Key).Should(Equal("db-password"))
+		Expect(response.Secrets[0].Value).Should(Equal("hunter2"))
+		Expect(response.Secrets[0].Note).Should(Equal("a note"))
+		Expect(*response.Secrets[0].ProjectID).Should(Equal(projectId))
+
+		projects, err := client.Projects().List(orgId)
+		Expect(err).Should(BeNil())
+		Expect(projects.Data).Should(HaveLen(1))
+		Expect(projects.Data[0].Name).Should(Equal("payments"))
+	})
+
+	It("Fails to log in with rejected credentials", func() {
+		client := NewRestClient(server.URL+"/api", server.URL+"/identity", nil)
+		badToken := fmt.Sprintf("0.%s.wrong:%s", clientId, base64.StdEncoding.EncodeToString(make([]byte, 16)))
+
+		err := client.AccessTokenLogin(badToken, nil)
+		Expect(err).ShouldNot(BeNil())
+		apiErr, ok := err.(*APIError)
+		Expect(ok).Should(BeTrue())
+		Expect(apiErr.StatusCode).Should(Equal(http.StatusUnauthorized))
+	})
+
+	It("Rejects malformed access tokens", func() {
+		client := NewRestClient(server.URL+"/api", server.URL+"/identity", nil)
+		Expect(client.AccessTokenLogin("not-a-token", nil)).ShouldNot(Succeed())
+		Expect(client.AccessTokenLogin("1.a.b:AAAAAAAAAAAAAAAAAAAAAA==", nil)).ShouldNot(Succeed())
+		Expect(client.AccessTokenLogin("0.a.b:AAAA", nil)).ShouldNot(Succeed())
+	})
+
+	It("Refuses API calls before login", func() {
+		client := NewRestClient(server.URL+"/api", server.URL+"/identity", nil)
+		_, err := client.Secrets().Sync(orgId, nil)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("Records a trace without secret contents and replays it", func() {
+		trace := &bytes.Buffer{}
+		recorder := NewTraceRecorder(trace)
+		client := recorder.Wrap(NewRestClient(server.URL+"/api", server.URL+"/identity", nil))
+
+		Expect(client.AccessTokenLogin(token, nil)).Should(Succeed())
+		_, err := client.Secrets().Sync(orgId, nil)
+		Expect(err).Should(BeNil())
+		Expect(client.AccessTokenLogin("0.a.b:AAAA", nil)).ShouldNot(Succeed())
+
+		Expect(trace.String()).ShouldNot(ContainSubstring("hunter2"))
+		Expect(trace.String()).ShouldNot(ContainSubstring("db-password"))
+		Expect(trace.String()).ShouldNot(ContainSubstring("client-secret"))
+
+		replayer, err := NewTraceReplayer(bytes.NewReader(trace.Bytes()))
+		Expect(err).Should(BeNil())
+		Expect(replayer.Remaining()).Should(Equal(3))
+
+		replayed := replayer.Client()
+		Expect(replayed.AccessTokenLogin("ignored", nil)).Should(Succeed())
+		response, err := replayed.Secrets().Sync(orgId, nil)
+		Expect(err).Should(BeNil())
+		Expect(response.HasChanges).Should(BeTrue())
+		Expect(response.Secrets).Should(HaveLen(1))
+		Expect(response.Secrets[0].ID).Should(Equal(secretId))
+		Expect(response.Secrets[0].Value).Should(Equal(ReplayedValue))
+
+		// Later clients continue from the same position in the trace
+		Expect(replayer.Client().AccessTokenLogin("ignored", nil)).ShouldNot(Succeed())
+		Expect(replayer.Remaining()).Should(Equal(0))
+		_, err = replayer.Client().Secrets().Sync(orgId, nil)
+		Expect(err).Should(MatchError(ContainSubstring("exhausted")))
+	})
+
+	It("Fails calls a trace does not record instead of panicking", func() {
+		replayer, err := NewTraceReplayer(bytes.NewReader(nil))
+		Expect(err).Should(BeNil())
+		client := replayer.Client()
+
+		_, err = client.Projects().List(orgId)
+		Expect(err).Should(MatchError(ContainSubstring("Projects().List is not recorded in trace")))
+		_, err = client.Projects().Create(orgId, "project")
+		Expect(err).Should(MatchError(ContainSubstring("not recorded in trace")))
+		_, err = client.Secrets().Get(secretId)
+		Expect(err).Should(MatchError(ContainSubstring("Secrets().Get is not recorded in trace")))
+		_, err = client.Secrets().GetByIDS([]string{secretId})
+		Expect(err).Should(MatchError(ContainSubstring("not recorded in trace")))
+		_, err = client.Secrets().Delete([]string{secretId})
+		Expect(err).Should(MatchError(ContainSubstring("not recorded in trace")))
+	})
+
+	It("Detects tampered ciphertext", func() {
+		value := encryptString(orgKey, []byte("hunter2"))
+		tampered := value[:len(value)-4] + "AAA="
+
+		_, err := orgKey.decryptString(tampered)
+		Expect(err).ShouldNot(BeNil())
+	})
+})
+
+var _ = Describe("Backend registry", func() {
+	It("Registers the built-in backends", func() {
+		Expect(Backends()).Should(ContainElement("rest"))
+		backend, ok := LookupBackend("rest")
+		Expect(ok).Should(BeTrue())
+
+		client, err := backend("https://api.example.com", "https://identity.example.com")
+		Expect(err).Should(BeNil())
+		Expect(client).Should(BeAssignableToTypeOf(&RestClient{}))
+	})
+
+	It("Registers custom backends", func() {
+		name := "custom-" + uuid.NewString()
+		var gotApi, gotIdentity string
+		RegisterBackend(name, func(apiUrl string, identityUrl string) (BitwardenClientInterface, error) {
+			gotApi, gotIdentity = apiUrl, identityUrl
+			return NewRestClient(apiUrl, identityUrl, nil), nil
+		})
+
+		backend, ok := LookupBackend(name)
+		Expect(ok).Should(BeTrue())
+		_, err := backend("https://api.example.com", "https://identity.example.com")
+		Expect(err).Should(BeNil())
+		Expect(gotApi).Should(Equal("https://api.example.com"))
+		Expect(gotIdentity).Should(Equal("https://identity.example.com"))
+		Expect(Backends()).Should(ContainElement(name))
+	})
+
+	It("Refuses duplicate registrations", func() {
+		Expect(func() {
+			RegisterBackend("rest", func(apiUrl string, identityUrl string) (BitwardenClientInterface, error) {
+				return nil, nil
+			})
+		}).Should(Panic())
+	})
+
+	It("Does not find unknown backends", func() {
+		_, ok := LookupBackend(uuid.NewString())
+		Expect(ok).Should(BeFalse())
+	})
+})
+
+type panickingClient struct {
+	closed bool
+}
+
+func (p *panickingClient) AccessTokenLogin(accessToken string, statePath *string) error {
+	panic("native library failure")
+}
+
+func (p *panickingClient) Projects() ProjectsInterface {
+	panic("native library failure")
+}
+
+func (p *panickingClient) Secrets() SecretsInterface {
+	panic("native library failure")
+}
+
+func (p *panickingClient) Close() {
+	p.closed = true
+	panic("native library failure")
+}
+
+var _ = Describe("Recovering client", func() {
+	It("Converts client panics into errors", func() {
+		inner := &panickingClient{}
+		client := NewRecoveringClient(inner)
+
+		err := client.AccessTokenLogin("token", nil)
+		Expect(err).ShouldNot(BeNil())
+		Expect(IsPanic(err)).Should(BeTrue())
+		Expect(err.(*PanicError).Operation).Should(Equal("AccessTokenLogin"))
+		Expect(err.(*PanicError).Stack).ShouldNot(BeEmpty())
+
+		_, err = client.Secrets().Sync("org", nil)
+		Expect(IsPanic(err)).Should(BeTrue())
+		Expect(err.Error()).Should(ContainSubstring("Secrets().Sync"))
+
+		_, err = client.Projects().List("org")
+		Expect(IsPanic(err)).Should(BeTrue())
+
+		Expect(func() { client.Close() }).ShouldNot(Panic())
+		Expect(inner.closed).Should(BeTrue())
+	})
+
+	It("Recovers panics in arbitrary calls", func() {
+		err := Recover("GetBitwardenClient", func() error {
+			panic("init failed")
+		})
+		Expect(IsPanic(err)).Should(BeTrue())
+
+		Expect(Recover("GetBitwardenClient", func() error { return nil })).Should(Succeed())
+		Expect(IsPanic(fmt.Errorf("plain error"))).Should(BeFalse())
+	})
+})
+
+var _ = Describe("REST backend hashing", func() {
+	AfterEach(func() {
+		SetHash(sha256.New)
+	})
+
+	It("Derives keys with the hash set with SetHash", func() {
+		calls := 0
+		SetHash(func() hash.Hash {
+			calls++
+			return sha256.New()
+		})
+
+		key := deriveShareableKey(make([]byte, 16), "accesstoken", "sm-access-token")
+		Expect(calls).ShouldNot(BeZero())
+		Expect(key.EncKey).Should(HaveLen(32))
+		Expect(key.MacKey).Should(HaveLen(32))
+	})
+})
+
+var _ = Describe("Auth errors", func() {
+	It("Detects rejected credentials", func() {
+		Expect(IsAuthError(&APIError{StatusCode: http.StatusUnauthorized})).Should(BeTrue())
+		Expect(IsAuthError(fmt.Errorf("API error: Received error message from server: [401 Unauthorized]"))).Should(BeTrue())
+		Expect(IsAuthError(fmt.Errorf("API error: request failed with status 401"))).Should(BeTrue())
+		Expect(IsAuthError(fmt.Errorf("API error: invalid_client"))).Should(BeTrue())
+	})
+
+	It("Ignores other failures", func() {
+		Expect(IsAuthError(nil)).Should(BeFalse())
+		Expect(IsAuthError(&APIError{StatusCode: http.StatusInternalServerError})).Should(BeFalse())
+		Expect(IsAuthError(fmt.Errorf("connection refused"))).Should(BeFalse())
+		Expect(IsAuthError(Recover("Sync", func() error { panic("401") }))).Should(BeFalse())
+		Expect(IsAuthError(fmt.Errorf("sync failed: %w", &APIError{StatusCode: http.StatusForbidden}))).Should(BeFalse())
+		Expect(IsAuthError(fmt.Errorf("API error: Received error message from server: [403 Forbidden]"))).Should(BeFalse())
+		Expect(IsAuthError(fmt.Errorf("secret 1401aa2e-0000-4000-8000-000000000401 failed to decrypt"))).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Unreachable errors", func() {
+	It("Detects endpoints that cannot be reached", func() {
+		Expect(IsUnreachableError(&APIError{StatusCode: http.StatusServiceUnavailable})).Should(BeTrue())
+		Expect(IsUnreachableError(fmt.Errorf("sync failed: %w", &net.OpError{Op: "dial", Err: fmt.Errorf("refused")}))).Should(BeTrue())
+		Expect(IsUnreachableError(fmt.Errorf("error sending request for url (https://api.bitwarden.com/): error trying to connect: tcp connect error: Connection refused"))).Should(BeTrue())
+	})
+
+	It("Ignores calls the endpoint rejected", func() {
+		Expect(IsUnreachableError(nil)).Should(BeFalse())
+		Expect(IsUnreachableError(&APIError{StatusCode: http.StatusUnauthorized})).Should(BeFalse())
+		Expect(IsUnreachableError(fmt.Errorf("API error: invalid_client"))).Should(BeFalse())
+		Expect(IsUnreachableError(Recover("Sync", func() error { panic("connection refused") }))).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Not found errors", func() {
+	It("Detects missing resources", func() {
+		Expect(IsNotFoundError(&APIError{StatusCode: http.StatusNotFound})).Should(BeTrue())
+		Expect(IsNotFoundError(fmt.Errorf("API error: Received error message from server: [404 Not Found] {\"message\":\"Resource not found.\"}"))).Should(BeTrue())
+	})
+
+	It("Ignores other failures", func() {
+		Expect(IsNotFoundError(nil)).Should(BeFalse())
+		Expect(IsNotFoundError(&APIError{StatusCode: http.StatusUnauthorized})).Should(BeFalse())
+		Expect(IsNotFoundError(Recover("Sync", func() error { panic("not found") }))).Should(BeFalse())
+		Expect(IsNotFoundError(fmt.Errorf("error trying to connect: tcp connect error: 10.0.0.1:8404"))).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Forbidden errors", func() {
+	It("Detects resources the machine account may not access", func() {
+		Expect(IsForbiddenError(fmt.Errorf("sync failed: %w", &APIError{StatusCode: http.StatusForbidden}))).Should(BeTrue())
+		Expect(IsForbiddenError(fmt.Errorf("API error: Received error message from server: [403 Forbidden]"))).Should(BeTrue())
+	})
+
+	It("Ignores other failures", func() {
+		Expect(IsForbiddenError(nil)).Should(BeFalse())
+		Expect(IsForbiddenError(&APIError{StatusCode: http.StatusUnauthorized})).Should(BeFalse())
+		Expect(IsForbiddenError(fmt.Errorf("project 403 of the organization"))).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Server errors", func() {
+	It("Detects failed and throttled calls", func() {
+		Expect(IsServerError(&APIError{StatusCode: http.StatusTooManyRequests})).Should(BeTrue())
+		Expect(IsServerError(fmt.Errorf("sync failed: %w", &APIError{StatusCode: http.StatusInternalServerError}))).Should(BeTrue())
+		Expect(IsServerError(fmt.Errorf("API error: Received error message from server: [429 Too Many Requests]"))).Should(BeTrue())
+		Expect(IsServerError(fmt.Errorf("API error: Received error message from server: [502 Bad Gateway]"))).Should(BeTrue())
+		Expect(IsServerError(fmt.Errorf("API error: request failed with status code: 503"))).Should(BeTrue())
+	})
+
+	It("Ignores rejected calls", func() {
+		Expect(IsServerError(nil)).Should(BeFalse())
+		Expect(IsServerError(&APIError{StatusCode: http.StatusNotFound})).Should(BeFalse())
+		Expect(IsServerError(fmt.Errorf("API error: invalid_client"))).Should(BeFalse())
+		Expect(IsServerError(fmt.Errorf("synced 429 of 500 secrets"))).Should(BeFalse())
+	})
+})
+
+var _ = Describe("CA bundles", func() {
+	var server *httptest.Server
+	var caBundle []byte
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		caBundle = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	login := func(client BitwardenClientInterface) error {
+		seed := make([]byte, 16)
+		_, err := rand.Read(seed)
+		Expect(err).Should(BeNil())
+		return client.AccessTokenLogin(fmt.Sprintf("0.%s.client-secret:%s", uuid.NewString(), base64.StdEncoding.EncodeToString(seed)), nil)
+	}
+
+	It("Reports servers with untrusted certificates", func() {
+		err := login(NewRestClient(server.URL+"/api", server.URL+"/identity", nil))
+		Expect(IsCertificateError(err)).Should(BeTrue())
+	})
+
+	It("Trusts the certificates of the CA bundle", func() {
+		client, err := NewRestClientWithCABundle(server.URL+"/api", server.URL+"/identity", caBundle)
+		Expect(err).Should(BeNil())
+
+		err = login(client)
+		Expect(IsCertificateError(err)).Should(BeFalse())
+		Expect(IsAuthError(err)).Should(BeTrue())
+	})
+
+	It("Rejects bundles without certificates", func() {
+		_, err := CertPool([]byte("not a certificate"))
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("Detects native SDK certificate errors", func() {
+		Expect(IsCertificateError(fmt.Errorf("error sending request: invalid peer certificate: UnknownIssuer"))).Should(BeTrue())
+		Expect(IsCertificateError(fmt.Errorf("connection refused"))).Should(BeFalse())
+		Expect(IsCertificateError(nil)).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Events client", func() {
+	var (
+		server  *httptest.Server
+		orgId   string
+		logins  int
+		expired bool
+	)
+
+	BeforeEach(func() {
+		orgId = uuid.NewString()
+		logins = 0
+		expired = false
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/identity/connect/token", func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.ParseForm()).Should(Succeed())
+			if r.PostForm.Get("client_id") != "organization."+orgId || r.PostForm.Get("client_secret") != "secret" || r.PostForm.Get("scope") != "api.organization" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			logins++
+			json.NewEncoder(w).Encode(organizationTokenResponse{AccessToken: fmt.Sprintf("bearer-%d", logins), ExpiresIn: 3600})
+		})
+		mux.HandleFunc("/api/public/events", func(w http.ResponseWriter, r *http.Request) {
+			// The first bearer token is revoked once the session expired
+			if r.Header.Get("Authorization") != fmt.Sprintf("Bearer bearer-%d", logins) || (expired && logins == 1) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			Expect(r.URL.Query().Get("start")).Should(Equal("2024-01-01T00:00:00Z"))
+
+			if r.URL.Query().Get("continuationToken") == "" {
+				json.NewEncoder(w).Encode(eventListModel{
+					Data:              []OrganizationEvent{{Type: EventSecretEdited, SecretID: "a"}},
+					ContinuationToken: "next",
+				})
+				return
+			}
+			json.NewEncoder(w).Encode(eventListModel{Data: []OrganizationEvent{{Type: EventSecretRetrieved, SecretID: "b"}}})
+		})
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Reads every page of the event log", func() {
+		client, err := NewEventsClient(server.URL+"/api", server.URL+"/identity", "organization."+orgId, "secret", nil)
+		Expect(err).Should(BeNil())
+		Expect(client.OrganizationID()).Should(Equal(orgId))
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		events, err := client.Events(start, start.Add(time.Minute))
+		Expect(err).Should(BeNil())
+		Expect(events).Should(HaveLen(2))
+		Expect(events[0].SecretID).Should(Equal("a"))
+
+		// The session is reused until it is rejected
+		_, err = client.Events(start, start.Add(time.Minute))
+		Expect(err).Should(BeNil())
+		Expect(logins).Should(Equal(1))
+
+		expired = true
+		_, err = client.Events(start, start.Add(time.Minute))
+		Expect(err).Should(BeNil())
+		Expect(logins).Should(Equal(2))
+	})
+
+	It("Only accepts organization API keys", func() {
+		_, err := NewEventsClient(server.URL+"/api", server.URL+"/identity", "user."+orgId, "secret", nil)
+		Expect(err).ShouldNot(BeNil())
+
+		client, err := NewEventsClient(server.URL+"/api", server.URL+"/identity", "organization."+orgId, "wrong", nil)
+		Expect(err).Should(BeNil())
+		_, err = client.Events(time.Now(), time.Now())
+		Expect(IsAuthError(err)).Should(BeTrue())
+	})
+
+	It("Tells changes from retrievals", func() {
+		Expect(IsSecretChange(EventSecretEdited)).Should(BeTrue())
+		Expect(IsSecretChange(EventSecretDeleted)).Should(BeTrue())
+		Expect(IsSecretChange(EventSecretRetrieved)).Should(BeFalse())
+		Expect(IsSecretChange(1000)).Should(BeFalse())
+	})
+})
@@ -0,0 +1,328 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package bwclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ReplayedValue is the value given to every secret returned by a replayed trace.  Traces never contain secret
+// values, notes, or keys.
+const ReplayedValue = "<replayed>"
+
+// TraceEvent is one recorded client call.  It only holds metadata needed to reproduce how the operator behaved:
+// access tokens, secret keys, values, and notes are never recorded.
+type TraceEvent struct {
+	Time           time.Time        `json:"time"`
+	Client         int              `json:"client"`
+	Operation      string           `json:"operation"`
+	DurationMillis int64            `json:"durationMillis"`
+	OrganizationID string           `json:"organizationId,omitempty"`
+	LastSyncedDate *time.Time       `json:"lastSyncedDate,omitempty"`
+	Error          string           `json:"error,omitempty"`
+	HasChanges     *bool            `json:"hasChanges,omitempty"`
+	Secrets        []TraceSecretRef `json:"secrets,omitempty"`
+}
+
+// TraceSecretRef identifies a secret returned by a recorded call without its contents.
+type TraceSecretRef struct {
+	ID             string  `json:"id"`
+	OrganizationID string  `json:"organizationId,omitempty"`
+	ProjectID      *string `json:"projectId,omitempty"`
+	CreationDate   string  `json:"creationDate,omitempty"`
+	RevisionDate   string  `json:"revisionDate,omitempty"`
+}
+
+// TraceRecorder writes the AccessTokenLogin and Secrets().Sync calls of wrapped clients to a JSON lines trace that
+// can later be loaded with NewTraceReplayer.
+type TraceRecorder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	clients int
+}
+
+func NewTraceRecorder(w io.Writer) *TraceRecorder {
+	return &TraceRecorder{encoder: json.NewEncoder(w)}
+}
+
+// Wrap returns a client whose calls are recorded.
+func (r *TraceRecorder) Wrap(inner BitwardenClientInterface) BitwardenClientInterface {
+	r.mu.Lock()
+	r.clients++
+	id := r.clients
+	r.mu.Unlock()
+
+	return &recordingClient{BitwardenClientInterface: inner, recorder: r, id: id}
+}
+
+func (r *TraceRecorder) record(event TraceEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Tracing is a debugging aid and must never fail a sync
+	_ = r.encoder.Encode(event)
+}
+
+type recordingClient struct {
+	BitwardenClientInterface
+	recorder *TraceRecorder
+	id       int
+}
+
+func (c *recordingClient) AccessTokenLogin(accessToken string, statePath *string) error {
+	start := time.Now()
+	err := c.BitwardenClientInterface.AccessTokenLogin(accessToken, statePath)
+	c.recorder.record(c.event("AccessTokenLogin", start, err))
+	return err
+}
+
+func (c *recordingClient) Secrets() SecretsInterface {
+	return &recordingSecrets{SecretsInterface: c.BitwardenClientInterface.Secrets(), client: c}
+}
+
+func (c *recordingClient) event(operation string, start time.Time, err error) TraceEvent {
+	event := TraceEvent{
+		Time:           start.UTC(),
+		Client:         c.id,
+		Operation:      operation,
+		DurationMillis: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	return event
+}
+
+type recordingSecrets struct {
+	SecretsInterface
+	client *recordingClient
+}
+
+func (s *recordingSecrets) Sync(organizationID string, lastSyncedDate *time.Time) (*SecretsSyncResponse, error) {
+	start := time.Now()
+	response, err := s.SecretsInterface.Sync(organizationID, lastSyncedDate)
+
+	event := s.client.event("Secrets().Sync", start, err)
+	event.OrganizationID = organizationID
+	event.LastSyncedDate = lastSyncedDate
+	if response != nil {
+		event.HasChanges = &response.HasChanges
+		for _, secret := range response.Secrets {
+			event.Secrets = append(event.Secrets, TraceSecretRef{
+				ID:             secret.ID,
+				OrganizationID: secret.OrganizationID,
+				ProjectID:      secret.ProjectID,
+				CreationDate:   secret.CreationDate,
+				RevisionDate:   secret.RevisionDate,
+			})
+		}
+	}
+
+	s.client.recorder.record(event)
+	return response, err
+}
+
+// TraceReplayer plays back a recorded trace.  Clients returned by Client share one position in the trace, so a run
+// of the operator sees the recorded calls in the order they originally happened regardless of which client made
+// them.
+type TraceReplayer struct {
+	mu     sync.Mutex
+	events []TraceEvent
+	next   int
+}
+
+// NewTraceReplayer loads a trace written by a TraceRecorder.
+func NewTraceReplayer(r io.Reader) (*TraceReplayer, error) {
+	replayer := &TraceReplayer{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event TraceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("invalid trace event %d: %w", len(replayer.events)+1, err)
+		}
+		replayer.events = append(replayer.events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return replayer, nil
+}
+
+// Client returns a client that answers calls from the trace.
+func (r *TraceReplayer) Client() BitwardenClientInterface {
+	return &replayClient{replayer: r}
+}
+
+// Remaining returns the number of recorded calls that have not been replayed yet.
+func (r *TraceReplayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.events) - r.next
+}
+
+func (r *TraceReplayer) take(operation string) (TraceEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.events) {
+		return TraceEvent{}, fmt.Errorf("replay trace exhausted at %s", operation)
+	}
+
+	event := r.events[r.next]
+	if event.Operation != operation {
+		return TraceEvent{}, fmt.Errorf("replay trace out of sequence at event %d: recorded %s, got %s", r.next+1, event.Operation, operation)
+	}
+	r.next++
+
+	return event, nil
+}
+
+type replayClient struct {
+	replayer *TraceReplayer
+}
+
+func (c *replayClient) AccessTokenLogin(accessToken string, statePath *string) error {
+	event, err := c.replayer.take("AccessTokenLogin")
+	if err != nil {
+		return err
+	}
+
+	return replayedError(event)
+}
+
+// Projects calls are not recorded, so every call of a replayed client fails.
+func (c *replayClient) Projects() ProjectsInterface {
+	return replayProjects{}
+}
+
+func (c *replayClient) Secrets() SecretsInterface {
+	return &replaySecrets{replayer: c.replayer}
+}
+
+func (c *replayClient) Close() {}
+
+// notRecorded returns the error of calls a trace never holds.
+func notRecorded(operation string) error {
+	return fmt.Errorf("%s is not recorded in trace", operation)
+}
+
+type replayProjects struct{}
+
+func (replayProjects) Create(organizationID string, name string) (*ProjectResponse, error) {
+	return nil, notRecorded("Projects().Create")
+}
+
+func (replayProjects) List(organizationID string) (*ProjectsResponse, error) {
+	return nil, notRecorded("Projects().List")
+}
+
+func (replayProjects) Get(projectID string) (*ProjectResponse, error) {
+	return nil, notRecorded("Projects().Get")
+}
+
+func (replayProjects) Update(projectID string, organizationID string, name string) (*ProjectResponse, error) {
+	return nil, notRecorded("Projects().Update")
+}
+
+func (replayProjects) Delete(projectIDs []string) (*ProjectsDeleteResponse, error) {
+	return nil, notRecorded("Projects().Delete")
+}
+
+// replaySecrets only replays Sync, the one call the operator makes and records.  The other calls fail.
+type replaySecrets struct {
+	replayer *TraceReplayer
+}
+
+func (s *replaySecrets) Create(key, value, note string, organizationID string, projectIDs []string) (*SecretResponse, error) {
+	return nil, notRecorded("Secrets().Create")
+}
+
+func (s *replaySecrets) List(organizationID string) (*SecretIdentifiersResponse, error) {
+	return nil, notRecorded("Secrets().List")
+}
+
+func (s *replaySecrets) Get(secretID string) (*SecretResponse, error) {
+	return nil, notRecorded("Secrets().Get")
+}
+
+func (s *replaySecrets) GetByIDS(secretIDs []string) (*SecretsResponse, error) {
+	return nil, notRecorded("Secrets().GetByIDS")
+}
+
+func (s *replaySecrets) Update(secretID string, key, value, note string, organizationID string, projectIDs []string) (*SecretResponse, error) {
+	return nil, notRecorded("Secrets().Update")
+}
+
+func (s *replaySecrets) Delete(secretIDs []string) (*SecretsDeleteResponse, error) {
+	return nil, notRecorded("Secrets().Delete")
+}
+
+func (s *replaySecrets) Sync(organizationID string, lastSyncedDate *time.Time) (*SecretsSyncResponse, error) {
+	event, err := s.replayer.take("Secrets().Sync")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := replayedError(event); err != nil {
+		return nil, err
+	}
+
+	response := &SecretsSyncResponse{}
+	if event.HasChanges != nil {
+		response.HasChanges = *event.HasChanges
+	}
+	for _, secret := range event.Secrets {
+		response.Secrets = append(response.Secrets, SecretResponse{
+			ID:             secret.ID,
+			OrganizationID: secret.OrganizationID,
+			ProjectID:      secret.ProjectID,
+			CreationDate:   secret.CreationDate,
+			RevisionDate:   secret.RevisionDate,
+			Value:          ReplayedValue,
+		})
+	}
+
+	return response, nil
+}
+
+func replayedError(event TraceEvent) error {
+	if event.Error == "" {
+		return nil
+	}
+
+	return errors.New(event.Error)
+}
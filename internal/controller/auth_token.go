@@ -0,0 +1,137 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// AuthTokenFiles locates authorization tokens mounted into the operator pod, for example from a projected volume,
+// instead of Kubernetes secrets in the namespace of every BitwardenSecret.
+type AuthTokenFiles struct {
+	// The token used by BitwardenSecrets that set neither spec.authToken.secretName nor spec.authToken.filePath
+	File string
+	// The directory spec.authToken.filePath is resolved in.  When empty, spec.authToken.filePath is rejected.
+	Dir string
+	// Patterns of the namespaces whose BitwardenSecrets may use File and the files in Dir.  Every BitwardenSecret
+	// could otherwise sync the secrets the operator's tokens grant access to, so none may when empty.
+	Namespaces []string
+}
+
+// ParseAuthTokenNamespaces returns the comma separated namespace patterns of AuthTokenFiles.Namespaces.
+func ParseAuthTokenNamespaces(list string) ([]string, error) {
+	return parseNamespacePatterns(list)
+}
+
+// ReadAuthToken returns the machine account access token of the BitwardenSecret.  Files are read on every sync, so
+// that rotated mounts are picked up.  Tokens of the operator pod are only sent to the operator endpoints.
+func ReadAuthToken(ctx context.Context, reader client.Reader, bwSecret *operatorsv1.BitwardenSecret, files AuthTokenFiles) (string, error) {
+	token, _, err := readAuthToken(ctx, reader, bwSecret, files)
+	return token, err
+}
+
+// readAuthToken returns the access token of the BitwardenSecret and the resourceVersion of the secret it was read
+// from, which is empty for tokens read from files.
+func readAuthToken(ctx context.Context, reader client.Reader, bwSecret *operatorsv1.BitwardenSecret, files AuthTokenFiles) (string, string, error) {
+	authToken := bwSecret.Spec.AuthToken
+	overridesEndpoints := bwSecret.Spec.ApiUrl != "" || bwSecret.Spec.IdentityUrl != ""
+
+	if authToken.SecretName != "" {
+		namespace := bwSecret.Namespace
+		if authToken.Namespace != "" && authToken.Namespace != bwSecret.Namespace {
+			if overridesEndpoints {
+				return "", "", fmt.Errorf("authorization tokens of other namespaces can not be used with spec.apiUrl and spec.identityUrl")
+			}
+
+			if err := checkTokenGrant(ctx, reader, authToken.Namespace, authToken.SecretName, bwSecret.Namespace); err != nil {
+				return "", "", err
+			}
+			namespace = authToken.Namespace
+		}
+
+		authK8sSecret := &corev1.Secret{}
+		err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: authToken.SecretName}, authK8sSecret)
+		if err != nil {
+			return "", "", err
+		}
+
+		return string(authK8sSecret.Data[authToken.SecretKey]), authK8sSecret.ResourceVersion, nil
+	}
+
+	if overridesEndpoints {
+		return "", "", fmt.Errorf("authorization tokens mounted into the operator can not be used with spec.apiUrl and spec.identityUrl")
+	}
+
+	if (files.File != "" || files.Dir != "") && !matchesNamespace(files.Namespaces, bwSecret.Namespace) {
+		return "", "", fmt.Errorf("BitwardenSecrets in namespace %s may not use the authorization tokens of the operator; set spec.authToken.secretName or add the namespace to --auth-token-namespaces", bwSecret.Namespace)
+	}
+
+	path := files.File
+	if authToken.FilePath != "" {
+		if files.Dir == "" {
+			return "", "", fmt.Errorf("spec.authToken.filePath requires the operator to be started with --auth-token-dir")
+		}
+
+		path = filepath.Join(files.Dir, authToken.FilePath)
+		rel, err := filepath.Rel(files.Dir, path)
+		if err != nil || filepath.IsAbs(authToken.FilePath) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", "", fmt.Errorf("spec.authToken.filePath %q must be a relative path within the --auth-token-dir of the operator", authToken.FilePath)
+		}
+	}
+
+	if path == "" {
+		return "", "", fmt.Errorf("spec.authToken.secretName is not set and the operator was started without --auth-token-file")
+	}
+
+	token, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	return strings.TrimSpace(string(token)), "", nil
+}
+
+// checkTokenGrant returns an error unless a BitwardenTokenGrant in namespace allows BitwardenSecrets of
+// granteeNamespace to use the secret.
+func checkTokenGrant(ctx context.Context, reader client.Reader, namespace string, secretName string, granteeNamespace string) error {
+	grants := &operatorsv1.BitwardenTokenGrantList{}
+	if err := reader.List(ctx, grants, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	for i := range grants.Items {
+		if grants.Items[i].Grants(secretName, granteeNamespace) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no BitwardenTokenGrant in namespace %s allows namespace %s to use the authorization token secret %s", namespace, granteeNamespace, secretName)
+}
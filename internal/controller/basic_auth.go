@@ -0,0 +1,73 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// ApplyBasicAuth writes the username and password of the BitwardenSecret's basic-auth template to the secret.  Without
+// a map only the username and password are written.  It does nothing when no template is set.
+func ApplyBasicAuth(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte, secret *corev1.Secret) error {
+	template := bwSecret.Spec.BasicAuth
+	if template == nil {
+		return nil
+	}
+
+	if secretType := TargetSecretType(bwSecret); secretType != corev1.SecretTypeBasicAuth {
+		return fmt.Errorf("spec.basicAuth requires spec.secretType %s, not %s", corev1.SecretTypeBasicAuth, secretType)
+	}
+
+	username, err := basicAuthValue(secrets, template.UsernameSecretId, "username")
+	if err != nil {
+		return err
+	}
+
+	password, err := basicAuthValue(secrets, template.PasswordSecretId, "password")
+	if err != nil {
+		return err
+	}
+
+	if bwSecret.Spec.SecretMap == nil || secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[corev1.BasicAuthUsernameKey] = username
+	secret.Data[corev1.BasicAuthPasswordKey] = password
+
+	return nil
+}
+
+func basicAuthValue(secrets map[string][]byte, id string, field string) ([]byte, error) {
+	if id == "" {
+		return nil, fmt.Errorf("the basic-auth %s secret ID is not set", field)
+	}
+
+	value, ok := secrets[id]
+	if !ok {
+		return nil, fmt.Errorf("the basic-auth %s secret %s is not accessible by the machine account", field, id)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,237 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// BitwardenClientCache keeps long lived Bitwarden clients so that reconciles for the same machine account reuse one
+// client instead of creating a new native client each time.  A client that keeps failing, or that panicked, is
+// treated as wedged: it is closed and rebuilt on the next use.
+//
+// Cached clients also keep their authenticated session for SessionTTL, so AccessTokenLogin only reaches the identity
+// endpoint when a session is new, expired, or was rejected by the server.
+type BitwardenClientCache struct {
+	// Number of consecutive failed syncs after which a cached client is reset
+	ResetThreshold int
+	// Cached clients that have not been used for this long are closed
+	IdleTimeout time.Duration
+	// How long an authenticated session is reused before logging in again.  Zero disables session reuse.
+	SessionTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cachedClient
+}
+
+type cachedClient struct {
+	// Serializes use of a single client, since native clients are not safe for concurrent use
+	mu       sync.Mutex
+	client   bwclient.BitwardenClientInterface
+	failures int
+	lastUsed time.Time
+	removed  bool
+
+	authenticated   bool
+	authenticatedAt time.Time
+}
+
+func NewBitwardenClientCache(resetThreshold int, idleTimeout time.Duration, sessionTTL time.Duration) *BitwardenClientCache {
+	return &BitwardenClientCache{
+		ResetThreshold: resetThreshold,
+		IdleTimeout:    idleTimeout,
+		SessionTTL:     sessionTTL,
+		entries:        map[string]*cachedClient{},
+	}
+}
+
+// Get returns the cached client for the auth token and organization, creating one with the factory when needed.  The
+// client is held exclusively until the returned release function is called with the outcome of the work done with it.
+func (c *BitwardenClientCache) Get(factory BitwardenClientFactory, authToken string, orgId string) (bwclient.BitwardenClientInterface, func(error), error) {
+	key := c.key(factory, authToken, orgId)
+
+	var entry *cachedClient
+	for {
+		c.mu.Lock()
+		c.expireIdle()
+		var ok bool
+		entry, ok = c.entries[key]
+		if !ok {
+			entry = &cachedClient{lastUsed: time.Now()}
+			c.entries[key] = entry
+		}
+		c.mu.Unlock()
+
+		entry.mu.Lock()
+		// The entry may have expired while waiting for the lock
+		if !entry.removed {
+			break
+		}
+		entry.mu.Unlock()
+	}
+
+	if entry.client == nil {
+		bitwardenClient, err := newBitwardenClient(factory)
+		if err != nil {
+			entry.mu.Unlock()
+			return nil, nil, err
+		}
+
+		entry.client = bitwardenClient
+		entry.failures = 0
+		entry.authenticated = false
+	}
+
+	release := func(err error) {
+		defer entry.mu.Unlock()
+		entry.lastUsed = time.Now()
+
+		if err == nil {
+			entry.failures = 0
+			return
+		}
+
+		// The call that timed out or was interrupted still uses the client and closes it once it returns
+		if abandonsClient(err) {
+			c.abandon(key, entry)
+			return
+		}
+
+		// The client was not used while the circuit breaker paused its calls
+		if IsCircuitOpenError(err) {
+			return
+		}
+
+		if bwclient.IsAuthError(err) {
+			entry.authenticated = false
+		}
+
+		entry.failures++
+		if bwclient.IsPanic(err) || entry.failures >= c.ResetThreshold {
+			c.reset(entry, err)
+		}
+	}
+
+	return &sessionClient{BitwardenClientInterface: entry.client, entry: entry, ttl: c.SessionTTL}, release, nil
+}
+
+// reset disposes a wedged client so that the next Get builds a new one.  The caller must hold entry.mu.
+func (c *BitwardenClientCache) reset(entry *cachedClient, err error) {
+	ctrl.Log.WithName("client-cache").Info("Resetting Bitwarden client", "failures", entry.failures, "reason", err.Error())
+
+	entry.client.Close()
+	entry.client = nil
+	entry.failures = 0
+	entry.authenticated = false
+	clientResetsTotal.Inc()
+}
+
+// abandon drops a client that is still in use by a call that timed out or was interrupted, so that the next Get
+// builds a new one without waiting for the call.  The caller must hold entry.mu.
+func (c *BitwardenClientCache) abandon(key string, entry *cachedClient) {
+	ctrl.Log.WithName("client-cache").Info("Abandoning Bitwarden client after a timed out or interrupted call")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries[key] == entry {
+		delete(c.entries, key)
+	}
+	entry.client = nil
+	entry.removed = true
+	clientResetsTotal.Inc()
+}
+
+// expireIdle closes clients that have not been used within IdleTimeout.  The caller must hold c.mu.
+func (c *BitwardenClientCache) expireIdle() {
+	if c.IdleTimeout <= 0 {
+		return
+	}
+
+	for key, entry := range c.entries {
+		if !entry.mu.TryLock() {
+			continue
+		}
+
+		if time.Since(entry.lastUsed) > c.IdleTimeout {
+			if entry.client != nil {
+				entry.client.Close()
+				entry.client = nil
+			}
+			entry.removed = true
+			delete(c.entries, key)
+		}
+		entry.mu.Unlock()
+	}
+}
+
+// sessionClient skips AccessTokenLogin while the cached client still holds a valid session.  It is only used while
+// the entry lock is held.
+type sessionClient struct {
+	bwclient.BitwardenClientInterface
+	entry *cachedClient
+	ttl   time.Duration
+}
+
+func (s *sessionClient) AccessTokenLogin(accessToken string, stateFile *string) error {
+	if s.ttl > 0 && s.entry.authenticated && time.Since(s.entry.authenticatedAt) < s.ttl {
+		sessionReusesTotal.Inc()
+		return nil
+	}
+
+	s.entry.authenticated = false
+	if err := s.BitwardenClientInterface.AccessTokenLogin(accessToken, stateFile); err != nil {
+		return err
+	}
+
+	s.entry.authenticated = true
+	s.entry.authenticatedAt = time.Now()
+	return nil
+}
+
+// key identifies a machine account on a specific server without keeping the raw token in memory.  Every organization
+// gets a session of its own, so a BitwardenSecret naming the wrong organization cannot disturb the others.
+func (c *BitwardenClientCache) key(factory BitwardenClientFactory, authToken string, orgId string) string {
+	endpoints := stableEndpoints(factory)
+	digest := NewHash()
+	digest.Write([]byte(endpoints.GetApiUrl()))
+	digest.Write([]byte{0})
+	digest.Write([]byte(endpoints.GetIdentityApiUrl()))
+	digest.Write([]byte{0})
+	digest.Write([]byte(authToken))
+	digest.Write([]byte{0})
+	digest.Write([]byte(strings.ToLower(orgId)))
+
+	// Clients trusting a CA bundle of their own are not shared with clients trusting the operator CAs
+	if caBundle := TrustedCABundle(factory); caBundle != nil {
+		digest.Write([]byte{0})
+		digest.Write(caBundle)
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
@@ -1,11 +1,17 @@
 package controller
 
 import (
-	sdk "github.com/bitwarden/sdk-go"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
 )
 
+// BitwardenClientFactory creates the clients the controller uses to reach Secrets Manager.  New server variants
+// should normally be added as a bwclient.Backend and selected with NewBitwardenClientFactoryForBackend.
 type BitwardenClientFactory interface {
-	GetBitwardenClient() (sdk.BitwardenClientInterface, error)
+	GetBitwardenClient() (bwclient.BitwardenClientInterface, error)
 	GetApiUrl() string
 	GetIdentityApiUrl() string
 }
@@ -23,8 +29,8 @@ func NewBitwardenClientFactory(bwApiUrl string, identApiUrl string) BitwardenCli
 	}
 }
 
-func (bc *BitwardenClientFactoryImp) GetBitwardenClient() (sdk.BitwardenClientInterface, error) {
-	bitwardenClient, err := sdk.NewBitwardenClient(&bc.BwApiUrl, &bc.IdentApiUrl)
+func (bc *BitwardenClientFactoryImp) GetBitwardenClient() (bwclient.BitwardenClientInterface, error) {
+	bitwardenClient, err := bwclient.NewSDKClient(&bc.BwApiUrl, &bc.IdentApiUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -39,3 +45,92 @@ func (bc *BitwardenClientFactoryImp) GetApiUrl() string {
 func (bc *BitwardenClientFactoryImp) GetIdentityApiUrl() string {
 	return bc.IdentApiUrl
 }
+
+// BackendClientFactory creates clients from any backend registered with bwclient.RegisterBackend.
+type BackendClientFactory struct {
+	Backend bwclient.Backend
+	// Optional support of the backend for clients trusting a CA bundle of their own
+	CABackend bwclient.CABackend
+	// When set, clients are created with CABackend and trust this PEM encoded CA bundle
+	CABundle    []byte
+	BwApiUrl    string
+	IdentApiUrl string
+}
+
+// NewBitwardenClientFactoryForBackend returns a factory for the named client backend.
+func NewBitwardenClientFactoryForBackend(backendName string, bwApiUrl string, identApiUrl string) (BitwardenClientFactory, error) {
+	backend, ok := bwclient.LookupBackend(backendName)
+	if !ok {
+		return nil, fmt.Errorf("unknown client backend %q, available backends: %s", backendName, strings.Join(bwclient.Backends(), ", "))
+	}
+
+	caBackend, _ := bwclient.LookupCABackend(backendName)
+
+	return &BackendClientFactory{
+		Backend:     backend,
+		CABackend:   caBackend,
+		BwApiUrl:    bwApiUrl,
+		IdentApiUrl: identApiUrl,
+	}, nil
+}
+
+func (bc *BackendClientFactory) GetBitwardenClient() (bwclient.BitwardenClientInterface, error) {
+	if bc.CABundle != nil {
+		return bc.CABackend(bc.BwApiUrl, bc.IdentApiUrl, bc.CABundle)
+	}
+
+	return bc.Backend(bc.BwApiUrl, bc.IdentApiUrl)
+}
+
+func (bc *BackendClientFactory) GetApiUrl() string {
+	return bc.BwApiUrl
+}
+
+func (bc *BackendClientFactory) GetIdentityApiUrl() string {
+	return bc.IdentApiUrl
+}
+
+// RecordingClientFactory records the calls of every client it creates to a debug trace.
+type RecordingClientFactory struct {
+	BitwardenClientFactory
+	Recorder *bwclient.TraceRecorder
+}
+
+func (bc *RecordingClientFactory) GetBitwardenClient() (bwclient.BitwardenClientInterface, error) {
+	bitwardenClient, err := bc.BitwardenClientFactory.GetBitwardenClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return bc.Recorder.Wrap(bitwardenClient), nil
+}
+
+// NewReplayClientFactory returns a factory whose clients answer from a recorded trace instead of a server.
+func NewReplayClientFactory(replayer *bwclient.TraceReplayer, bwApiUrl string, identApiUrl string) BitwardenClientFactory {
+	return &BackendClientFactory{
+		Backend: func(apiUrl string, identityUrl string) (bwclient.BitwardenClientInterface, error) {
+			return replayer.Client(), nil
+		},
+		BwApiUrl:    bwApiUrl,
+		IdentApiUrl: identApiUrl,
+	}
+}
+
+// newBitwardenClient creates a client with the factory and wraps it so that its calls are timed, rate limited, paused
+// by the circuit breaker of its endpoints during outages, the access token is scrubbed from its errors, its login state is persisted as
+// configured, and Go panics of the client are returned as errors, so one bad call cannot take down the whole operator.
+func newBitwardenClient(factory BitwardenClientFactory) (bwclient.BitwardenClientInterface, error) {
+	defer observeDuration(clientCreateDuration, time.Now())
+
+	var bitwardenClient bwclient.BitwardenClientInterface
+	err := bwclient.Recover("GetBitwardenClient", func() error {
+		var err error
+		bitwardenClient, err = factory.GetBitwardenClient()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bwclient.NewRecoveringClient(newCircuitBreakerClient(newRateLimitedClient(&instrumentedClient{BitwardenClientInterface: newRedactingClient(newStatePersistenceClient(bitwardenClient))}, apiLimiter), apiBreakers.For(factory))), nil
+}
@@ -0,0 +1,272 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// ProjectInventoryReconciler maintains a BitwardenProject, named by the project ID, for every Secrets Manager project
+// that the machine account of a BitwardenSecret in the namespace can access, so that users can look up valid project
+// IDs with kubectl.  Requests carry only the namespace.
+type ProjectInventoryReconciler struct {
+	client.Client
+	BitwardenClientFactory BitwardenClientFactory
+	StatePath              string
+	RefreshIntervalSeconds int
+	// Authorization tokens mounted into the operator pod
+	AuthTokenFiles AuthTokenFiles
+	// Optional policy of the namespaces BitwardenSecrets may sync in.  When nil every namespace is allowed.
+	NamespacePolicy *NamespacePolicy
+}
+
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardenprojects,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardenprojects/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardensecrets,verbs=get;list;watch
+
+func (r *ProjectInventoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx).WithValues("namespace", req.Namespace)
+
+	bwSecrets := &operatorsv1.BitwardenSecretList{}
+	if err := r.List(ctx, bwSecrets, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	if r.NamespacePolicy != nil && !r.NamespacePolicy.Allows(req.Namespace) {
+		bwSecrets.Items = nil
+	}
+
+	projects, err := r.ListProjects(ctx, logger, bwSecrets.Items)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.ApplyProjects(ctx, req.Namespace, projects); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(bwSecrets.Items) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	// Projects are created and renamed in Secrets Manager without any change to the BitwardenSecrets
+	return ctrl.Result{RequeueAfter: time.Duration(r.RefreshIntervalSeconds) * time.Second}, nil
+}
+
+// projectAccount identifies a machine account, the endpoints it logs in to, and the organization its projects are
+// listed for
+type projectAccount struct {
+	apiUrl      string
+	identityUrl string
+	authToken   string
+	orgId       string
+}
+
+// ListProjects returns the status of every project that the machine account of one of the BitwardenSecrets can
+// access, by project ID.  Machine accounts used by several BitwardenSecrets are listed once.  BitwardenSecrets whose
+// authorization token or endpoints are invalid are skipped, as their own sync reports the problem.
+func (r *ProjectInventoryReconciler) ListProjects(ctx context.Context, logger logr.Logger, bwSecrets []operatorsv1.BitwardenSecret) (map[string]*operatorsv1.BitwardenProjectStatus, error) {
+	accounts := map[projectAccount][]string{}
+	factories := map[projectAccount]BitwardenClientFactory{}
+	var order []projectAccount
+	for i := range bwSecrets {
+		bwSecret := &bwSecrets[i]
+		authToken, err := ReadAuthToken(ctx, r.Client, bwSecret, r.AuthTokenFiles)
+		if err != nil {
+			continue
+		}
+		factory, err := ClientFactoryFor(r.BitwardenClientFactory, bwSecret)
+		if err != nil {
+			continue
+		}
+
+		account := projectAccount{
+			apiUrl:      bwSecret.Spec.ApiUrl,
+			identityUrl: bwSecret.Spec.IdentityUrl,
+			authToken:   authToken,
+			orgId:       bwSecret.Spec.OrganizationId,
+		}
+		if _, ok := accounts[account]; !ok {
+			order = append(order, account)
+			factories[account] = factory
+		}
+		accounts[account] = append(accounts[account], bwSecret.Name)
+	}
+
+	projects := map[string]*operatorsv1.BitwardenProjectStatus{}
+	for _, account := range order {
+		smProjects, err := r.listAccountProjects(logger, factories[account], account.authToken, account.orgId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, smProject := range smProjects {
+			project, ok := projects[smProject.ID]
+			if !ok {
+				project = &operatorsv1.BitwardenProjectStatus{
+					ProjectId:      smProject.ID,
+					ProjectName:    smProject.Name,
+					OrganizationId: smProject.OrganizationID,
+					CreationDate:   parseProjectTime(smProject.CreationDate),
+					RevisionDate:   parseProjectTime(smProject.RevisionDate),
+				}
+				projects[smProject.ID] = project
+			}
+			project.BitwardenSecrets = append(project.BitwardenSecrets, accounts[account]...)
+		}
+	}
+
+	for _, project := range projects {
+		sort.Strings(project.BitwardenSecrets)
+	}
+
+	return projects, nil
+}
+
+func (r *ProjectInventoryReconciler) listAccountProjects(logger logr.Logger, factory BitwardenClientFactory, authToken string, orgId string) ([]bwclient.ProjectResponse, error) {
+	bitwardenClient, err := newBitwardenClient(factory)
+	if err != nil {
+		logClientPanic(logger, err)
+		logger.Error(err, "Failed to create client")
+		return nil, err
+	}
+
+	statePath, err := tokenStatePath(r.StatePath, authToken)
+	if err != nil {
+		bitwardenClient.Close()
+		logger.Error(err, "Failed to create the state directory")
+		return nil, err
+	}
+
+	if err := bitwardenClient.AccessTokenLogin(authToken, &statePath); err != nil {
+		discardPanickedClient(logger, bitwardenClient, err)
+		logClientPanic(logger, err)
+		logger.Error(err, "Failed to authenticate")
+		return nil, &AuthError{Err: err}
+	}
+
+	smProjects, err := bitwardenClient.Projects().List(orgId)
+	if err != nil {
+		discardPanickedClient(logger, bitwardenClient, err)
+		logClientPanic(logger, err)
+		logger.Error(err, "Failed to list projects.")
+		return nil, err
+	}
+
+	defer bitwardenClient.Close()
+
+	return smProjects.Data, nil
+}
+
+// ApplyProjects creates or updates a BitwardenProject for every project and deletes the BitwardenProjects of the
+// namespace whose project is no longer accessible.  Projects whose ID is not a valid object name are skipped.
+func (r *ProjectInventoryReconciler) ApplyProjects(ctx context.Context, namespace string, projects map[string]*operatorsv1.BitwardenProjectStatus) error {
+	existing := &operatorsv1.BitwardenProjectList{}
+	if err := r.List(ctx, existing, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	for i := range existing.Items {
+		bwProject := &existing.Items[i]
+		if _, ok := projects[bwProject.Name]; ok {
+			continue
+		}
+		if err := r.Delete(ctx, bwProject); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	for id, project := range projects {
+		if len(validation.IsDNS1123Subdomain(id)) > 0 {
+			continue
+		}
+
+		bwProject := &operatorsv1.BitwardenProject{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: id}, bwProject)
+		if err != nil && errors.IsNotFound(err) {
+			bwProject.Name = id
+			bwProject.Namespace = namespace
+			if err := r.Create(ctx, bwProject); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		if equality.Semantic.DeepEqual(bwProject.Status, *project) {
+			continue
+		}
+
+		bwProject.Status = *project
+		if err := r.Status().Update(ctx, bwProject); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseProjectTime returns the RFC 3339 time of Secrets Manager, or nil when it cannot be parsed
+func parseProjectTime(value string) *metav1.Time {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil
+	}
+
+	// Times are stored with a precision of seconds
+	t := metav1.NewTime(parsed.Truncate(time.Second))
+	return &t
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProjectInventoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	byNamespace := handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace()}}}
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("bitwardenproject").
+		Watches(&operatorsv1.BitwardenSecret{}, byNamespace, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// BitwardenProjects deleted by users are recreated right away
+		Watches(&operatorsv1.BitwardenProject{}, byNamespace, builder.WithPredicates(predicate.Funcs{
+			CreateFunc: func(event.CreateEvent) bool { return false },
+			UpdateFunc: func(event.UpdateEvent) bool { return false },
+		})).
+		Complete(r)
+}
@@ -23,23 +23,38 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"encoding/hex"
 	"encoding/json"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+	"github.com/bitwarden/sm-kubernetes/internal/tracing"
 )
 
 // BitwardenSecretReconciler reconciles a BitwardenSecret object
@@ -49,13 +64,42 @@ type BitwardenSecretReconciler struct {
 	BitwardenClientFactory BitwardenClientFactory
 	StatePath              string
 	RefreshIntervalSeconds int
+	// How long each Secrets Manager call of a BitwardenSecret without spec.timeout may take.  Zero waits indefinitely.
+	SDKTimeout time.Duration
+	// Optional cache of long lived clients.  When nil a new client is created for every sync.
+	ClientCache *BitwardenClientCache
+	// Optional pool that bounds the number of concurrent Secrets Manager pulls.  When nil pulls run on the
+	// controller worker.
+	PullPool *PullWorkerPool
+	// Number of BitwardenSecrets reconciled in parallel.  Defaults to 1.
+	MaxConcurrentReconciles int
+	// Number of spec.targets secrets of one BitwardenSecret written in parallel.  Defaults to 1.
+	TargetWriteParallelism int
+	// Optional recorder of the sync lifecycle events shown by kubectl describe
+	Recorder record.EventRecorder
+	// Authorization tokens mounted into the operator pod
+	AuthTokenFiles AuthTokenFiles
+	// Optional tracker of whether pulls reach Secrets Manager, for the readiness check
+	APIHealth *APIHealth
+	// Optional policy of the namespaces BitwardenSecrets may sync in.  When nil every namespace is allowed.
+	NamespacePolicy *NamespacePolicy
+	// Creates the clients of the clusters referenced by spec.targets[].clusterRef.  Defaults to
+	// NewRemoteClusterClient.
+	RemoteClusterClient func(kubeconfig []byte) (client.Client, error)
+	// Optional cache of the clients of the clusters referenced by spec.targets[].clusterRef.  When nil a new client
+	// is created for every sync.
+	RemoteClusters *RemoteClusterCache
 }
 
 //+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardensecrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardensecrets/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardensecrets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardentokengrants,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=secrets/status,verbs=get
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -65,39 +109,88 @@ type BitwardenSecretReconciler struct {
 func (r *BitwardenSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
+	ctx, span := tracing.Start(ctx, "BitwardenSecret.Reconcile", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.bitwarden.com/bitwardensecret", req.Name),
+	))
+	defer span.End()
+
 	message := fmt.Sprintf("Syncing  %s/%s", req.Namespace, req.Name)
 	ns := req.Namespace
 
 	bwSecret := &operatorsv1.BitwardenSecret{}
 
 	err := r.Get(ctx, req.NamespacedName, bwSecret)
+	readStatus := bwSecret.Status.DeepCopy()
 
 	// Deleted Bitwarden Secret event.
 	if err != nil && errors.IsNotFound(err) {
 		logger.Info(fmt.Sprintf("%s/%s was deleted.", req.Namespace, req.Name))
+		forgetSyncTimestamps(req.Namespace, req.Name)
 		return ctrl.Result{}, nil
 	} else if err != nil {
 		r.LogError(logger, ctx, bwSecret, err, "Error looking up BitwardenSecret")
 		//Other lookup error
 		return ctrl.Result{
-			RequeueAfter: time.Duration(r.RefreshIntervalSeconds) * time.Second,
+			RequeueAfter: r.RefreshInterval(bwSecret),
 		}, err
 	}
 
+	restoreSyncTimestamps(bwSecret)
+
+	deleting, err := r.HandleDeletionPolicy(ctx, bwSecret)
+	if err != nil {
+		r.LogError(logger, ctx, bwSecret, err, "Failed to apply the deletion policy")
+		return ctrl.Result{
+			RequeueAfter: r.RefreshInterval(bwSecret),
+		}, err
+	}
+	if deleting {
+		logger.Info(fmt.Sprintf("%s/%s is being deleted.", req.Namespace, req.Name))
+		return ctrl.Result{}, nil
+	}
+
+	// Refused by the namespace policy.  Changing the policy restarts the operator, which reconciles every BitwardenSecret.
+	if !r.NamespacePolicy.Allows(req.Namespace) {
+		logger.Info(fmt.Sprintf("%s/%s is in a namespace that is not allowed to sync.  Skipping sync.", req.Namespace, req.Name))
+		if !apimeta.IsStatusConditionTrue(bwSecret.Status.Conditions, DeniedCondition) {
+			recordEvent(ctx, r.Recorder, bwSecret, corev1.EventTypeWarning, DeniedReason, fmt.Sprintf("BitwardenSecrets in the namespace %s are not allowed to sync", req.Namespace))
+		}
+		SetDeniedCondition(bwSecret)
+		r.UpdateStatus(ctx, bwSecret, readStatus)
+		return ctrl.Result{}, nil
+	}
+	apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, DeniedCondition)
+
+	// Syncing is suspended.  Resuming changes the spec, which queues the next reconcile.
+	if bwSecret.Spec.Paused {
+		logger.V(1).Info(fmt.Sprintf("%s/%s is paused.  Skipping sync.", req.Namespace, req.Name))
+		apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+			Status:  metav1.ConditionTrue,
+			Reason:  "Paused",
+			Message: "Syncing is suspended by spec.paused",
+			Type:    PausedCondition,
+		})
+		r.UpdateStatus(ctx, bwSecret, readStatus)
+		return ctrl.Result{}, nil
+	}
+	apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, PausedCondition)
+
 	lastSync := bwSecret.Status.LastSuccessfulSyncTime
 
+	forceSync := ForceSyncRequested(bwSecret)
+
 	// Reconcile was queued by last sync time status update on the BitwardenSecret.  We will ignore it.
-	if time.Now().UTC().Before(lastSync.Time.Add(1 * time.Second)) {
+	if !forceSync && time.Now().UTC().Before(lastSync.Time.Add(1*time.Second)) {
 		return ctrl.Result{}, nil
 	}
 
-	logger.Info(message)
+	logger.V(1).Info(message)
+	ctx = withSyncAttemptStart(ctx, time.Now())
+	recordEvent(ctx, r.Recorder, bwSecret, corev1.EventTypeNormal, SyncStartedReason, "Syncing secrets from Secrets Manager")
 
-	authK8sSecret := &corev1.Secret{}
-	namespacedAuthK8sSecret := types.NamespacedName{
-		Name:      bwSecret.Spec.AuthToken.SecretName,
-		Namespace: ns,
-	}
+	summary := NewSyncSummary()
+	defer summary.Log(logger)
 
 	k8sSecret := &corev1.Secret{}
 	namespacedK8sSecret := types.NamespacedName{
@@ -105,89 +198,675 @@ func (r *BitwardenSecretReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		Namespace: ns,
 	}
 
-	err = r.Client.Get(ctx, namespacedAuthK8sSecret, authK8sSecret)
+	authToken, authTokenVersion, err := readAuthToken(ctx, r.Client, bwSecret, r.AuthTokenFiles)
+	redactor := NewRedactor(authToken)
+	ctx = withRedactor(ctx, redactor)
 
 	if err != nil {
+		recordEvent(ctx, r.Recorder, bwSecret, corev1.EventTypeWarning, AuthFailedReason, fmt.Sprintf("Failed to read the authorization token: %s", err.Error()))
 		r.LogError(logger, ctx, bwSecret, err, "Error pulling authorization token secret")
 		return ctrl.Result{
-			RequeueAfter: time.Duration(r.RefreshIntervalSeconds) * time.Second,
+			RequeueAfter: r.RefreshInterval(bwSecret),
 		}, nil
 	}
 
-	authToken := string(authK8sSecret.Data[bwSecret.Spec.AuthToken.SecretKey])
+	// A permanent error fails the same way until the spec or the authorization token changes, a sync is forced, or
+	// the next refresh, for example after access was granted in Bitwarden
+	if remaining := r.PermanentStallRemaining(bwSecret, authTokenVersion); !forceSync && remaining > 0 {
+		logger.V(1).Info(fmt.Sprintf("%s/%s is stalled by a permanent error.  Skipping sync.", req.Namespace, req.Name))
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	bwSecret.Status.AuthTokenResourceVersion = authTokenVersion
+
+	existingK8sSecret, err := r.GetExistingK8sSecret(ctx, namespacedK8sSecret)
+	if err != nil {
+		r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Error looking up %s/%s", namespacedK8sSecret.Namespace, namespacedK8sSecret.Name))
+		return ctrl.Result{
+			RequeueAfter: r.RefreshInterval(bwSecret),
+		}, nil
+	}
+
+	// The secret is under manual control, for example during an incident
+	if IsK8sSecretIgnored(existingK8sSecret) {
+		logger.V(1).Info(fmt.Sprintf("%s/%s has the %s annotation.  Skipping sync.", namespacedK8sSecret.Namespace, namespacedK8sSecret.Name, IgnoreAnnotation))
+		summary.Result = "Ignored"
+		apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+			Status:  metav1.ConditionTrue,
+			Reason:  "IgnoreAnnotation",
+			Message: fmt.Sprintf("The secret has the %s annotation and is not being synchronized", IgnoreAnnotation),
+			Type:    "Ignored",
+		})
+		r.UpdateStatus(ctx, bwSecret, readStatus)
+		return ctrl.Result{
+			RequeueAfter: r.RefreshInterval(bwSecret),
+		}, nil
+	}
+	apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, "Ignored")
+
+	// Rolled back after a bad rotation.  The rolled back secret is not recorded as written, so that removing the
+	// annotation restores the latest data with a full sync.
+	if snapshot := bwSecret.Annotations[RollbackAnnotation]; snapshot != "" {
+		rolledBack, err := r.RollBackK8sSecret(ctx, bwSecret, existingK8sSecret, snapshot)
+		if err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to roll back %s/%s to %s", namespacedK8sSecret.Namespace, namespacedK8sSecret.Name, snapshot))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+
+		if rolledBack {
+			recordEvent(ctx, r.Recorder, bwSecret, corev1.EventTypeNormal, RolledBackReason, fmt.Sprintf("Rolled back secret %s to %s", namespacedK8sSecret.Name, snapshot))
+		}
+		summary.Result = "RolledBack"
+		apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+			Status:  metav1.ConditionTrue,
+			Reason:  "RollbackAnnotation",
+			Message: fmt.Sprintf("The secret holds the data of %s and is not synchronized until the %s annotation is removed", snapshot, RollbackAnnotation),
+			Type:    RolledBackCondition,
+		})
+		r.UpdateStatus(ctx, bwSecret, readStatus)
+		return ctrl.Result{}, nil
+	}
+	apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, RolledBackCondition)
+
+	// Changes are held back outside of the sync window.  A secret that does not exist yet has no consumers to disrupt,
+	// and a dry run changes nothing.
+	if bwSecret.Spec.SyncWindow != nil && existingK8sSecret != nil && !bwSecret.Spec.DryRun {
+		open, opens, err := SyncWindowOpen(bwSecret.Spec.SyncWindow, time.Now())
+		if err != nil {
+			err = &InvalidSpecError{Err: err}
+			r.LogError(logger, ctx, bwSecret, err, "Invalid sync window")
+			return r.ResultForError(bwSecret, err), nil
+		}
+
+		if !open {
+			logger.V(1).Info(fmt.Sprintf("%s/%s is outside of its sync window until %s.  Skipping sync.", req.Namespace, req.Name, opens.Format(time.RFC3339)))
+			summary.Result = "OutsideSyncWindow"
+			apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+				Status:  metav1.ConditionTrue,
+				Reason:  "OutsideSyncWindow",
+				Message: fmt.Sprintf("Changes are applied when the sync window opens at %s", opens.Format(time.RFC3339)),
+				Type:    SyncWindowClosedCondition,
+			})
+			r.UpdateStatus(ctx, bwSecret, readStatus)
+
+			requeueAfter := r.RefreshInterval(bwSecret)
+			if untilOpen := time.Until(opens); untilOpen < requeueAfter {
+				requeueAfter = untilOpen
+			}
+			return ctrl.Result{
+				RequeueAfter: requeueAfter,
+			}, nil
+		}
+	}
+	apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, SyncWindowClosedCondition)
+
+	// A secret modified or deleted outside of the operator is restored with a full sync
+	drifted := SecretDrifted(bwSecret, existingK8sSecret)
+	if drifted {
+		logger.V(1).Info(fmt.Sprintf("%s/%s was modified outside of the operator.  Performing a full sync.", namespacedK8sSecret.Namespace, namespacedK8sSecret.Name))
+		lastSync = metav1.Time{}
+	}
+
+	// A changed spec, such as a removed map entry, is rendered from a full sync so that stale keys are pruned
+	specChanged := bwSecret.Status.ObservedGeneration != bwSecret.Generation
+	if specChanged {
+		logger.V(1).Info(fmt.Sprintf("%s/%s changed since the last sync.  Performing a full sync.", req.Namespace, req.Name))
+		lastSync = metav1.Time{}
+		SetReconcilingCondition(&bwSecret.Status.Conditions, bwSecret.Generation, "SpecChanged", fmt.Sprintf("Syncing generation %d", bwSecret.Generation))
+	}
+
+	if forceSync {
+		logger.V(1).Info(fmt.Sprintf("%s/%s has a new %s annotation.  Performing a full sync.", req.Namespace, req.Name, ForceSyncAnnotation))
+		lastSync = metav1.Time{}
+	}
+	// A dry run previews the whole secret
+	if bwSecret.Spec.DryRun {
+		lastSync = metav1.Time{}
+	}
+	summary.FullSync = lastSync.IsZero()
+
 	orgId := bwSecret.Spec.OrganizationId
 
-	refresh, secrets, err := r.PullSecretManagerSecretDeltas(logger, orgId, authToken, lastSync.Time)
+	factory, err := ResolveClientFactory(ctx, r.Client, r.BitwardenClientFactory, bwSecret)
+	if err != nil {
+		r.LogError(logger, ctx, bwSecret, err, "Invalid Bitwarden endpoints or CA bundle")
+		return ctrl.Result{
+			RequeueAfter: r.RefreshInterval(bwSecret),
+		}, nil
+	}
+
+	// BitwardenSecrets with their own endpoints or CA bundle are pulled with clients for those endpoints
+	puller := r
+	if factory != r.BitwardenClientFactory {
+		puller = &BitwardenSecretReconciler{
+			BitwardenClientFactory: factory,
+			StatePath:              r.StatePath,
+			ClientCache:            r.ClientCache,
+		}
+	}
+
+	if err := ValidateSecretFilter(bwSecret.Spec.Filter); err != nil {
+		err = &InvalidSpecError{Err: err}
+		r.LogError(logger, ctx, bwSecret, err, "Invalid secret filter")
+		return r.ResultForError(bwSecret, err), nil
+	}
+
+	if err := ValidateCreationPolicy(bwSecret); err != nil {
+		err = &InvalidSpecError{Err: err}
+		r.LogError(logger, ctx, bwSecret, err, "Invalid creation policy")
+		return r.ResultForError(bwSecret, err), nil
+	}
+
+	if err := ValidateOwnerReference(bwSecret); err != nil {
+		err = &InvalidSpecError{Err: err}
+		r.LogError(logger, ctx, bwSecret, err, "Invalid owner reference setting")
+		return r.ResultForError(bwSecret, err), nil
+	}
+
+	var refresh bool
+	var secrets map[string][]byte
+	var report PullReport
+	pullStart := time.Now()
+	selection := SelectionFor(bwSecret)
+	selection.Timeout = r.SDKCallTimeout(bwSecret)
+	pull := func() {
+		refresh, secrets, report, err = puller.PullSecretManagerSecretDeltas(ctx, logger, orgId, authToken, lastSync.Time, selection)
+	}
+
+	if r.PullPool != nil {
+		if poolErr := r.PullPool.Run(ctx, pull); poolErr != nil {
+			err = poolErr
+		}
+	} else {
+		pull()
+	}
+	summary.PullDuration = time.Since(pullStart)
+
+	// The operator is shutting down or the deadline of the reconcile passed.  The interrupted sync is not recorded as a
+	// failure and is retried soon.
+	if err != nil && ctx.Err() != nil {
+		logger.Info(fmt.Sprintf("Sync of %s/%s was interrupted", req.Namespace, req.Name), "reason", ctx.Err().Error())
+		summary.Result = "Interrupted"
+		return ctrl.Result{
+			RequeueAfter: r.TransientRetryInterval(bwSecret),
+		}, nil
+	}
+	r.APIHealth.RecordPull(err)
+	redactor.AddSecrets(secrets)
+	defer zeroSecrets(secrets)
+
+	if failover, ok := factory.(*FailoverClientFactory); ok {
+		SetFailedOverCondition(bwSecret, failover)
+	}
+	summary.SecretsFetched = len(secrets)
+	SetCertificateErrorCondition(bwSecret, err)
+	SetTimedOutCondition(bwSecret, err)
 
 	if err != nil {
-		r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Error pulling Secret Manager secrets from API => API: %s -- Identity: %s -- State: %s -- OrgId: %s ", r.BitwardenClientFactory.GetApiUrl(), r.BitwardenClientFactory.GetIdentityApiUrl(), r.StatePath, orgId))
+		err = &PullError{Err: err}
+		if bwclient.IsPanic(err) {
+			SetDegradedCondition(bwSecret, "ClientPanic", err.Error())
+		}
+		recordPullFailure(ctx, r.Recorder, bwSecret, err)
+		r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Error pulling Secret Manager secrets from API => API: %s -- Identity: %s -- State: %s -- OrgId: %s ", factory.GetApiUrl(), factory.GetIdentityApiUrl(), r.StatePath, orgId))
+		return r.ResultForError(bwSecret, err), nil
+	}
+
+	// Nothing is written in a dry run.  It is not recorded as a successful sync, so that the first sync after the dry
+	// run is turned off writes the previewed changes.
+	if bwSecret.Spec.DryRun {
+		preview, err := r.PreviewSync(ctx, bwSecret, secrets, report, existingK8sSecret)
+		if err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Dry run of %s/%s failed", req.Namespace, req.Name))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+
+		summary.Result = "DryRun"
+		summary.KeysAdded = len(preview.KeysCreated)
+		summary.KeysUpdated = len(preview.KeysUpdated)
+		summary.KeysRemoved = len(preview.KeysRemoved)
+
+		bwSecret.Status.DryRun = preview
+		SetDryRunCondition(bwSecret, preview)
+		bwSecret.Status.ObservedGeneration = bwSecret.Generation
+		bwSecret.Status.LastForceSync = bwSecret.Annotations[ForceSyncAnnotation]
+		SetReadyCondition(&bwSecret.Status.Conditions, bwSecret.Generation, fmt.Sprintf("Completed dry run for %s/%s", req.Namespace, req.Name))
+		RecordSyncAttempt(ctx, bwSecret, "DryRun", "")
+		r.UpdateStatus(ctx, bwSecret, readStatus)
 		return ctrl.Result{
-			RequeueAfter: time.Duration(r.RefreshIntervalSeconds) * time.Second,
+			RequeueAfter: r.RefreshInterval(bwSecret),
 		}, nil
 	}
+	bwSecret.Status.DryRun = nil
+	SetDryRunCondition(bwSecret, nil)
 
 	if refresh {
+		SetEmptyProjectCondition(bwSecret, report.EmptyProjects)
+		SetOrganizationMismatchCondition(&bwSecret.Status.Conditions, orgId, report.ForeignSecrets)
+		bwSecret.Status.FilteredSecrets = report.Filtered
+
+		writeStart := time.Now()
+		created := false
 		err = r.Get(ctx, namespacedK8sSecret, k8sSecret)
+		existingHash := SecretContentHash(k8sSecret)
 
 		//Creating new
 		if err != nil && errors.IsNotFound(err) {
 			k8sSecret = CreateK8sSecret(bwSecret)
 
-			// Cascading delete
-			if err := ctrl.SetControllerReference(bwSecret, k8sSecret, r.Scheme); err != nil {
-				r.LogError(logger, ctx, bwSecret, err, "Failed to set controller reference")
-				return ctrl.Result{
-					RequeueAfter: time.Duration(r.RefreshIntervalSeconds) * time.Second,
-				}, err
+			// Cascading delete.  Secrets merged into are left to whoever else writes to them, and standalone secrets
+			// outlive the BitwardenSecret.
+			if OwnsK8sSecret(bwSecret) {
+				if err := ctrl.SetControllerReference(bwSecret, k8sSecret, r.Scheme); err != nil {
+					r.LogError(logger, ctx, bwSecret, err, "Failed to set controller reference")
+					return ctrl.Result{
+						RequeueAfter: r.RefreshInterval(bwSecret),
+					}, err
+				}
 			}
 
-			err := r.Create(ctx, k8sSecret)
+			err := r.Create(ctx, k8sSecret, client.FieldOwner(FieldManager))
 			if err != nil {
 				r.LogError(logger, ctx, bwSecret, err, "Creation of K8s secret failed.")
 				return ctrl.Result{
-					RequeueAfter: time.Duration(r.RefreshIntervalSeconds) * time.Second,
+					RequeueAfter: r.RefreshInterval(bwSecret),
 				}, err
 			}
+			created = true
 
 		}
 
+		current := k8sSecret.DeepCopy()
+		previousData := k8sSecret.Data
+		previousRevisions := ParseRevisionsAnnotation(k8sSecret)
+
 		UpdateSecretValues(k8sSecret, secrets)
 
-		ApplySecretMap(bwSecret, k8sSecret)
+		// With the Partial failure policy keys that fail to render keep their previous values instead of failing the sync
+		failures := KeyFailures{}
+		if WritesPartially(bwSecret) {
+			ApplySecretMapPartially(bwSecret, k8sSecret, failures)
+		} else if err := ApplySecretMap(bwSecret, k8sSecret); err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to map the secrets of %s/%s", req.Namespace, req.Name))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+
+		ApplyKeyTransform(bwSecret, k8sSecret)
+
+		if err := ApplyOutput(bwSecret, k8sSecret); err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to render the %s output of %s/%s", bwSecret.Spec.Output.Format, req.Namespace, req.Name))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+
+		if err := ApplyDockerConfig(bwSecret, secrets, k8sSecret); err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to assemble the docker config for %s/%s", req.Namespace, req.Name))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
 
-		err = SetK8sSecretAnnotations(bwSecret, k8sSecret)
+		if err := ApplyTLS(bwSecret, secrets, k8sSecret); err != nil {
+			SetInvalidTLSCondition(bwSecret, err)
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to assemble the TLS secret %s/%s", req.Namespace, bwSecret.Spec.SecretName))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
 
+		if err := ApplyBasicAuth(bwSecret, secrets, k8sSecret); err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to assemble the basic-auth secret %s/%s", req.Namespace, bwSecret.Spec.SecretName))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+
+		if err := ApplySSHAuth(bwSecret, secrets, k8sSecret); err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to assemble the SSH secret %s/%s", req.Namespace, bwSecret.Spec.SecretName))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+
+		if err := ApplyKubeconfig(bwSecret, secrets, k8sSecret); err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to assemble the kubeconfig for %s/%s", req.Namespace, req.Name))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+
+		if WritesPartially(bwSecret) {
+			ApplyTemplatePartially(bwSecret, secrets, k8sSecret, failures)
+			err = nil
+		} else {
+			err = ApplyTemplate(bwSecret, secrets, k8sSecret)
+		}
+		SetTemplateErrorCondition(bwSecret, err)
 		if err != nil {
-			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Error setting annotations for  %s/%s", req.Namespace, req.Name))
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to render the templates of %s/%s", req.Namespace, req.Name))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
 		}
 
-		err = r.Update(ctx, k8sSecret)
+		KeepFailedKeys(k8sSecret.Data, previousData, failures)
+		SetKeysFailedCondition(ctx, bwSecret, failures)
+		if len(failures) > 0 {
+			logger.Info(fmt.Sprintf("%d keys of %s/%s failed to render and kept their previous values", len(failures), req.Namespace, bwSecret.Spec.SecretName), "keys", keyFailureNames(failures))
+		}
+
+		err = ValidateSecretType(bwSecret, k8sSecret)
+		if TargetSecretType(bwSecret) == corev1.SecretTypeTLS {
+			SetInvalidTLSCondition(bwSecret, err)
+		} else {
+			SetInvalidTLSCondition(bwSecret, nil)
+		}
+		if err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("%s/%s is not a valid %s secret", req.Namespace, bwSecret.Spec.SecretName, TargetSecretType(bwSecret)))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+
+		configMap := SplitConfigMap(bwSecret, k8sSecret)
+		syncedKeys := SyncedKeyNames(k8sSecret.Data)
+
+		if MergesIntoK8sSecret(bwSecret) {
+			MergeManagedKeys(k8sSecret, previousData)
+		}
+
+		revisions := KeyRevisions(bwSecret, report.Revisions, k8sSecret.Data)
+		SetPinnedRevisionUnavailableCondition(bwSecret, ApplyPinnedRevisions(bwSecret, report.Revisions, previousData, previousRevisions, k8sSecret.Data, revisions))
+
+		summary.RecordKeyChanges(previousData, k8sSecret.Data)
+
+		// Versioned secrets hold the data and the secret itself becomes an alias of the active version
+		if bwSecret.Spec.Versioning != nil {
+			version, err := r.WriteSecretVersion(ctx, bwSecret, k8sSecret.Data)
+			if err != nil {
+				r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to write a version of %s/%s", req.Namespace, bwSecret.Spec.SecretName))
+				return ctrl.Result{
+					RequeueAfter: r.RefreshInterval(bwSecret),
+				}, err
+			}
+
+			bwSecret.Status.CurrentVersion = version
+			k8sSecret.Data = map[string][]byte{VersionAliasKey: []byte(version)}
+		} else {
+			bwSecret.Status.CurrentVersion = ""
+		}
+
+		ApplySecretMetadata(bwSecret, k8sSecret)
+		SetK8sSecretAnnotations(bwSecret, k8sSecret)
+		SetRevisionsAnnotation(k8sSecret, revisions)
+		SetSourceMetadataAnnotations(bwSecret, k8sSecret, revisions, report.Sources)
+
+		// Secrets created before ownerReference was turned off are released
+		if !OwnsK8sSecret(bwSecret) {
+			RemoveOwnerReference(bwSecret, k8sSecret)
+		}
+
+		// The alias of versioned secrets only holds the name of the active version
+		secretType := TargetSecretType(bwSecret)
+		if bwSecret.Spec.Versioning != nil {
+			secretType = corev1.SecretTypeOpaque
+		}
+
+		replace := k8sSecret.Type != secretType || K8sSecretImmutable(k8sSecret)
+
+		// Replacing a secret merged into would drop the keys of the other tools writing to it
+		if replace && MergesIntoK8sSecret(bwSecret) && !created {
+			err := fmt.Errorf("cannot merge into the %s secret %s, since it would have to be replaced", k8sSecret.Type, k8sSecret.Name)
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to update  %s/%s", req.Namespace, req.Name))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+		k8sSecret.Immutable = TargetImmutable(bwSecret)
+
+		// Writing the same values again would only bump the resourceVersion of the secret
+		unchanged := !created && !replace && SecretContentHash(k8sSecret) == existingHash
+
+		// The previous data is kept before it is overwritten, so that the secret can be rolled back to it
+		if bwSecret.Spec.History != nil && bwSecret.Spec.Versioning == nil && !created && existingK8sSecret != nil && summary.KeysAdded+summary.KeysUpdated+summary.KeysRemoved > 0 {
+			if _, err := r.WriteHistorySnapshot(ctx, bwSecret, existingK8sSecret); err != nil {
+				r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to keep the previous data of %s/%s", req.Namespace, bwSecret.Spec.SecretName))
+				return ctrl.Result{
+					RequeueAfter: r.RefreshInterval(bwSecret),
+				}, err
+			}
+		}
+
+		if replace {
+			err = r.ReplaceK8sSecret(ctx, k8sSecret, secretType)
+		} else if !unchanged {
+			err = r.ApplyK8sSecret(ctx, bwSecret, current, k8sSecret, drifted)
+		}
 		if err != nil {
 			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to update  %s/%s", req.Namespace, req.Name))
 			return ctrl.Result{
-				RequeueAfter: time.Duration(r.RefreshIntervalSeconds) * time.Second,
+				RequeueAfter: r.RefreshInterval(bwSecret),
 			}, err
 		}
 
+		if created {
+			recordEvent(ctx, r.Recorder, bwSecret, corev1.EventTypeNormal, SecretCreatedReason, fmt.Sprintf("Created secret %s", k8sSecret.Name))
+		} else if unchanged {
+			logger.V(1).Info(fmt.Sprintf("%s/%s is up to date.  Skipping update.", req.Namespace, bwSecret.Spec.SecretName))
+			secretUpdatesSkippedTotal.Inc()
+		} else {
+			recordEvent(ctx, r.Recorder, bwSecret, corev1.EventTypeNormal, SecretUpdatedReason, fmt.Sprintf("Updated secret %s", k8sSecret.Name))
+		}
+
+		// Workloads of a new secret start with its values anyway
+		if !created && summary.KeysAdded+summary.KeysUpdated+summary.KeysRemoved > 0 && RolloutRestartEnabled(bwSecret) {
+			restarted, err := r.RestartWorkloads(ctx, bwSecret, k8sSecret.Name)
+			if len(restarted) > 0 {
+				recordEvent(ctx, r.Recorder, bwSecret, corev1.EventTypeNormal, RolloutRestartedReason, fmt.Sprintf("Restarted %s", strings.Join(restarted, ", ")))
+			}
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("Failed to restart the workloads using %s/%s", req.Namespace, k8sSecret.Name))
+				recordEvent(ctx, r.Recorder, bwSecret, corev1.EventTypeWarning, RolloutFailedReason, "Failed to restart workloads: "+err.Error())
+			}
+		}
+
+		err = r.WriteConfigMap(ctx, bwSecret, configMap)
+		if err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to write the ConfigMap of %s/%s", req.Namespace, req.Name))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, err
+		}
+
+		if err := r.WriteTargetSecrets(ctx, bwSecret, secrets); err != nil {
+			r.LogError(logger, ctx, bwSecret, err, fmt.Sprintf("Failed to write the target secrets of %s/%s", req.Namespace, req.Name))
+			return ctrl.Result{
+				RequeueAfter: r.RefreshInterval(bwSecret),
+			}, nil
+		}
+		observeDuration(secretWriteDuration, writeStart)
+		summary.WriteDuration = time.Since(writeStart)
+
+		if bwSecret.Spec.Versioning != nil {
+			if err := r.PruneSecretVersions(ctx, bwSecret, bwSecret.Status.CurrentVersion); err != nil {
+				logger.Error(err, fmt.Sprintf("Failed to prune old versions of %s/%s", req.Namespace, bwSecret.Spec.SecretName))
+			}
+		}
+
+		bwSecret.Status.ObservedGeneration = bwSecret.Generation
+		if err := RecordAppliedSecretMap(bwSecret); err != nil {
+			logger.Error(err, fmt.Sprintf("Failed to record the applied map of %s/%s", req.Namespace, req.Name))
+		}
+		bwSecret.Status.SecretResourceVersion = k8sSecret.ResourceVersion
+		bwSecret.Status.SecretUID = string(k8sSecret.UID)
+		bwSecret.Status.SyncedKeyCount = len(syncedKeys)
+		bwSecret.Status.SyncedKeys = syncedKeys
+
+		// The previous secret is kept until the new one is written, so that its consumers can move over.  A failed
+		// clean up is retried on the next sync.
+		if err := r.CleanUpRenamedSecret(ctx, bwSecret); err != nil {
+			logger.Error(err, fmt.Sprintf("Failed to clean up %s/%s after spec.secretName changed", req.Namespace, bwSecret.Status.SecretName))
+		} else {
+			bwSecret.Status.SecretName = bwSecret.Spec.SecretName
+		}
+		bwSecret.Status.LastForceSync = bwSecret.Annotations[ForceSyncAnnotation]
+
+		bwSecret.Status.LastSyncTrace = DescribeSync(bwSecret, secrets)
+		if report.Filtered > 0 {
+			bwSecret.Status.LastSyncTrace += fmt.Sprintf(" The filter left out %d secrets.", report.Filtered)
+		}
+		if bwSecret.Status.CurrentVersion != "" {
+			bwSecret.Status.LastSyncTrace += fmt.Sprintf(" The data was written to version %s.", bwSecret.Status.CurrentVersion)
+		}
+		if drifted {
+			bwSecret.Status.LastSyncTrace = "The secret was modified outside of the operator and has been restored. " + bwSecret.Status.LastSyncTrace
+		}
 		r.LogCompletion(logger, ctx, bwSecret, fmt.Sprintf("Completed sync for %s/%s", req.Namespace, req.Name))
+		summary.Result = "Succeeded"
 	} else {
-		logger.Info(fmt.Sprintf("No changes to %s/%s.  Skipping sync.", req.Namespace, req.Name))
+		logger.V(1).Info(fmt.Sprintf("No changes to %s/%s.  Skipping sync.", req.Namespace, req.Name))
+		summary.Result = "NoChanges"
+
+		bwSecret.Status.LastSyncTrace = noChangesTrace
+		RecordSyncAttempt(ctx, bwSecret, "NoChanges", "")
+		r.UpdateStatus(ctx, bwSecret, readStatus)
 	}
 
 	return ctrl.Result{
-		RequeueAfter: time.Duration(r.RefreshIntervalSeconds) * time.Second,
+		RequeueAfter: r.RefreshInterval(bwSecret),
 	}, nil
 }
 
+// SyncedKeyNames returns the sorted names of the keys of the data, for the status of the BitwardenSecret.
+func SyncedKeyNames(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// UpdateStatus writes the status of the BitwardenSecret, unless it is the same as the status read at the start of the
+// reconcile.
+func (r *BitwardenSecretReconciler) UpdateStatus(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, readStatus *operatorsv1.BitwardenSecretStatus) error {
+	if equality.Semantic.DeepEqual(readStatus, &bwSecret.Status) {
+		return nil
+	}
+
+	return r.Status().Update(ctx, bwSecret)
+}
+
+// RefreshInterval returns how often the BitwardenSecret is synced: its own refresh interval when set, otherwise the
+// operator refresh interval.
+func (r *BitwardenSecretReconciler) RefreshInterval(bwSecret *operatorsv1.BitwardenSecret) time.Duration {
+	if bwSecret.Spec.RefreshInterval == nil {
+		return time.Duration(r.RefreshIntervalSeconds) * time.Second
+	}
+
+	if bwSecret.Spec.RefreshInterval.Duration < MinimumRefreshInterval {
+		return MinimumRefreshInterval
+	}
+
+	return bwSecret.Spec.RefreshInterval.Duration
+}
+
+// ForceSyncRequested reports whether the BitwardenSecret has a k8s.bitwarden.com/force-sync value that has not been
+// handled by a successful sync yet.
+func ForceSyncRequested(bwSecret *operatorsv1.BitwardenSecret) bool {
+	nonce := bwSecret.Annotations[ForceSyncAnnotation]
+	return nonce != "" && nonce != bwSecret.Status.LastForceSync
+}
+
+// forceSyncPredicate passes updates of the BitwardenSecret that change its k8s.bitwarden.com/force-sync annotation,
+// which do not change its generation.
+var forceSyncPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectOld.GetAnnotations()[ForceSyncAnnotation] != e.ObjectNew.GetAnnotations()[ForceSyncAnnotation]
+	},
+}
+
+// rollbackPredicate passes updates of the BitwardenSecret that set, change, or remove its k8s.bitwarden.com/rollback-to
+// annotation.
+var rollbackPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectOld.GetAnnotations()[RollbackAnnotation] != e.ObjectNew.GetAnnotations()[RollbackAnnotation]
+	},
+}
+
+// ownedSecretPredicate passes the events of owned secrets that may be drift.  The operator creates the secrets itself,
+// and the event of its own update is ignored by Reconcile since it follows the last sync too closely.
+var ownedSecretPredicate = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return false
+	},
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *BitwardenSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&operatorsv1.BitwardenSecret{}).
-		Complete(r)
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		// Status updates, such as the sync history, must not trigger another sync
+		For(&operatorsv1.BitwardenSecret{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, forceSyncPredicate, rollbackPredicate))).
+		// Secrets deleted or edited outside of the operator are restored right away instead of on the next refresh
+		Owns(&corev1.Secret{}, builder.WithPredicates(ownedSecretPredicate)).
+		// Rotated authorization tokens retry BitwardenSecrets stalled by a permanent error right away
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.stalledBitwardenSecretsForAuthToken), builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		WithOptions(crcontroller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles})
+
+	// Failing over or back updates the FailedOver condition of every BitwardenSecret right away
+	if failover, ok := r.BitwardenClientFactory.(*FailoverClientFactory); ok {
+		controllerBuilder = controllerBuilder.WatchesRawSource(&source.Channel{Source: failover.Changes()}, handler.EnqueueRequestsFromMapFunc(r.bitwardenSecretsForFailover))
+	}
+
+	return controllerBuilder.Complete(r)
+}
+
+// GetExistingK8sSecret returns the Kubernetes secret the BitwardenSecret writes to, or nil if it does not exist yet.
+func (r *BitwardenSecretReconciler) GetExistingK8sSecret(ctx context.Context, namespacedK8sSecret types.NamespacedName) (*corev1.Secret, error) {
+	k8sSecret := &corev1.Secret{}
+	err := r.Get(ctx, namespacedK8sSecret, k8sSecret)
+
+	if err != nil && errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return k8sSecret, nil
+}
+
+// IsK8sSecretIgnored reports whether the secret has been taken out of the operator's control with the
+// k8s.bitwarden.com/ignore annotation.
+func IsK8sSecretIgnored(k8sSecret *corev1.Secret) bool {
+	return k8sSecret != nil && k8sSecret.Annotations[IgnoreAnnotation] == "true"
+}
+
+// SecretDrifted reports whether the Kubernetes secret changed since the operator last wrote it, by comparing its
+// resourceVersion and UID with the ones recorded in the BitwardenSecret status.  A nil secret has been deleted if the
+// operator wrote it before.
+func SecretDrifted(bwSecret *operatorsv1.BitwardenSecret, k8sSecret *corev1.Secret) bool {
+	if k8sSecret == nil {
+		return bwSecret.Status.SecretResourceVersion != ""
+	}
+
+	return k8sSecret.ResourceVersion != bwSecret.Status.SecretResourceVersion || string(k8sSecret.UID) != bwSecret.Status.SecretUID
 }
 
 func (r *BitwardenSecretReconciler) LogError(logger logr.Logger, ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, err error, message string) {
+	// Neither the auth token nor pulled values may reach the logs, the status, or traces
+	redactor := redactorFrom(ctx)
+	err = redactor.RedactError(err)
+	message = redactor.Redact(message)
+
 	logger.Error(err, message)
+	tracing.RecordFailure(ctx, err, message)
 
 	if bwSecret != nil {
 		errorCondition := metav1.Condition{
@@ -198,12 +877,24 @@ func (r *BitwardenSecretReconciler) LogError(logger logr.Logger, ctx context.Con
 		}
 
 		apimeta.SetStatusCondition(&bwSecret.Status.Conditions, errorCondition)
+
+		// A sync retried shortly is still progressing
+		switch class, reason := ClassifyError(err); class {
+		case ErrorClassTransient:
+			SetReconcilingCondition(&bwSecret.Status.Conditions, bwSecret.Generation, reason, errorCondition.Message)
+		case ErrorClassPermanent:
+			SetStalledCondition(&bwSecret.Status.Conditions, bwSecret.Generation, reason, errorCondition.Message+".  Retried on the next refresh, or right away once the spec or the authorization token changes")
+		default:
+			SetStalledCondition(&bwSecret.Status.Conditions, bwSecret.Generation, errorCondition.Reason, errorCondition.Message)
+		}
+		bwSecret.Status.LastSyncTrace = fmt.Sprintf("Sync failed: %s", message)
+		RecordSyncAttempt(ctx, bwSecret, "Failed", errorCondition.Message)
 		r.Status().Update(ctx, bwSecret)
 	}
 }
 
 func (r *BitwardenSecretReconciler) LogCompletion(logger logr.Logger, ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, message string) {
-	logger.Info(message)
+	logger.V(1).Info(message)
 
 	if bwSecret != nil {
 		completeCondition := metav1.Condition{
@@ -216,44 +907,169 @@ func (r *BitwardenSecretReconciler) LogCompletion(logger logr.Logger, ctx contex
 		bwSecret.Status.LastSuccessfulSyncTime = metav1.Time{Time: time.Now().UTC()}
 
 		apimeta.SetStatusCondition(&bwSecret.Status.Conditions, completeCondition)
+		SetReadyCondition(&bwSecret.Status.Conditions, bwSecret.Generation, message)
+		RecordSyncAttempt(ctx, bwSecret, "Succeeded", "")
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, "Degraded")
 		r.Status().Update(ctx, bwSecret)
 	}
 }
 
+// SetDegradedCondition marks the BitwardenSecret as Degraded.  The condition is cleared by the next successful sync.
+func SetDegradedCondition(bwSecret *operatorsv1.BitwardenSecret, reason string, message string) {
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+		Type:    "Degraded",
+	})
+}
+
 // This function will determine if any secrets have been updated and return all secrets assigned to the machine account if so.
+// Only the secrets of the selection are returned: those belonging to its projects, referenced by ID or name, and whose
+// key passes its filter.
 // First returned value is a boolean stating if something changed or not.
 // The second returned value is a mapping of secret IDs and their values from Secrets Manager
-func (r *BitwardenSecretReconciler) PullSecretManagerSecretDeltas(logger logr.Logger, orgId string, authToken string, lastSync time.Time) (bool, map[string][]byte, error) {
-	bitwardenClient, err := r.BitwardenClientFactory.GetBitwardenClient()
+// The third returned value reports the projects that hold no secrets the machine account can access and the number of
+// filtered secrets
+func (r *BitwardenSecretReconciler) PullSecretManagerSecretDeltas(ctx context.Context, logger logr.Logger, orgId string, authToken string, lastSync time.Time, selection PullSelection) (bool, map[string][]byte, PullReport, error) {
+	ctx, span := tracing.Start(ctx, "PullSecretManagerSecretDeltas", trace.WithAttributes(attribute.Bool("bitwarden.full_sync", lastSync.IsZero())))
+	refresh, secrets, report, err := r.pullSecretManagerSecretDeltas(ctx, logger, orgId, authToken, lastSync, selection)
+	span.SetAttributes(attribute.Bool("bitwarden.has_changes", refresh), attribute.Int("bitwarden.secrets", len(secrets)))
+	tracing.End(span, err)
+
+	return refresh, secrets, report, err
+}
+
+func (r *BitwardenSecretReconciler) pullSecretManagerSecretDeltas(ctx context.Context, logger logr.Logger, orgId string, authToken string, lastSync time.Time, selection PullSelection) (bool, map[string][]byte, PullReport, error) {
+	if r.ClientCache != nil {
+		bitwardenClient, release, err := r.ClientCache.Get(r.BitwardenClientFactory, authToken, orgId)
+		if err != nil {
+			logClientPanic(logger, err)
+			logger.Error(err, "Failed to create client")
+			return false, nil, PullReport{}, err
+		}
+
+		refresh, secrets, report, err := r.syncSecrets(ctx, logger, bitwardenClient, orgId, authToken, lastSync, selection)
+		release(err)
+		return refresh, secrets, report, err
+	}
+
+	bitwardenClient, err := newBitwardenClient(r.BitwardenClientFactory)
 	if err != nil {
+		logClientPanic(logger, err)
 		logger.Error(err, "Failed to create client")
-		return false, nil, err
+		return false, nil, PullReport{}, err
+	}
+
+	refresh, secrets, report, err := r.syncSecrets(ctx, logger, bitwardenClient, orgId, authToken, lastSync, selection)
+	if err != nil {
+		discardPanickedClient(logger, bitwardenClient, err)
+		return false, nil, PullReport{}, err
+	}
+
+	defer bitwardenClient.Close()
+
+	return refresh, secrets, report, nil
+}
+
+func (r *BitwardenSecretReconciler) syncSecrets(ctx context.Context, logger logr.Logger, bitwardenClient bwclient.BitwardenClientInterface, orgId string, authToken string, lastSync time.Time, selection PullSelection) (bool, map[string][]byte, PullReport, error) {
+	// Every machine account keeps its own state, so that concurrent logins do not overwrite each other's
+	statePath, err := tokenStatePath(r.StatePath, authToken)
+	if err != nil {
+		logger.Error(err, "Failed to create the state directory")
+		return false, nil, PullReport{}, err
 	}
 
-	err = bitwardenClient.AccessTokenLogin(authToken, &r.StatePath)
+	_, span := tracing.Start(ctx, "AccessTokenLogin", trace.WithSpanKind(trace.SpanKindClient))
+	err = callWithContext(ctx, bitwardenClient, "AccessTokenLogin", selection.Timeout, func() error {
+		return bitwardenClient.AccessTokenLogin(authToken, &statePath)
+	})
+	tracing.End(span, err)
+	// A login that did not complete or was paused by the circuit breaker says nothing about the credentials
+	if abandonsClient(err) || ctx.Err() != nil || IsCircuitOpenError(err) {
+		logger.Error(err, "Failed to authenticate")
+		return false, nil, PullReport{}, err
+	}
 	if err != nil {
+		logClientPanic(logger, err)
 		logger.Error(err, "Failed to authenticate")
-		return false, nil, err
+		return false, nil, PullReport{}, &AuthError{Err: err}
 	}
 
 	secrets := map[string][]byte{}
 
-	smSecretResponse, err := bitwardenClient.Secrets().Sync(orgId, &lastSync)
+	_, span = tracing.Start(ctx, "Secrets.Sync", trace.WithSpanKind(trace.SpanKindClient))
+	var smSecretResponse *bwclient.SecretsSyncResponse
+	err = callWithContext(ctx, bitwardenClient, "Secrets.Sync", selection.Timeout, func() error {
+		var err error
+		smSecretResponse, err = bitwardenClient.Secrets().Sync(orgId, &lastSync)
+		return err
+	})
+	tracing.End(span, err)
 
 	if err != nil {
+		logClientPanic(logger, err)
 		logger.Error(err, "Failed to get secrets since last sync.")
-		return false, nil, err
+		return false, nil, PullReport{}, err
 	}
 
-	smSecretVals := smSecretResponse.Secrets
+	smSecretVals, foreign := FilterForeignSecrets(smSecretResponse.Secrets, orgId)
+	report := PullReport{ForeignSecrets: foreign}
+	var projects []bwclient.ProjectResponse
+	if (len(selection.Projects) > 0 || selection.SourceMetadata) && smSecretResponse.HasChanges {
+		_, span := tracing.Start(ctx, "Projects.List", trace.WithSpanKind(trace.SpanKindClient))
+		var smProjects *bwclient.ProjectsResponse
+		err := callWithContext(ctx, bitwardenClient, "Projects.List", 0, func() error {
+			var err error
+			smProjects, err = bitwardenClient.Projects().List(orgId)
+			return err
+		})
+		tracing.End(span, err)
+		if err != nil {
+			logClientPanic(logger, err)
+			logger.Error(err, "Failed to list projects.")
+			return false, nil, PullReport{}, err
+		}
+		projects = smProjects.Data
+	}
+
+	if len(selection.Projects) > 0 && smSecretResponse.HasChanges {
+		smSecretVals, report.EmptyProjects = FilterSecretsByProjects(smSecretVals, projects, selection.Projects)
+	}
+
+	smSecretVals, report.Filtered, err = FilterSecretsByKey(smSecretVals, selection.Filter)
+	if err != nil {
+		return false, nil, PullReport{}, err
+	}
 
+	report.Revisions = make(map[string]string, len(smSecretVals))
 	for _, smSecretVal := range smSecretVals {
 		secrets[smSecretVal.ID] = []byte(smSecretVal.Value)
+		report.Revisions[smSecretVal.ID] = smSecretVal.RevisionDate
+	}
+	AddNotes(secrets, smSecretVals, selection.NoteIDs)
+	if selection.SourceMetadata {
+		report.Sources = SecretSources(smSecretVals, projects)
 	}
 
-	defer bitwardenClient.Close()
+	if smSecretResponse.HasChanges {
+		report.ForeignSecrets = append(report.ForeignSecrets, findForeignMappedSecrets(ctx, bitwardenClient, orgId, selection.MappedIDs, secrets)...)
+	}
+
+	return smSecretResponse.HasChanges, secrets, report, nil
+}
 
-	return smSecretResponse.HasChanges, secrets, nil
+// discardPanickedClient releases a client whose call panicked so that the next sync starts with a fresh one.
+func discardPanickedClient(logger logr.Logger, bitwardenClient bwclient.BitwardenClientInterface, err error) {
+	if bwclient.IsPanic(err) {
+		bitwardenClient.Close()
+	}
+}
+
+func logClientPanic(logger logr.Logger, err error) {
+	if panicErr, ok := err.(*bwclient.PanicError); ok {
+		logger.Error(err, "Recovered from a Bitwarden client panic", "stack", string(panicErr.Stack))
+	}
 }
 
 func UpdateSecretValues(secret *corev1.Secret, secrets map[string][]byte) {
@@ -279,40 +1095,229 @@ func CreateK8sSecret(bwSecret *operatorsv1.BitwardenSecret) *corev1.Secret {
 	return secret
 }
 
-func ApplySecretMap(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret) {
+func ApplySecretMap(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret) error {
 	// If no explicit map is provided, leave all values in place
 	if bwSecret.Spec.SecretMap == nil {
-		return
+		return nil
 	}
 
 	// Otherwise, build a new Data map with only the mapped keys
 	filtered := make(map[string][]byte, len(bwSecret.Spec.SecretMap))
 	for _, m := range bwSecret.Spec.SecretMap {
-		if v, ok := secret.Data[m.BwSecretId]; ok {
-			filtered[m.SecretKeyName] = v
+		v, ok, err := MappedValue(m, secret.Data)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			for _, key := range MappedKeys(m) {
+				filtered[key] = v
+			}
 		}
 	}
 
 	secret.Data = filtered
+	return nil
+}
+
+// MappedValue returns the value a map entry writes, and false if its secret was not pulled.
+func MappedValue(m operatorsv1.SecretMap, secrets map[string][]byte) ([]byte, bool, error) {
+	id := m.BwSecretId
+	if m.Source == operatorsv1.SecretSourceNote {
+		id = NoteKey(id)
+	}
+
+	v, ok := secrets[id]
+	if !ok {
+		return nil, false, nil
+	}
+
+	// Several keys can be split off a single JSON secret
+	if m.Property != "" {
+		var err error
+		if v, err = ExtractProperty(v, m.Property); err != nil {
+			return nil, false, fmt.Errorf("map entry %s of secret %s: %w", m.SecretKeyName, m.BwSecretId, err)
+		}
+	}
+
+	v, err := DecodeValue(v, m.DecodingStrategy)
+	if err != nil {
+		return nil, false, fmt.Errorf("map entry %s of secret %s: %w", m.SecretKeyName, m.BwSecretId, err)
+	}
+
+	return v, true, nil
+}
+
+// MappedKeys returns every key a map entry writes its value to: the secret key name followed by its aliases.
+func MappedKeys(m operatorsv1.SecretMap) []string {
+	return append([]string{m.SecretKeyName}, m.Aliases...)
+}
+
+// RenderK8sSecret returns the Kubernetes secret a full sync of the BitwardenSecret would write, without touching the
+// cluster.
+func RenderK8sSecret(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte) (*corev1.Secret, error) {
+	secret, _, err := renderK8sSecret(bwSecret, secrets, nil)
+	return secret, err
 }
 
-func SetK8sSecretAnnotations(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret) error {
+// renderK8sSecret renders the Kubernetes secret like RenderK8sSecret.  With the Partial failure policy the keys that
+// fail to render keep their value in previous, if any, and are returned as failures.
+func renderK8sSecret(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte, previous map[string][]byte) (*corev1.Secret, KeyFailures, error) {
+	secret := CreateK8sSecret(bwSecret)
+	secret.Type = TargetSecretType(bwSecret)
+	secret.Immutable = TargetImmutable(bwSecret)
+	UpdateSecretValues(secret, secrets)
+	failures := KeyFailures{}
+	if WritesPartially(bwSecret) {
+		ApplySecretMapPartially(bwSecret, secret, failures)
+	} else if err := ApplySecretMap(bwSecret, secret); err != nil {
+		return nil, nil, err
+	}
+	ApplyKeyTransform(bwSecret, secret)
+	if err := ApplyOutput(bwSecret, secret); err != nil {
+		return nil, nil, err
+	}
+	ApplySecretMetadata(bwSecret, secret)
+
+	if err := ApplyDockerConfig(bwSecret, secrets, secret); err != nil {
+		return nil, nil, err
+	}
+
+	if err := ApplyTLS(bwSecret, secrets, secret); err != nil {
+		return nil, nil, err
+	}
+
+	if err := ApplyBasicAuth(bwSecret, secrets, secret); err != nil {
+		return nil, nil, err
+	}
+
+	if err := ApplySSHAuth(bwSecret, secrets, secret); err != nil {
+		return nil, nil, err
+	}
+
+	if err := ApplyKubeconfig(bwSecret, secrets, secret); err != nil {
+		return nil, nil, err
+	}
+
+	if WritesPartially(bwSecret) {
+		ApplyTemplatePartially(bwSecret, secrets, secret, failures)
+	} else if err := ApplyTemplate(bwSecret, secrets, secret); err != nil {
+		return nil, nil, err
+	}
+	KeepFailedKeys(secret.Data, previous, failures)
+
+	if err := ValidateSecretType(bwSecret, secret); err != nil {
+		return nil, nil, err
+	}
+
+	return secret, failures, nil
+}
+
+// Setting this annotation to "true" on a Kubernetes secret stops the operator from updating it
+const IgnoreAnnotation = "k8s.bitwarden.com/ignore"
+
+// Condition set while syncing is suspended by spec.paused
+const PausedCondition = "Paused"
+
+// Setting this annotation on a BitwardenSecret to a new value, such as a timestamp, triggers an immediate full sync
+const ForceSyncAnnotation = "k8s.bitwarden.com/force-sync"
+
+// The shortest refresh interval a BitwardenSecret may request, to protect the Secrets Manager API from overly
+// frequent syncs
+const MinimumRefreshInterval = 30 * time.Second
+
+const noChangesTrace = "Secrets Manager reported no changes since the last successful sync; the secret was not updated."
+
+// Maximum number of secret IDs listed in a sync trace
+const maxTraceIds = 10
+
+// DescribeSync explains which of the pulled secrets ended up in the Kubernetes secret.
+func DescribeSync(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte) string {
+	trace := fmt.Sprintf("Synced %d secrets from Secrets Manager.", len(secrets))
+	if bwSecret.Spec.SecretMap == nil {
+		return trace + " No map is set, so all secrets were written using their IDs as keys."
+	}
+
+	mapped := map[string]bool{}
+	unmatched := []string{}
+	for _, m := range bwSecret.Spec.SecretMap {
+		if _, ok := secrets[m.BwSecretId]; ok {
+			mapped[m.BwSecretId] = true
+		} else {
+			unmatched = append(unmatched, m.BwSecretId)
+		}
+	}
+
+	trace += fmt.Sprintf(" The map kept %d of them; %d secrets without a map entry were left out.", len(mapped), len(secrets)-len(mapped))
+	if len(unmatched) > 0 {
+		trace += fmt.Sprintf(" Map entries with no matching secret the machine account can access: %s.", joinTraceIds(unmatched))
+	}
+
+	return trace
+}
+
+func joinTraceIds(ids []string) string {
+	if len(ids) <= maxTraceIds {
+		return strings.Join(ids, ", ")
+	}
+
+	return fmt.Sprintf("%s and %d more", strings.Join(ids[:maxTraceIds], ", "), len(ids)-maxTraceIds)
+}
+
+// ApplySecretMetadata adds the labels and annotations of spec.secretMetadata to the secret.  The label linking the
+// secret to its BitwardenSecret cannot be overridden.
+func ApplySecretMetadata(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret) {
+	if bwSecret.Spec.SecretMetadata == nil {
+		return
+	}
+
+	if secret.ObjectMeta.Labels == nil {
+		secret.ObjectMeta.Labels = map[string]string{}
+	}
+	for key, value := range bwSecret.Spec.SecretMetadata.Labels {
+		secret.ObjectMeta.Labels[key] = value
+	}
+	secret.ObjectMeta.Labels["k8s.bitwarden.com/bw-secret"] = string(bwSecret.UID)
+
+	if secret.ObjectMeta.Annotations == nil {
+		secret.ObjectMeta.Annotations = map[string]string{}
+	}
+	for key, value := range bwSecret.Spec.SecretMetadata.Annotations {
+		secret.ObjectMeta.Annotations[key] = value
+	}
+}
+
+func SetK8sSecretAnnotations(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret) {
 
 	if secret.ObjectMeta.Annotations == nil {
 		secret.ObjectMeta.Annotations = map[string]string{}
 	}
 
-	secret.ObjectMeta.Annotations["k8s.bitwarden.com/sync-time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	secret.ObjectMeta.Annotations[SyncTimeAnnotation] = time.Now().UTC().Format(time.RFC3339Nano)
+	SetDataChecksumAnnotation(secret)
 
+	// The applied map is recorded in the BitwardenSecret status.  Remove the annotation written by older versions.
+	delete(secret.ObjectMeta.Annotations, "k8s.bitwarden.com/custom-map")
+}
+
+// RecordAppliedSecretMap records the map written to the Kubernetes secret, and a hash of it, in the BitwardenSecret
+// status rather than on the consumer visible secret.
+func RecordAppliedSecretMap(bwSecret *operatorsv1.BitwardenSecret) error {
 	if bwSecret.Spec.SecretMap == nil {
-		delete(secret.ObjectMeta.Annotations, "k8s.bitwarden.com/custom-map")
-	} else {
-		bytes, err := json.MarshalIndent(bwSecret.Spec.SecretMap, "", "  ")
-		if err != nil {
-			return err
-		}
-		secret.ObjectMeta.Annotations["k8s.bitwarden.com/custom-map"] = string(bytes)
+		bwSecret.Status.AppliedSecretMap = nil
+		bwSecret.Status.AppliedSecretMapHash = ""
+		return nil
 	}
 
+	bytes, err := json.Marshal(bwSecret.Spec.SecretMap)
+	if err != nil {
+		return err
+	}
+
+	digest := NewHash()
+	digest.Write(bytes)
+
+	bwSecret.Status.AppliedSecretMap = append([]operatorsv1.SecretMap{}, bwSecret.Spec.SecretMap...)
+	bwSecret.Status.AppliedSecretMapHash = hex.EncodeToString(digest.Sum(nil))
 	return nil
 }
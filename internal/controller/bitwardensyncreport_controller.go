@@ -0,0 +1,137 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// SyncReportReconciler maintains the cluster scoped BitwardenSyncReport named operatorsv1.SyncReportName, which
+// summarizes the sync health of every BitwardenSecret so that admins have a single object to check.
+type SyncReportReconciler struct {
+	client.Client
+	RefreshIntervalSeconds int
+}
+
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardensyncreports,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardensyncreports/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardensecrets,verbs=get;list;watch
+
+func (r *SyncReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	report := &operatorsv1.BitwardenSyncReport{}
+	err := r.Get(ctx, types.NamespacedName{Name: operatorsv1.SyncReportName}, report)
+	if err != nil && errors.IsNotFound(err) {
+		report.Name = operatorsv1.SyncReportName
+		if err := r.Create(ctx, report); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	bwSecrets := &operatorsv1.BitwardenSecretList{}
+	if err := r.List(ctx, bwSecrets); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	refreshInterval := time.Duration(r.RefreshIntervalSeconds) * time.Second
+	secretReconciler := &BitwardenSecretReconciler{RefreshIntervalSeconds: r.RefreshIntervalSeconds}
+	staleAfter := func(bwSecret *operatorsv1.BitwardenSecret) time.Duration {
+		if report.Spec.StaleAfter != nil {
+			return report.Spec.StaleAfter.Duration
+		}
+		return 3 * secretReconciler.RefreshInterval(bwSecret)
+	}
+
+	status := SummarizeSyncHealth(bwSecrets.Items, staleAfter, time.Now().UTC())
+	if !equality.Semantic.DeepEqual(status, report.Status) {
+		report.Status = status
+		if err := r.Status().Update(ctx, report); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Staleness changes with time alone
+	return ctrl.Result{RequeueAfter: refreshInterval}, nil
+}
+
+// SummarizeSyncHealth counts the BitwardenSecrets that are degraded, whose last sync attempt failed, or that have not
+// synced successfully within the time staleAfter returns for it.  A BitwardenSecret is healthy when it is none of these.
+func SummarizeSyncHealth(bwSecrets []operatorsv1.BitwardenSecret, staleAfter func(*operatorsv1.BitwardenSecret) time.Duration, now time.Time) operatorsv1.BitwardenSyncReportStatus {
+	status := operatorsv1.BitwardenSyncReportStatus{Total: len(bwSecrets)}
+
+	for i := range bwSecrets {
+		bwSecret := &bwSecrets[i]
+		healthy := true
+
+		if apimeta.IsStatusConditionTrue(bwSecret.Status.Conditions, "Degraded") {
+			status.Degraded++
+			healthy = false
+		}
+
+		if LastSyncFailed(bwSecret) {
+			status.Failing++
+			healthy = false
+		}
+
+		lastSync := bwSecret.Status.LastSuccessfulSyncTime
+		if lastSync.IsZero() || now.Sub(lastSync.Time) > staleAfter(bwSecret) {
+			status.Stale++
+			healthy = false
+		}
+
+		if !lastSync.IsZero() && (status.OldestSyncTime == nil || lastSync.Before(status.OldestSyncTime)) {
+			oldest := lastSync
+			status.OldestSyncTime = &oldest
+			status.OldestSyncBitwardenSecret = fmt.Sprintf("%s/%s", bwSecret.Namespace, bwSecret.Name)
+		}
+
+		if healthy {
+			status.Healthy++
+		}
+	}
+
+	return status
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SyncReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&operatorsv1.BitwardenSyncReport{}).
+		// Every change to any BitwardenSecret, including status updates, recomputes the single report
+		Watches(&operatorsv1.BitwardenSecret{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: operatorsv1.SyncReportName}}}
+		})).
+		Complete(r)
+}
@@ -0,0 +1,164 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// Condition set while the certificate of the Bitwarden server cannot be verified
+const CertificateErrorCondition = "CertificateVerificationFailed"
+
+// The key of the CA bundle secret read when spec.caBundleSecretRef.key is not set
+const DefaultCABundleKey = "ca.crt"
+
+// CABundleClientFactory is implemented by factories that can create clients trusting a CA bundle of their own.
+type CABundleClientFactory interface {
+	WithCABundle(caBundle []byte) (BitwardenClientFactory, error)
+	// TrustedCABundle returns the CA bundle of the clients created by the factory, or nil for the operator CAs
+	TrustedCABundle() []byte
+}
+
+// TrustedCABundle returns the CA bundle of the clients created by the factory, looking through wrapping factories.
+// It returns nil for factories that trust the operator CAs only.
+func TrustedCABundle(factory BitwardenClientFactory) []byte {
+	caFactory, ok := factory.(CABundleClientFactory)
+	if !ok {
+		return nil
+	}
+
+	return caFactory.TrustedCABundle()
+}
+
+func (bc *BackendClientFactory) WithCABundle(caBundle []byte) (BitwardenClientFactory, error) {
+	if bc.CABackend == nil {
+		return nil, fmt.Errorf("the client backend of the operator does not support per-resource CA bundles; use --ca-bundle instead")
+	}
+
+	return &BackendClientFactory{
+		Backend:     bc.Backend,
+		CABackend:   bc.CABackend,
+		CABundle:    caBundle,
+		BwApiUrl:    bc.BwApiUrl,
+		IdentApiUrl: bc.IdentApiUrl,
+	}, nil
+}
+
+func (bc *BackendClientFactory) TrustedCABundle() []byte {
+	return bc.CABundle
+}
+
+func (bc *RecordingClientFactory) WithCABundle(caBundle []byte) (BitwardenClientFactory, error) {
+	factory, err := ClientFactoryWithCABundle(bc.BitwardenClientFactory, caBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecordingClientFactory{
+		BitwardenClientFactory: factory,
+		Recorder:               bc.Recorder,
+	}, nil
+}
+
+func (bc *RecordingClientFactory) TrustedCABundle() []byte {
+	return TrustedCABundle(bc.BitwardenClientFactory)
+}
+
+// WithCABundle returns a factory for the primary endpoint trusting caBundle.  The secondary endpoint is configured for
+// the operator CA bundle only, so BitwardenSecrets with their own CA bundle do not fail over.
+func (f *FailoverClientFactory) WithCABundle(caBundle []byte) (BitwardenClientFactory, error) {
+	return ClientFactoryWithCABundle(f.Primary, caBundle)
+}
+
+func (f *FailoverClientFactory) TrustedCABundle() []byte {
+	return TrustedCABundle(f.Primary)
+}
+
+// ClientFactoryWithCABundle returns a factory creating clients that trust caBundle in addition to the system roots.
+func ClientFactoryWithCABundle(factory BitwardenClientFactory, caBundle []byte) (BitwardenClientFactory, error) {
+	if _, err := bwclient.CertPool(caBundle); err != nil {
+		return nil, err
+	}
+
+	caFactory, ok := factory.(CABundleClientFactory)
+	if !ok {
+		return nil, fmt.Errorf("the client backend of the operator does not support per-resource CA bundles; use --ca-bundle instead")
+	}
+
+	return caFactory.WithCABundle(caBundle)
+}
+
+// ResolveClientFactory returns the factory creating clients for the BitwardenSecret, taking its endpoints and CA
+// bundle into account.
+func ResolveClientFactory(ctx context.Context, reader client.Reader, factory BitwardenClientFactory, bwSecret *operatorsv1.BitwardenSecret) (BitwardenClientFactory, error) {
+	factory, err := ClientFactoryFor(factory, bwSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := bwSecret.Spec.CABundleSecretRef
+	if ref == nil {
+		return factory, nil
+	}
+
+	key := ref.Key
+	if key == "" {
+		key = DefaultCABundleKey
+	}
+
+	caK8sSecret := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: bwSecret.Namespace, Name: ref.Name}, caK8sSecret); err != nil {
+		return nil, fmt.Errorf("failed to read the CA bundle secret %s: %w", ref.Name, err)
+	}
+
+	caBundle, ok := caK8sSecret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("the CA bundle secret %s has no key %s", ref.Name, key)
+	}
+
+	return ClientFactoryWithCABundle(factory, caBundle)
+}
+
+// SetCertificateErrorCondition marks the BitwardenSecret with the CertificateVerificationFailed condition when err is
+// a certificate verification error, and clears the condition otherwise.
+func SetCertificateErrorCondition(bwSecret *operatorsv1.BitwardenSecret, err error) {
+	if !bwclient.IsCertificateError(err) {
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, CertificateErrorCondition)
+		return
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "UntrustedCertificate",
+		Message: fmt.Sprintf("The certificate of the Bitwarden server could not be verified.  Supply the CA that issued it with spec.caBundleSecretRef or --ca-bundle: %s", err.Error()),
+		Type:    CertificateErrorCondition,
+	})
+}
@@ -0,0 +1,386 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// CircuitState is the state of the circuit breaker, exported as the value of the
+// bitwarden_api_circuit_breaker_state metric.
+type CircuitState int
+
+const (
+	// Calls reach Bitwarden
+	CircuitClosed CircuitState = 0
+	// Calls fail immediately until the cool-down has passed
+	CircuitOpen CircuitState = 1
+	// A single call probes whether Bitwarden is available again
+	CircuitHalfOpen CircuitState = 2
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "Open"
+	case CircuitHalfOpen:
+		return "HalfOpen"
+	}
+
+	return "Closed"
+}
+
+// Shared by every client the operator creates, so that an outage of a Bitwarden server stops the calls of all
+// BitwardenSecrets syncing from it instead of each one retrying on its own schedule.  Nil while the circuit breaker
+// is disabled.
+var apiBreakers *CircuitBreakers
+
+// SetAPICircuitBreaker makes the clients created afterwards stop calling a Bitwarden server for coolDown once
+// threshold calls in a row to it failed because it is unreachable or failing.  A threshold of zero or less disables
+// the circuit breaker.
+func SetAPICircuitBreaker(threshold int, coolDown time.Duration) {
+	if threshold <= 0 {
+		apiBreakers = nil
+		return
+	}
+
+	apiBreakers = NewCircuitBreakers(threshold, coolDown)
+}
+
+// CircuitBreakers holds one CircuitBreaker per pair of API and identity endpoints, so that a BitwardenSecret
+// overriding its endpoints with a server that is down does not pause the BitwardenSecrets syncing from healthy ones.
+type CircuitBreakers struct {
+	Threshold int
+	CoolDown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+// NewCircuitBreakers returns circuit breakers that open after threshold failed calls in a row for coolDown.
+func NewCircuitBreakers(threshold int, coolDown time.Duration) *CircuitBreakers {
+	return &CircuitBreakers{
+		Threshold: threshold,
+		CoolDown:  coolDown,
+		breakers:  map[string]*CircuitBreaker{},
+	}
+}
+
+// For returns the circuit breaker of the endpoints of the factory.  A failover factory shares the breaker of its
+// primary endpoints, whichever endpoint it currently uses.  It returns nil on nil CircuitBreakers.
+func (c *CircuitBreakers) For(factory BitwardenClientFactory) *CircuitBreaker {
+	if c == nil {
+		return nil
+	}
+
+	endpoints := stableEndpoints(factory)
+	endpoint := endpoints.GetApiUrl()
+	key := endpoint + "\x00" + endpoints.GetIdentityApiUrl()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	breaker, ok := c.breakers[key]
+	if !ok {
+		breaker = NewCircuitBreaker(endpoint, c.Threshold, c.CoolDown)
+		c.breakers[key] = breaker
+	}
+
+	return breaker
+}
+
+// CircuitOpenError is returned instead of calling Bitwarden while the circuit breaker is open.
+type CircuitOpenError struct {
+	// The API endpoint whose calls are paused
+	Endpoint string
+	// How long the circuit stays open, or zero while another call probes the API
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.RetryAfter <= 0 {
+		return fmt.Sprintf("calls to the Bitwarden API at %s are paused by the circuit breaker while it checks whether the API is back", e.Endpoint)
+	}
+
+	return fmt.Sprintf("calls to the Bitwarden API at %s are paused by the circuit breaker for another %s", e.Endpoint, (e.RetryAfter + time.Second - 1).Truncate(time.Second))
+}
+
+// IsCircuitOpenError reports whether the error was returned by the circuit breaker instead of calling Bitwarden.
+func IsCircuitOpenError(err error) bool {
+	var circuitErr *CircuitOpenError
+	return errors.As(err, &circuitErr)
+}
+
+// CircuitBreaker opens after Threshold calls in a row failed because Bitwarden was unreachable, failing, or
+// throttling, and fails every call with a CircuitOpenError for CoolDown.  After the cool-down a single call probes
+// the API: the circuit closes if it gets an answer and opens for another cool-down if it fails the same way.
+type CircuitBreaker struct {
+	// The API endpoint whose calls go through the circuit breaker, used in logs and metrics
+	Endpoint  string
+	Threshold int
+	CoolDown  time.Duration
+
+	mu             sync.Mutex
+	state          CircuitState
+	failures       int
+	openedAt       time.Time
+	probeStartedAt time.Time
+}
+
+// NewCircuitBreaker returns a closed circuit breaker for the calls to endpoint.
+func NewCircuitBreaker(endpoint string, threshold int, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Endpoint:  endpoint,
+		Threshold: threshold,
+		CoolDown:  coolDown,
+	}
+}
+
+// State returns the current state of the circuit breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// call runs fn unless the circuit is open, and records its outcome.
+func (b *CircuitBreaker) call(fn func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+	b.record(err)
+	return err
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if remaining := b.CoolDown - time.Since(b.openedAt); remaining > 0 {
+			return &CircuitOpenError{Endpoint: b.Endpoint, RetryAfter: remaining}
+		}
+	case CircuitHalfOpen:
+		// A probe that never returns must not keep the circuit from closing
+		if time.Since(b.probeStartedAt) < b.CoolDown {
+			return &CircuitOpenError{Endpoint: b.Endpoint}
+		}
+	default:
+		return nil
+	}
+
+	b.probeStartedAt = time.Now()
+	b.setState(CircuitHalfOpen)
+	return nil
+}
+
+// record counts the calls in a row that failed because of an outage.  Any answer of the API, even an error such as a
+// rejected access token, shows that it is available.  Panics say nothing about the API and are not counted.
+func (b *CircuitBreaker) record(err error) {
+	if bwclient.IsPanic(err) {
+		return
+	}
+
+	outage := !bwclient.IsCertificateError(err) && (bwclient.IsUnreachableError(err) || bwclient.IsServerError(err))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !outage {
+		b.failures = 0
+		b.setState(CircuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.Threshold) {
+		b.openedAt = time.Now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// setState moves the circuit breaker to state, logging and exporting the change.  The caller must hold b.mu.
+func (b *CircuitBreaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+
+	logger := ctrl.Log.WithName("circuit-breaker").WithValues("endpoint", b.Endpoint)
+	switch state {
+	case CircuitOpen:
+		if b.state == CircuitClosed {
+			circuitBreakerTripsTotal.WithLabelValues(b.Endpoint).Inc()
+		}
+		logger.Info("Bitwarden API calls keep failing, pausing them", "failures", b.failures, "coolDown", b.CoolDown.String())
+	case CircuitHalfOpen:
+		logger.Info("Probing whether the Bitwarden API is available again")
+	case CircuitClosed:
+		logger.Info("Bitwarden API is available again, resuming calls")
+	}
+
+	b.state = state
+	circuitBreakerState.WithLabelValues(b.Endpoint).Set(float64(state))
+}
+
+// circuitBreakerClient runs every call that reaches Bitwarden through the circuit breaker.
+type circuitBreakerClient struct {
+	bwclient.BitwardenClientInterface
+	breaker *CircuitBreaker
+}
+
+func newCircuitBreakerClient(inner bwclient.BitwardenClientInterface, breaker *CircuitBreaker) bwclient.BitwardenClientInterface {
+	if breaker == nil {
+		return inner
+	}
+
+	return &circuitBreakerClient{BitwardenClientInterface: inner, breaker: breaker}
+}
+
+func (c *circuitBreakerClient) AccessTokenLogin(accessToken string, stateFile *string) error {
+	return c.breaker.call(func() error {
+		return c.BitwardenClientInterface.AccessTokenLogin(accessToken, stateFile)
+	})
+}
+
+func (c *circuitBreakerClient) Secrets() bwclient.SecretsInterface {
+	return &circuitBreakerSecrets{SecretsInterface: c.BitwardenClientInterface.Secrets(), breaker: c.breaker}
+}
+
+func (c *circuitBreakerClient) Projects() bwclient.ProjectsInterface {
+	return &circuitBreakerProjects{ProjectsInterface: c.BitwardenClientInterface.Projects(), breaker: c.breaker}
+}
+
+type circuitBreakerSecrets struct {
+	bwclient.SecretsInterface
+	breaker *CircuitBreaker
+}
+
+func (s *circuitBreakerSecrets) Create(key, value, note string, organizationID string, projectIDs []string) (res *bwclient.SecretResponse, err error) {
+	err = s.breaker.call(func() error {
+		res, err = s.SecretsInterface.Create(key, value, note, organizationID, projectIDs)
+		return err
+	})
+	return res, err
+}
+
+func (s *circuitBreakerSecrets) List(organizationID string) (res *bwclient.SecretIdentifiersResponse, err error) {
+	err = s.breaker.call(func() error {
+		res, err = s.SecretsInterface.List(organizationID)
+		return err
+	})
+	return res, err
+}
+
+func (s *circuitBreakerSecrets) Get(secretID string) (res *bwclient.SecretResponse, err error) {
+	err = s.breaker.call(func() error {
+		res, err = s.SecretsInterface.Get(secretID)
+		return err
+	})
+	return res, err
+}
+
+func (s *circuitBreakerSecrets) GetByIDS(secretIDs []string) (res *bwclient.SecretsResponse, err error) {
+	err = s.breaker.call(func() error {
+		res, err = s.SecretsInterface.GetByIDS(secretIDs)
+		return err
+	})
+	return res, err
+}
+
+func (s *circuitBreakerSecrets) Update(secretID string, key, value, note string, organizationID string, projectIDs []string) (res *bwclient.SecretResponse, err error) {
+	err = s.breaker.call(func() error {
+		res, err = s.SecretsInterface.Update(secretID, key, value, note, organizationID, projectIDs)
+		return err
+	})
+	return res, err
+}
+
+func (s *circuitBreakerSecrets) Delete(secretIDs []string) (res *bwclient.SecretsDeleteResponse, err error) {
+	err = s.breaker.call(func() error {
+		res, err = s.SecretsInterface.Delete(secretIDs)
+		return err
+	})
+	return res, err
+}
+
+func (s *circuitBreakerSecrets) Sync(organizationID string, lastSyncedDate *time.Time) (res *bwclient.SecretsSyncResponse, err error) {
+	err = s.breaker.call(func() error {
+		res, err = s.SecretsInterface.Sync(organizationID, lastSyncedDate)
+		return err
+	})
+	return res, err
+}
+
+type circuitBreakerProjects struct {
+	bwclient.ProjectsInterface
+	breaker *CircuitBreaker
+}
+
+func (p *circuitBreakerProjects) Create(organizationID string, name string) (res *bwclient.ProjectResponse, err error) {
+	err = p.breaker.call(func() error {
+		res, err = p.ProjectsInterface.Create(organizationID, name)
+		return err
+	})
+	return res, err
+}
+
+func (p *circuitBreakerProjects) List(organizationID string) (res *bwclient.ProjectsResponse, err error) {
+	err = p.breaker.call(func() error {
+		res, err = p.ProjectsInterface.List(organizationID)
+		return err
+	})
+	return res, err
+}
+
+func (p *circuitBreakerProjects) Get(projectID string) (res *bwclient.ProjectResponse, err error) {
+	err = p.breaker.call(func() error {
+		res, err = p.ProjectsInterface.Get(projectID)
+		return err
+	})
+	return res, err
+}
+
+func (p *circuitBreakerProjects) Update(projectID string, organizationID string, name string) (res *bwclient.ProjectResponse, err error) {
+	err = p.breaker.call(func() error {
+		res, err = p.ProjectsInterface.Update(projectID, organizationID, name)
+		return err
+	})
+	return res, err
+}
+
+func (p *circuitBreakerProjects) Delete(projectIDs []string) (res *bwclient.ProjectsDeleteResponse, err error) {
+	err = p.breaker.call(func() error {
+		res, err = p.ProjectsInterface.Delete(projectIDs)
+		return err
+	})
+	return res, err
+}
@@ -0,0 +1,299 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Label identifying the secrets written by a ClusterBitwardenSecret, set to its UID
+const ClusterBitwardenSecretLabel = "k8s.bitwarden.com/cluster-bw-secret"
+
+// ClusterBitwardenSecretReconciler writes the same Kubernetes secret to every namespace selected by a
+// ClusterBitwardenSecret, so that shared credentials such as registry credentials do not need a BitwardenSecret per
+// namespace.  Secrets Manager is read once per sync for all namespaces.
+type ClusterBitwardenSecretReconciler struct {
+	client.Client
+	Scheme                 *runtime.Scheme
+	BitwardenClientFactory BitwardenClientFactory
+	StatePath              string
+	RefreshIntervalSeconds int
+	ClientCache            *BitwardenClientCache
+	// How long each Secrets Manager call may take.  Zero waits indefinitely.
+	SDKTimeout time.Duration
+	// Number of ClusterBitwardenSecrets reconciled in parallel.  Defaults to 1.
+	MaxConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=clusterbitwardensecrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=clusterbitwardensecrets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=clusterbitwardensecrets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+
+func (r *ClusterBitwardenSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	refreshInterval := time.Duration(r.RefreshIntervalSeconds) * time.Second
+
+	clusterSecret := &operatorsv1.ClusterBitwardenSecret{}
+	err := r.Get(ctx, req.NamespacedName, clusterSecret)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Info(fmt.Sprintf("%s was deleted.", req.Name))
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		logger.Error(err, "Error looking up ClusterBitwardenSecret")
+		return ctrl.Result{RequeueAfter: refreshInterval}, err
+	}
+
+	namespaces, err := r.SelectedNamespaces(ctx, clusterSecret)
+	if err != nil {
+		r.logClusterError(ctx, clusterSecret, err, "Error listing the selected namespaces")
+		return ctrl.Result{RequeueAfter: refreshInterval}, nil
+	}
+
+	authK8sSecret := &corev1.Secret{}
+	authToken := clusterSecret.Spec.AuthToken
+	err = r.Get(ctx, types.NamespacedName{Namespace: authToken.Namespace, Name: authToken.SecretName}, authK8sSecret)
+	if err != nil {
+		r.logClusterError(ctx, clusterSecret, err, "Error pulling authorization token secret")
+		return ctrl.Result{RequeueAfter: refreshInterval}, nil
+	}
+	redactor := NewRedactor(string(authK8sSecret.Data[authToken.SecretKey]))
+	ctx = withRedactor(ctx, redactor)
+
+	// Every sync is a full sync, since a newly selected namespace needs all of the secrets
+	pullReconciler := &BitwardenSecretReconciler{
+		BitwardenClientFactory: r.BitwardenClientFactory,
+		StatePath:              r.StatePath,
+		ClientCache:            r.ClientCache,
+	}
+	_, secrets, report, err := pullReconciler.PullSecretManagerSecretDeltas(ctx, logger, clusterSecret.Spec.OrganizationId, string(authK8sSecret.Data[authToken.SecretKey]), time.Time{}, PullSelection{Projects: clusterSecret.Spec.Projects, MappedIDs: MappedSecretIDs(clusterSecret.Spec.SecretMap), NoteIDs: NoteSecretIDs(clusterSecret.Spec.SecretMap), Timeout: r.SDKTimeout})
+	if err != nil {
+		r.logClusterError(ctx, clusterSecret, err, "Error pulling Secret Manager secrets from API")
+		return ctrl.Result{RequeueAfter: refreshInterval}, nil
+	}
+	redactor.AddSecrets(secrets)
+	defer zeroSecrets(secrets)
+
+	SetOrganizationMismatchCondition(&clusterSecret.Status.Conditions, clusterSecret.Spec.OrganizationId, report.ForeignSecrets)
+
+	rendered, err := RenderK8sSecret(ClusterSecretTemplate(clusterSecret), secrets)
+	if err != nil {
+		r.logClusterError(ctx, clusterSecret, err, "Error rendering the secret")
+		return ctrl.Result{RequeueAfter: refreshInterval}, nil
+	}
+
+	synced := []string{}
+	conflicting := []string{}
+	for _, namespace := range namespaces {
+		written, err := r.WriteClusterSecret(ctx, clusterSecret, namespace, rendered.Data)
+		if err != nil {
+			r.logClusterError(ctx, clusterSecret, err, fmt.Sprintf("Failed to write %s/%s", namespace, clusterSecret.Spec.SecretName))
+			return ctrl.Result{RequeueAfter: refreshInterval}, nil
+		}
+
+		if written {
+			synced = append(synced, namespace)
+		} else {
+			conflicting = append(conflicting, namespace)
+		}
+	}
+
+	if err := r.PruneClusterSecrets(ctx, clusterSecret, namespaces); err != nil {
+		r.logClusterError(ctx, clusterSecret, err, "Failed to remove the secret from namespaces that are no longer selected")
+		return ctrl.Result{RequeueAfter: refreshInterval}, nil
+	}
+
+	clusterSecret.Status.Namespaces = synced
+	clusterSecret.Status.ConflictingNamespaces = conflicting
+	clusterSecret.Status.LastSuccessfulSyncTime = metav1.Time{Time: time.Now().UTC()}
+	apimeta.RemoveStatusCondition(&clusterSecret.Status.Conditions, "FailedSync")
+	apimeta.SetStatusCondition(&clusterSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "ReconciliationComplete",
+		Message: fmt.Sprintf("Synced %s to %d namespaces", clusterSecret.Spec.SecretName, len(synced)),
+		Type:    "SuccessfulSync",
+	})
+	clusterSecret.Status.ObservedGeneration = clusterSecret.Generation
+	SetReadyCondition(&clusterSecret.Status.Conditions, clusterSecret.Generation, fmt.Sprintf("Synced %s to %d namespaces", clusterSecret.Spec.SecretName, len(synced)))
+	if len(conflicting) > 0 {
+		logger.Info(fmt.Sprintf("%s is not managed by %s in the namespaces %v and was left untouched", clusterSecret.Spec.SecretName, clusterSecret.Name, conflicting))
+	}
+	if err := r.Status().Update(ctx, clusterSecret); err != nil {
+		return ctrl.Result{RequeueAfter: refreshInterval}, err
+	}
+
+	return ctrl.Result{RequeueAfter: refreshInterval}, nil
+}
+
+// ClusterSecretTemplate returns the BitwardenSecret rendering the secret written to every selected namespace.
+func ClusterSecretTemplate(clusterSecret *operatorsv1.ClusterBitwardenSecret) *operatorsv1.BitwardenSecret {
+	return &operatorsv1.BitwardenSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterSecret.Name, UID: clusterSecret.UID},
+		Spec: operatorsv1.BitwardenSecretSpec{
+			OrganizationId: clusterSecret.Spec.OrganizationId,
+			SecretName:     clusterSecret.Spec.SecretName,
+			SecretMap:      clusterSecret.Spec.SecretMap,
+			Projects:       clusterSecret.Spec.Projects,
+		},
+	}
+}
+
+// SelectedNamespaces returns the sorted names of the active namespaces matching the namespace selector.
+func (r *ClusterBitwardenSecretReconciler) SelectedNamespaces(ctx context.Context, clusterSecret *operatorsv1.ClusterBitwardenSecret) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&clusterSecret.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceList := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	namespaces := []string{}
+	for _, namespace := range namespaceList.Items {
+		if namespace.Status.Phase != corev1.NamespaceTerminating {
+			namespaces = append(namespaces, namespace.Name)
+		}
+	}
+	sort.Strings(namespaces)
+
+	return namespaces, nil
+}
+
+// WriteClusterSecret creates or updates the secret of the ClusterBitwardenSecret in the namespace.  A secret of the
+// same name that the ClusterBitwardenSecret does not manage is left untouched and false is returned.
+func (r *ClusterBitwardenSecretReconciler) WriteClusterSecret(ctx context.Context, clusterSecret *operatorsv1.ClusterBitwardenSecret, namespace string, data map[string][]byte) (bool, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: clusterSecret.Spec.SecretName}, secret)
+	if err != nil && errors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      clusterSecret.Spec.SecretName,
+				Namespace: namespace,
+				Labels:    map[string]string{ClusterBitwardenSecretLabel: string(clusterSecret.UID)},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		SetDataChecksumAnnotation(secret)
+
+		// Cascading delete
+		if err := ctrl.SetControllerReference(clusterSecret, secret, r.Scheme); err != nil {
+			return false, err
+		}
+
+		return true, r.Create(ctx, secret)
+	} else if err != nil {
+		return false, err
+	}
+
+	if secret.Labels[ClusterBitwardenSecretLabel] != string(clusterSecret.UID) {
+		return false, nil
+	}
+
+	secret.Data = data
+	SetDataChecksumAnnotation(secret)
+	return true, r.Update(ctx, secret)
+}
+
+// PruneClusterSecrets deletes the secrets of the ClusterBitwardenSecret from namespaces that are no longer selected.
+func (r *ClusterBitwardenSecretReconciler) PruneClusterSecrets(ctx context.Context, clusterSecret *operatorsv1.ClusterBitwardenSecret, namespaces []string) error {
+	selected := map[string]bool{}
+	for _, namespace := range namespaces {
+		selected[namespace] = true
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.MatchingLabels{ClusterBitwardenSecretLabel: string(clusterSecret.UID)}); err != nil {
+		return err
+	}
+
+	for i := range secrets.Items {
+		if !selected[secrets.Items[i].Namespace] {
+			if err := r.Delete(ctx, &secrets.Items[i]); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *ClusterBitwardenSecretReconciler) logClusterError(ctx context.Context, clusterSecret *operatorsv1.ClusterBitwardenSecret, err error, message string) {
+	redactor := redactorFrom(ctx)
+	err = redactor.RedactError(err)
+	message = redactor.Redact(message)
+
+	log.FromContext(ctx).Error(err, message)
+
+	apimeta.SetStatusCondition(&clusterSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionFalse,
+		Reason:  "ReconciliationFailed",
+		Message: fmt.Sprintf("%s - %s", message, err.Error()),
+		Type:    "FailedSync",
+	})
+	SetStalledCondition(&clusterSecret.Status.Conditions, clusterSecret.Generation, "ReconciliationFailed", fmt.Sprintf("%s - %s", message, err.Error()))
+	r.Status().Update(ctx, clusterSecret)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterBitwardenSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// Status updates must not trigger another sync
+		For(&operatorsv1.ClusterBitwardenSecret{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// New namespaces and label changes fan the secret out without waiting for the next refresh
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+			clusterSecrets := &operatorsv1.ClusterBitwardenSecretList{}
+			if err := r.List(ctx, clusterSecrets); err != nil {
+				return nil
+			}
+
+			requests := []reconcile.Request{}
+			for _, clusterSecret := range clusterSecrets.Items {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: clusterSecret.Name}})
+			}
+			return requests
+		}), builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		WithOptions(crcontroller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
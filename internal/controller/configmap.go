@@ -0,0 +1,126 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// IsSensitive reports whether a map entry belongs in the Kubernetes secret.  Entries are sensitive unless classified
+// otherwise.
+func IsSensitive(m operatorsv1.SecretMap) bool {
+	return m.Sensitive == nil || *m.Sensitive
+}
+
+// ConfigMapName returns the name of the ConfigMap holding the BitwardenSecret's non-sensitive values.
+func ConfigMapName(bwSecret *operatorsv1.BitwardenSecret) string {
+	if bwSecret.Spec.ConfigMapName != "" {
+		return bwSecret.Spec.ConfigMapName
+	}
+
+	return bwSecret.Spec.SecretName
+}
+
+// SplitConfigMap moves the values of map entries classified as non-sensitive out of the mapped secret and into a
+// ConfigMap.  It returns nil when every entry is sensitive.
+func SplitConfigMap(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret) *corev1.ConfigMap {
+	data := map[string]string{}
+	for _, m := range bwSecret.Spec.SecretMap {
+		if IsSensitive(m) {
+			continue
+		}
+
+		for _, key := range MappedKeys(m) {
+			key = TransformKey(bwSecret, key)
+			if v, ok := secret.Data[key]; ok {
+				data[key] = string(v)
+				delete(secret.Data, key)
+			}
+		}
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConfigMapName(bwSecret),
+			Namespace: bwSecret.Namespace,
+			Labels:    map[string]string{},
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		Data: data,
+	}
+	configMap.Labels["k8s.bitwarden.com/bw-secret"] = string(bwSecret.UID)
+	return configMap
+}
+
+// WriteConfigMap creates or updates the ConfigMap holding the non-sensitive values.  A nil configMap deletes the
+// ConfigMap previously written for the BitwardenSecret, if any.  ConfigMaps not owned by the BitwardenSecret are
+// never modified.
+func (r *BitwardenSecretReconciler) WriteConfigMap(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, configMap *corev1.ConfigMap) error {
+	existing := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: bwSecret.Namespace, Name: ConfigMapName(bwSecret)}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	if found && !metav1.IsControlledBy(existing, bwSecret) {
+		if configMap == nil {
+			return nil
+		}
+		return fmt.Errorf("ConfigMap %s/%s already exists and is not managed by this BitwardenSecret", existing.Namespace, existing.Name)
+	}
+
+	if configMap == nil {
+		if found {
+			if err := r.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := ctrl.SetControllerReference(bwSecret, configMap, r.Scheme); err != nil {
+		return err
+	}
+
+	if !found {
+		return r.Create(ctx, configMap)
+	}
+
+	configMap.ResourceVersion = existing.ResourceVersion
+	return r.Update(ctx, configMap)
+}
@@ -0,0 +1,117 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotation of written secrets holding the time of the last write
+const SyncTimeAnnotation = "k8s.bitwarden.com/sync-time"
+
+// Annotation of written secrets holding the DataChecksum of their data
+const DataChecksumAnnotation = "k8s.bitwarden.com/data-sha256"
+
+// secretContent is the part of a secret the hash covers.  Maps are marshalled with sorted keys.
+type secretContent struct {
+	Type            corev1.SecretType       `json:"type"`
+	Immutable       *bool                   `json:"immutable"`
+	Data            map[string][]byte       `json:"data"`
+	Labels          map[string]string       `json:"labels"`
+	Annotations     map[string]string       `json:"annotations"`
+	OwnerReferences []metav1.OwnerReference `json:"ownerReferences"`
+}
+
+// SecretContentHash returns a hash of everything the operator writes to a secret except the sync time and the
+// revisions, so that a secret whose hash did not change can be left alone instead of being updated with the same
+// values, which would bump its resourceVersion and restart the workloads of reloaders watching it.  A new revision with
+// the same value is recorded with the next write.
+func SecretContentHash(secret *corev1.Secret) string {
+	annotations := map[string]string{}
+	for key, value := range secret.Annotations {
+		if key != SyncTimeAnnotation && key != RevisionsAnnotation {
+			annotations[key] = value
+		}
+	}
+
+	content := secretContent{
+		Type:            secret.Type,
+		Annotations:     annotations,
+		Data:            map[string][]byte{},
+		Labels:          map[string]string{},
+		OwnerReferences: secret.OwnerReferences,
+	}
+	if secret.Immutable != nil && *secret.Immutable {
+		content.Immutable = secret.Immutable
+	}
+	for key, value := range secret.Data {
+		content.Data[key] = value
+	}
+	for key, value := range secret.Labels {
+		content.Labels[key] = value
+	}
+	if len(content.OwnerReferences) == 0 {
+		content.OwnerReferences = nil
+	}
+
+	// Marshalling maps of strings and byte slices cannot fail
+	bytes, _ := json.Marshal(content)
+
+	digest := NewHash()
+	digest.Write(bytes)
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// DataChecksum returns the hex encoded SHA-256 of a line "<key>=<base64 value>" for every key of the data in sorted
+// order, each followed by a newline.  This is what
+//
+//	kubectl get secret <name> -o json | jq -r '.data | to_entries | sort_by(.key) | .[] | "\(.key)=\(.value)"' | sha256sum
+//
+// prints, so external tools can tell whether the content of a secret changed without reading its values.
+func DataChecksum(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	digest := NewHash()
+	for _, key := range keys {
+		digest.Write([]byte(key + "=" + base64.StdEncoding.EncodeToString(data[key]) + "\n"))
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// SetDataChecksumAnnotation annotates the secret with the checksum of its data.
+func SetDataChecksumAnnotation(secret *corev1.Secret) {
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+
+	secret.Annotations[DataChecksumAnnotation] = DataChecksum(secret.Data)
+}
@@ -0,0 +1,86 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Annotation of secrets written with the Merge creation policy, holding the comma separated keys the operator wrote
+const ManagedKeysAnnotation = "k8s.bitwarden.com/managed-keys"
+
+// MergesIntoK8sSecret reports whether the BitwardenSecret merges its keys into the Kubernetes secret instead of
+// owning it.
+func MergesIntoK8sSecret(bwSecret *operatorsv1.BitwardenSecret) bool {
+	return bwSecret.Spec.CreationPolicy == operatorsv1.CreationPolicyMerge
+}
+
+// ValidateCreationPolicy checks that the creation policy can be combined with the other settings of the
+// BitwardenSecret.
+func ValidateCreationPolicy(bwSecret *operatorsv1.BitwardenSecret) error {
+	if MergesIntoK8sSecret(bwSecret) && bwSecret.Spec.Versioning != nil {
+		return fmt.Errorf("creationPolicy %s cannot be combined with versioning", operatorsv1.CreationPolicyMerge)
+	}
+
+	return nil
+}
+
+// ManagedKeys returns the keys recorded as written by the operator in the managed keys annotation of the secret.
+func ManagedKeys(secret *corev1.Secret) []string {
+	value := secret.Annotations[ManagedKeysAnnotation]
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
+}
+
+// MergeManagedKeys merges the data rendered into the secret with its existing data.  Keys written by the operator
+// before but no longer rendered are removed, and keys written by anyone else are kept.  The rendered keys are
+// recorded in the managed keys annotation.
+func MergeManagedKeys(secret *corev1.Secret, existing map[string][]byte) {
+	merged := make(map[string][]byte, len(existing)+len(secret.Data))
+	for key, value := range existing {
+		merged[key] = value
+	}
+	for _, key := range ManagedKeys(secret) {
+		delete(merged, key)
+	}
+
+	managed := make([]string, 0, len(secret.Data))
+	for key, value := range secret.Data {
+		merged[key] = value
+		managed = append(managed, key)
+	}
+	sort.Strings(managed)
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[ManagedKeysAnnotation] = strings.Join(managed, ",")
+	secret.Data = merged
+}
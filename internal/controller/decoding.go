@@ -0,0 +1,48 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// DecodeValue decodes a secret value with the decoding strategy of its map entry, for binary content such as
+// keystores that is stored base64 encoded in Secrets Manager.
+func DecodeValue(value []byte, strategy operatorsv1.DecodingStrategy) ([]byte, error) {
+	switch strategy {
+	case "", operatorsv1.DecodingStrategyNone:
+		return value, nil
+	case operatorsv1.DecodingStrategyBase64:
+		// Line breaks are common in pasted base64 and padding is optional
+		encoded := strings.Join(strings.Fields(string(value)), "")
+		decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+		if err != nil {
+			return nil, fmt.Errorf("the value is not valid base64: %w", err)
+		}
+		return decoded, nil
+	}
+
+	return nil, fmt.Errorf("unknown decoding strategy %q", strategy)
+}
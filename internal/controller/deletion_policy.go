@@ -0,0 +1,174 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Finalizer of BitwardenSecrets with the Retain deletion policy, which releases the written objects from garbage
+// collection before the BitwardenSecret is removed
+const RetainFinalizer = "k8s.bitwarden.com/retain-secrets"
+
+// HandleDeletionPolicy keeps the finalizers of the BitwardenSecret in line with its deletion policy and its remote
+// targets and, once the BitwardenSecret is being deleted, deletes its remote targets or releases the objects it wrote,
+// and removes the finalizers.  It reports whether the BitwardenSecret is being deleted, in which case it must not be
+// synced.
+func (r *BitwardenSecretReconciler) HandleDeletionPolicy(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret) (bool, error) {
+	retain := bwSecret.Spec.DeletionPolicy == operatorsv1.DeletionPolicyRetain
+
+	if !bwSecret.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(bwSecret, RemoteTargetsFinalizer) {
+			if !retain {
+				if err := r.DeleteRemoteTargets(ctx, bwSecret); err != nil {
+					return true, err
+				}
+			}
+
+			controllerutil.RemoveFinalizer(bwSecret, RemoteTargetsFinalizer)
+			if err := r.Update(ctx, bwSecret); err != nil {
+				return true, err
+			}
+		}
+
+		if !controllerutil.ContainsFinalizer(bwSecret, RetainFinalizer) {
+			return true, nil
+		}
+
+		if err := r.ReleaseWrittenObjects(ctx, bwSecret); err != nil {
+			return true, err
+		}
+
+		controllerutil.RemoveFinalizer(bwSecret, RetainFinalizer)
+		return true, r.Update(ctx, bwSecret)
+	}
+
+	changed := setFinalizer(bwSecret, RetainFinalizer, retain)
+	if setFinalizer(bwSecret, RemoteTargetsFinalizer, !retain && HasRemoteTargets(bwSecret)) {
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+
+	return false, r.Update(ctx, bwSecret)
+}
+
+// setFinalizer adds or removes the finalizer and reports whether the BitwardenSecret changed.
+func setFinalizer(bwSecret *operatorsv1.BitwardenSecret, finalizer string, wanted bool) bool {
+	if wanted {
+		return controllerutil.AddFinalizer(bwSecret, finalizer)
+	}
+
+	return controllerutil.RemoveFinalizer(bwSecret, finalizer)
+}
+
+// ReleaseWrittenObjects removes the owner reference to the BitwardenSecret from every secret and ConfigMap it wrote,
+// so that they outlive it.
+func (r *BitwardenSecretReconciler) ReleaseWrittenObjects(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret) error {
+	selector := []client.ListOption{
+		client.InNamespace(bwSecret.Namespace),
+		client.MatchingLabels{"k8s.bitwarden.com/bw-secret": string(bwSecret.UID)},
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, selector...); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		if err := r.releaseObject(ctx, bwSecret, &secrets.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.List(ctx, configMaps, selector...); err != nil {
+		return err
+	}
+	for i := range configMaps.Items {
+		if err := r.releaseObject(ctx, bwSecret, &configMaps.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *BitwardenSecretReconciler) releaseObject(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, obj client.Object) error {
+	if !RemoveOwnerReference(bwSecret, obj) {
+		return nil
+	}
+
+	return r.Update(ctx, obj)
+}
+
+// RemoveOwnerReference removes the owner reference to the BitwardenSecret from the object and reports whether it had
+// one.
+func RemoveOwnerReference(bwSecret *operatorsv1.BitwardenSecret, obj client.Object) bool {
+	owners := obj.GetOwnerReferences()
+	kept := make([]metav1.OwnerReference, 0, len(owners))
+	for _, owner := range owners {
+		if owner.UID != bwSecret.UID {
+			kept = append(kept, owner)
+		}
+	}
+
+	if len(kept) == len(owners) {
+		return false
+	}
+
+	obj.SetOwnerReferences(kept)
+	return true
+}
+
+// OwnsK8sSecret reports whether the Kubernetes secret gets an owner reference to the BitwardenSecret, so that it is
+// garbage collected along with it.  Secrets merged into and standalone secrets are not owned.
+func OwnsK8sSecret(bwSecret *operatorsv1.BitwardenSecret) bool {
+	if MergesIntoK8sSecret(bwSecret) {
+		return false
+	}
+
+	return bwSecret.Spec.OwnerReference == nil || *bwSecret.Spec.OwnerReference
+}
+
+// OwnsTargets reports whether the secrets and ConfigMaps of spec.targets get an owner reference to the
+// BitwardenSecret.  Targets are never merged into, so only spec.ownerReference turns it off.
+func OwnsTargets(bwSecret *operatorsv1.BitwardenSecret) bool {
+	return bwSecret.Spec.OwnerReference == nil || *bwSecret.Spec.OwnerReference
+}
+
+// ValidateOwnerReference checks that standalone secrets are retained, since deleting them with the BitwardenSecret
+// would need the owner reference they do not have.
+func ValidateOwnerReference(bwSecret *operatorsv1.BitwardenSecret) error {
+	if bwSecret.Spec.OwnerReference != nil && !*bwSecret.Spec.OwnerReference && bwSecret.Spec.DeletionPolicy != operatorsv1.DeletionPolicyRetain {
+		return fmt.Errorf("ownerReference false requires deletionPolicy %s", operatorsv1.DeletionPolicyRetain)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// dockerConfigJSON is the structure of the .dockerconfigjson key of an image pull secret
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email,omitempty"`
+	Auth     string `json:"auth"`
+}
+
+// ApplyDockerConfig writes the .dockerconfigjson assembled from the BitwardenSecret's docker config template to the
+// secret.  Without a map only the .dockerconfigjson is written, rather than every secret the machine account can
+// access.  It does nothing when no template is set.
+func ApplyDockerConfig(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte, secret *corev1.Secret) error {
+	template := bwSecret.Spec.DockerConfig
+	if template == nil {
+		return nil
+	}
+
+	dockerConfig, err := RenderDockerConfig(template, secrets)
+	if err != nil {
+		return err
+	}
+
+	if bwSecret.Spec.SecretMap == nil || secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[corev1.DockerConfigJsonKey] = dockerConfig
+
+	return nil
+}
+
+// RenderDockerConfig assembles a .dockerconfigjson holding the credentials of a single registry.
+func RenderDockerConfig(template *operatorsv1.DockerConfigTemplate, secrets map[string][]byte) ([]byte, error) {
+	registry := template.Registry
+	if template.RegistrySecretId != "" {
+		value, err := dockerConfigValue(secrets, template.RegistrySecretId, "registry")
+		if err != nil {
+			return nil, err
+		}
+		registry = value
+	}
+
+	if registry == "" {
+		return nil, fmt.Errorf("the docker config registry is not set")
+	}
+
+	username, err := dockerConfigValue(secrets, template.UsernameSecretId, "username")
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := dockerConfigValue(secrets, template.PasswordSecretId, "password")
+	if err != nil {
+		return nil, err
+	}
+
+	entry := dockerConfigEntry{
+		Username: username,
+		Password: password,
+		Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+
+	if template.EmailSecretId != "" {
+		entry.Email, err = dockerConfigValue(secrets, template.EmailSecretId, "email")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dockerConfig, err := json.Marshal(dockerConfigJSON{Auths: map[string]dockerConfigEntry{registry: entry}})
+	if err != nil {
+		return nil, err
+	}
+
+	return dockerConfig, ValidateDockerConfig(dockerConfig)
+}
+
+// ValidateDockerConfig checks that the value is a .dockerconfigjson with at least one registry, each with credentials.
+func ValidateDockerConfig(value []byte) error {
+	if len(value) == 0 {
+		return fmt.Errorf("a secret of type %s requires the %s key", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey)
+	}
+
+	dockerConfig := dockerConfigJSON{}
+	if err := json.Unmarshal(value, &dockerConfig); err != nil {
+		return fmt.Errorf("the %s key is not valid JSON: %w", corev1.DockerConfigJsonKey, err)
+	}
+
+	if len(dockerConfig.Auths) == 0 {
+		return fmt.Errorf("the %s key holds no registry credentials", corev1.DockerConfigJsonKey)
+	}
+
+	for registry, entry := range dockerConfig.Auths {
+		if strings.TrimSpace(registry) == "" || strings.ContainsAny(registry, " \t\r\n") {
+			return fmt.Errorf("the %s key holds an invalid registry %q", corev1.DockerConfigJsonKey, registry)
+		}
+
+		if entry.Auth == "" && (entry.Username == "" || entry.Password == "") {
+			return fmt.Errorf("the %s key holds no credentials for %s", corev1.DockerConfigJsonKey, registry)
+		}
+	}
+
+	return nil
+}
+
+// dockerConfigValue returns a pulled value with surrounding whitespace, such as a trailing newline, removed.
+func dockerConfigValue(secrets map[string][]byte, id string, field string) (string, error) {
+	if id == "" {
+		return "", fmt.Errorf("the docker config %s secret ID is not set", field)
+	}
+
+	value, ok := secrets[id]
+	if !ok {
+		return "", fmt.Errorf("the docker config %s secret %s is not accessible by the machine account", field, id)
+	}
+
+	return strings.TrimSpace(string(value)), nil
+}
@@ -0,0 +1,113 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Condition set while spec.dryRun is set, summarizing the changes the last dry run would have made
+const DryRunCondition = "DryRun"
+
+// PreviewSync renders the Kubernetes secret a sync would write from the pulled secrets and returns the keys it would
+// change in the existing secret, which is nil if the secret does not exist yet.  Nothing is written to the cluster.
+func (r *BitwardenSecretReconciler) PreviewSync(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte, report PullReport, existing *corev1.Secret) (*operatorsv1.DryRunSummary, error) {
+	var previous map[string][]byte
+	var previousRevisions map[string]SecretRevision
+	if existing != nil {
+		previous = existing.Data
+		previousRevisions = ParseRevisionsAnnotation(existing)
+	}
+
+	// Versioned secrets only hold the name of the active version, whose data is compared instead
+	if bwSecret.Spec.Versioning != nil && bwSecret.Status.CurrentVersion != "" {
+		version := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: bwSecret.Namespace, Name: bwSecret.Status.CurrentVersion}, version)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		} else if err == nil {
+			previous = version.Data
+		}
+	}
+
+	rendered, failures, err := renderK8sSecret(bwSecret, secrets, previous)
+	if err != nil {
+		return nil, err
+	}
+	SplitConfigMap(bwSecret, rendered)
+
+	if MergesIntoK8sSecret(bwSecret) && existing != nil {
+		metav1.SetMetaDataAnnotation(&rendered.ObjectMeta, ManagedKeysAnnotation, existing.Annotations[ManagedKeysAnnotation])
+		MergeManagedKeys(rendered, previous)
+	}
+
+	revisions := KeyRevisions(bwSecret, report.Revisions, rendered.Data)
+	ApplyPinnedRevisions(bwSecret, report.Revisions, previous, previousRevisions, rendered.Data, revisions)
+
+	preview := &operatorsv1.DryRunSummary{Time: metav1.Time{Time: time.Now().UTC()}}
+	for key, value := range rendered.Data {
+		previousValue, ok := previous[key]
+		if !ok {
+			preview.KeysCreated = append(preview.KeysCreated, key)
+		} else if !bytes.Equal(previousValue, value) {
+			preview.KeysUpdated = append(preview.KeysUpdated, key)
+		}
+	}
+	for key := range previous {
+		if _, ok := rendered.Data[key]; !ok {
+			preview.KeysRemoved = append(preview.KeysRemoved, key)
+		}
+	}
+	sort.Strings(preview.KeysCreated)
+	sort.Strings(preview.KeysUpdated)
+	sort.Strings(preview.KeysRemoved)
+	preview.KeysFailed = keyFailureNames(failures)
+
+	return preview, nil
+}
+
+// SetDryRunCondition marks the BitwardenSecret with the number of keys the previewed sync would change, or clears the
+// condition when preview is nil.
+func SetDryRunCondition(bwSecret *operatorsv1.BitwardenSecret, preview *operatorsv1.DryRunSummary) {
+	if preview == nil {
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, DryRunCondition)
+		return
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "DryRun",
+		Message: fmt.Sprintf("Nothing was written.  A sync would create %d, update %d, and remove %d keys of the secret", len(preview.KeysCreated), len(preview.KeysUpdated), len(preview.KeysRemoved)),
+		Type:    DryRunCondition,
+	})
+}
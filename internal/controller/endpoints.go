@@ -0,0 +1,104 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net/url"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// EndpointClientFactory is implemented by factories that can create clients for other API and identity endpoints,
+// which BitwardenSecrets pointing to a different server than the operator use.
+type EndpointClientFactory interface {
+	WithEndpoints(bwApiUrl string, identApiUrl string) BitwardenClientFactory
+}
+
+func (bc *BitwardenClientFactoryImp) WithEndpoints(bwApiUrl string, identApiUrl string) BitwardenClientFactory {
+	return NewBitwardenClientFactory(bwApiUrl, identApiUrl)
+}
+
+func (bc *BackendClientFactory) WithEndpoints(bwApiUrl string, identApiUrl string) BitwardenClientFactory {
+	return &BackendClientFactory{
+		Backend:     bc.Backend,
+		CABackend:   bc.CABackend,
+		CABundle:    bc.CABundle,
+		BwApiUrl:    bwApiUrl,
+		IdentApiUrl: identApiUrl,
+	}
+}
+
+func (bc *RecordingClientFactory) WithEndpoints(bwApiUrl string, identApiUrl string) BitwardenClientFactory {
+	endpoints, ok := bc.BitwardenClientFactory.(EndpointClientFactory)
+	if !ok {
+		return nil
+	}
+
+	return &RecordingClientFactory{
+		BitwardenClientFactory: endpoints.WithEndpoints(bwApiUrl, identApiUrl),
+		Recorder:               bc.Recorder,
+	}
+}
+
+// WithEndpoints returns a factory for the given endpoints created like the primary one.  The secondary endpoint
+// stands in for the operator endpoints only, so BitwardenSecrets with their own endpoints do not fail over.
+func (f *FailoverClientFactory) WithEndpoints(bwApiUrl string, identApiUrl string) BitwardenClientFactory {
+	endpoints, ok := f.Primary.(EndpointClientFactory)
+	if !ok {
+		return nil
+	}
+
+	return endpoints.WithEndpoints(bwApiUrl, identApiUrl)
+}
+
+// ClientFactoryFor returns the factory creating clients for the endpoints of the BitwardenSecret: the operator
+// factory unless spec.apiUrl and spec.identityUrl are set.
+func ClientFactoryFor(factory BitwardenClientFactory, bwSecret *operatorsv1.BitwardenSecret) (BitwardenClientFactory, error) {
+	apiUrl := bwSecret.Spec.ApiUrl
+	identityUrl := bwSecret.Spec.IdentityUrl
+	if apiUrl == "" && identityUrl == "" {
+		return factory, nil
+	}
+
+	if apiUrl == "" || identityUrl == "" {
+		return nil, fmt.Errorf("spec.apiUrl and spec.identityUrl must be set together")
+	}
+
+	for _, endpoint := range []string{apiUrl, identityUrl} {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("%q is not a valid Bitwarden endpoint URL", endpoint)
+		}
+	}
+
+	endpoints, ok := factory.(EndpointClientFactory)
+	if !ok {
+		return nil, fmt.Errorf("the client backend of the operator does not support per-resource endpoints")
+	}
+
+	endpointFactory := endpoints.WithEndpoints(apiUrl, identityUrl)
+	if endpointFactory == nil {
+		return nil, fmt.Errorf("the client backend of the operator does not support per-resource endpoints")
+	}
+
+	return endpointFactory, nil
+}
@@ -0,0 +1,211 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// ErrorClass tells how a failed sync is retried.
+type ErrorClass string
+
+const (
+	// Errors that usually clear up on their own, such as an unreachable or overloaded server.  They are retried soon,
+	// with a backoff.
+	ErrorClassTransient ErrorClass = "Transient"
+	// Errors that fail the same way until something outside of the operator changes, such as rejected credentials or
+	// a malformed spec.  They are only retried on the next refresh.
+	ErrorClassPermanent ErrorClass = "Permanent"
+	// Errors of unknown cause, retried on the next refresh
+	ErrorClassUnknown ErrorClass = "Unknown"
+)
+
+// Delay before the first retry of a transient error, doubled for every further failed attempt in a row
+const TransientRetryBaseInterval = 5 * time.Second
+
+// Reasons of the Stalled condition that stop a BitwardenSecret from syncing before its next refresh
+var permanentErrorReasons = map[string]bool{
+	"Unauthorized":         true,
+	"Forbidden":            true,
+	"NotFound":             true,
+	"UntrustedCertificate": true,
+	"InvalidSpec":          true,
+}
+
+// PullError is returned when pulling the secrets from Secrets Manager failed, so that its cause can be classified.
+type PullError struct {
+	Err error
+}
+
+func (e *PullError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PullError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidSpecError is returned when the spec of a BitwardenSecret cannot be synced as it is.
+type InvalidSpecError struct {
+	Err error
+}
+
+func (e *InvalidSpecError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidSpecError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError returns the class of the error a sync failed with, and a reason naming its cause.  Only pull errors
+// and invalid specs are classified; errors of the Kubernetes API and rendering are of unknown class.
+func ClassifyError(err error) (ErrorClass, string) {
+	var interruptedErr *InterruptedError
+	var invalidSpecErr *InvalidSpecError
+	var pullErr *PullError
+
+	switch {
+	case IsTimeoutError(err):
+		return ErrorClassTransient, "Timeout"
+	case errors.As(err, &interruptedErr):
+		return ErrorClassTransient, "Interrupted"
+	case IsCircuitOpenError(err):
+		return ErrorClassTransient, "CircuitOpen"
+	case errors.As(err, &invalidSpecErr):
+		return ErrorClassPermanent, "InvalidSpec"
+	case !errors.As(err, &pullErr) || bwclient.IsPanic(err):
+		return ErrorClassUnknown, "ReconciliationFailed"
+	// Certificate errors are network errors as well, but do not go away on their own
+	case bwclient.IsCertificateError(err):
+		return ErrorClassPermanent, "UntrustedCertificate"
+	case bwclient.IsUnreachableError(err):
+		return ErrorClassTransient, "Unreachable"
+	case bwclient.IsServerError(err):
+		return ErrorClassTransient, "ServerError"
+	// A session rejected later on is renewed by the next login, so only a login the server explicitly rejected is
+	// permanent.  Logins that failed for other reasons, such as writing the state file, are of unknown class.
+	case IsAuthError(err) && bwclient.IsAuthError(err):
+		return ErrorClassPermanent, "Unauthorized"
+	case bwclient.IsForbiddenError(err):
+		return ErrorClassPermanent, "Forbidden"
+	case bwclient.IsNotFoundError(err):
+		return ErrorClassPermanent, "NotFound"
+	}
+
+	return ErrorClassUnknown, "ReconciliationFailed"
+}
+
+// PermanentlyStalled reports whether the last sync of the current generation of the BitwardenSecret failed with a
+// permanent error.
+func PermanentlyStalled(bwSecret *operatorsv1.BitwardenSecret) bool {
+	condition := apimeta.FindStatusCondition(bwSecret.Status.Conditions, StalledCondition)
+	return condition != nil && condition.ObservedGeneration == bwSecret.Generation && permanentErrorReasons[condition.Reason]
+}
+
+// PermanentStallRemaining returns how long syncs of a BitwardenSecret that failed with a permanent error are skipped:
+// until the next refresh after the failed attempt.  The stall ends right away once the spec changes, or the
+// authorization token secret changes from the one with resourceVersion authTokenVersion read by the failed attempt.
+func (r *BitwardenSecretReconciler) PermanentStallRemaining(bwSecret *operatorsv1.BitwardenSecret, authTokenVersion string) time.Duration {
+	if !PermanentlyStalled(bwSecret) || authTokenVersion != bwSecret.Status.AuthTokenResourceVersion {
+		return 0
+	}
+
+	history := bwSecret.Status.History
+	if len(history) == 0 || history[len(history)-1].Result != "Failed" {
+		return 0
+	}
+
+	if remaining := time.Until(history[len(history)-1].Time.Add(r.RefreshInterval(bwSecret))); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// ResultForError returns when a sync that failed with the error is retried: soon for transient errors, but not before
+// the circuit breaker closes, and on the next refresh otherwise.
+func (r *BitwardenSecretReconciler) ResultForError(bwSecret *operatorsv1.BitwardenSecret, err error) ctrl.Result {
+	if class, _ := ClassifyError(err); class == ErrorClassTransient {
+		interval := r.TransientRetryInterval(bwSecret)
+		var circuitErr *CircuitOpenError
+		if errors.As(err, &circuitErr) && circuitErr.RetryAfter > interval {
+			interval = circuitErr.RetryAfter
+		}
+		return ctrl.Result{RequeueAfter: interval}
+	}
+
+	return ctrl.Result{RequeueAfter: r.RefreshInterval(bwSecret)}
+}
+
+// stalledBitwardenSecretsForAuthToken returns the BitwardenSecrets stalled by a permanent error that read their
+// authorization token from the secret, so that rotating the token syncs them without waiting for the next refresh.
+func (r *BitwardenSecretReconciler) stalledBitwardenSecretsForAuthToken(ctx context.Context, secret client.Object) []reconcile.Request {
+	bwSecrets := &operatorsv1.BitwardenSecretList{}
+	if err := r.List(ctx, bwSecrets); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range bwSecrets.Items {
+		bwSecret := &bwSecrets.Items[i]
+		authToken := bwSecret.Spec.AuthToken
+		namespace := bwSecret.Namespace
+		if authToken.Namespace != "" {
+			namespace = authToken.Namespace
+		}
+
+		if authToken.SecretName == secret.GetName() && namespace == secret.GetNamespace() && PermanentlyStalled(bwSecret) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: bwSecret.Namespace, Name: bwSecret.Name}})
+		}
+	}
+
+	return requests
+}
+
+// TransientRetryInterval returns when a sync that failed with a transient error is retried: after
+// TransientRetryBaseInterval, doubled for every earlier failed attempt in a row, but no later than the next refresh.
+func (r *BitwardenSecretReconciler) TransientRetryInterval(bwSecret *operatorsv1.BitwardenSecret) time.Duration {
+	refreshInterval := r.RefreshInterval(bwSecret)
+
+	interval := TransientRetryBaseInterval
+	history := bwSecret.Status.History
+	for i := len(history) - 2; i >= 0 && history[i].Result == "Failed" && interval < refreshInterval; i-- {
+		interval *= 2
+	}
+
+	if refreshInterval > 0 && interval > refreshInterval {
+		return refreshInterval
+	}
+
+	return interval
+}
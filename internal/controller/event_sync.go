@@ -0,0 +1,163 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// OrganizationEventSource reads the event log of an organization, as bwclient.EventsClient does.
+type OrganizationEventSource interface {
+	Events(start time.Time, end time.Time) ([]bwclient.OrganizationEvent, error)
+}
+
+// EventSyncer polls the event log of an organization every Interval and triggers an immediate sync of the
+// BitwardenSecrets affected by changed secrets, so that changes arrive within seconds even with long refresh intervals.
+// Each poll reaches back one more Interval, since events can take a moment to show up in the log.  It implements
+// manager.Runnable and runs on the leader only.
+type EventSyncer struct {
+	Client         client.Client
+	Source         OrganizationEventSource
+	OrganizationID string
+	Interval       time.Duration
+
+	// Changes found by the previous poll, which are not triggered again
+	seen map[string]bool
+}
+
+func (s *EventSyncer) Start(ctx context.Context) error {
+	if s.Interval <= 0 {
+		return fmt.Errorf("event poll interval must be positive, got %s", s.Interval)
+	}
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	since := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			now := time.Now()
+			if err := s.Poll(ctx, since.Add(-s.Interval), now); err != nil {
+				ctrl.Log.WithName("event-sync").Error(err, "Failed to poll the organization event log", "organization", s.OrganizationID)
+				continue
+			}
+			since = now
+		}
+	}
+}
+
+// Poll triggers a sync of the BitwardenSecrets affected by the secrets changed between start and end.
+func (s *EventSyncer) Poll(ctx context.Context, start time.Time, end time.Time) error {
+	events, err := s.Source.Events(start, end)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	changed := []string{}
+	for _, event := range events {
+		if !bwclient.IsSecretChange(event.Type) {
+			continue
+		}
+
+		id := event.SecretID
+		if id == "" {
+			id = event.ItemID
+		}
+		change := fmt.Sprintf("%d/%s/%s", event.Type, id, event.Date.Format(time.RFC3339Nano))
+		seen[change] = true
+		if !s.seen[change] {
+			changed = append(changed, id)
+		}
+	}
+	s.seen = seen
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	bwSecrets := &operatorsv1.BitwardenSecretList{}
+	if err := s.Client.List(ctx, bwSecrets); err != nil {
+		return err
+	}
+
+	logger := ctrl.Log.WithName("event-sync")
+	for _, name := range AffectedBitwardenSecrets(bwSecrets.Items, s.OrganizationID, changed) {
+		if _, err := TriggerSync(ctx, s.Client, name); err != nil {
+			logger.Error(err, fmt.Sprintf("Failed to trigger a sync of %s/%s", name.Namespace, name.Name))
+			continue
+		}
+		logger.V(1).Info(fmt.Sprintf("Triggered a sync of %s/%s after changes in Secrets Manager", name.Namespace, name.Name))
+	}
+
+	return nil
+}
+
+// AffectedBitwardenSecrets returns the BitwardenSecrets of the organization that read one of the changed secrets.
+// BitwardenSecrets that only map secrets are affected by changes to the mapped secrets, every other one by any change
+// in its organization, since it syncs every secret it can access or references secrets elsewhere in its spec.
+func AffectedBitwardenSecrets(bwSecrets []operatorsv1.BitwardenSecret, orgId string, changed []string) []types.NamespacedName {
+	affected := []types.NamespacedName{}
+	for i := range bwSecrets {
+		bwSecret := &bwSecrets[i]
+		if bwSecret.Spec.Paused || !sameOrganization(bwSecret.Spec.OrganizationId, orgId) {
+			continue
+		}
+
+		if readsOnlyMappedSecrets(bwSecret) && !referencesAny(MappedSecretIDs(bwSecret.Spec.SecretMap), changed) {
+			continue
+		}
+
+		affected = append(affected, types.NamespacedName{Namespace: bwSecret.Namespace, Name: bwSecret.Name})
+	}
+
+	return affected
+}
+
+// readsOnlyMappedSecrets reports whether the secret map lists every secret the BitwardenSecret reads.
+func readsOnlyMappedSecrets(bwSecret *operatorsv1.BitwardenSecret) bool {
+	spec := &bwSecret.Spec
+	return spec.SecretMap != nil && spec.Template == nil && spec.DockerConfig == nil && spec.TLS == nil && spec.BasicAuth == nil && spec.SSHAuth == nil && spec.Kubeconfig == nil && len(spec.Targets) == 0
+}
+
+func referencesAny(ids []string, changed []string) bool {
+	for _, id := range ids {
+		for _, change := range changed {
+			if strings.EqualFold(id, change) {
+				return true
+			}
+		}
+	}
+	return false
+}
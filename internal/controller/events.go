@@ -0,0 +1,85 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Reasons of the events recorded on BitwardenSecrets
+const (
+	SyncStartedReason      = "SyncStarted"
+	SecretCreatedReason    = "SecretCreated"
+	SecretUpdatedReason    = "SecretUpdated"
+	AuthFailedReason       = "AuthFailed"
+	ApiFailedReason        = "ApiFailed"
+	SecretRenamedReason    = "SecretRenamed"
+	RolloutRestartedReason = "RolloutRestarted"
+	RolloutFailedReason    = "RolloutFailed"
+	DeniedReason           = "Denied"
+	RolledBackReason       = "RolledBack"
+)
+
+// AuthError is returned when the machine account could not log in to Secrets Manager, as opposed to a failing API call
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// IsAuthError reports whether the error was caused by a failed login.
+func IsAuthError(err error) bool {
+	var authErr *AuthError
+	return errors.As(err, &authErr)
+}
+
+// recordEvent records an event on the BitwardenSecret, scrubbing the sensitive values of the current reconcile and
+// access tokens from the message.  It does nothing without a recorder, as in tests.
+func recordEvent(ctx context.Context, recorder record.EventRecorder, bwSecret *operatorsv1.BitwardenSecret, eventType string, reason string, message string) {
+	if recorder == nil {
+		return
+	}
+
+	// Events are readable by anyone allowed to list events in the namespace
+	recorder.Event(bwSecret, eventType, reason, redactorFrom(ctx).Redact(message))
+}
+
+// recordPullFailure records a failed pull as an authentication failure or an API failure.
+func recordPullFailure(ctx context.Context, recorder record.EventRecorder, bwSecret *operatorsv1.BitwardenSecret, err error) {
+	if IsAuthError(err) {
+		recordEvent(ctx, recorder, bwSecret, corev1.EventTypeWarning, AuthFailedReason, "Failed to authenticate to Secrets Manager: "+err.Error())
+		return
+	}
+
+	recordEvent(ctx, recorder, bwSecret, corev1.EventTypeWarning, ApiFailedReason, "Failed to pull secrets from Secrets Manager: "+err.Error())
+}
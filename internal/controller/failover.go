@@ -0,0 +1,419 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// Condition set while syncs are served by the secondary endpoint
+const FailedOverCondition = "FailedOver"
+
+// Suffix of the state file of the secondary endpoint.  Each endpoint logs in on its own, so they must not overwrite
+// each other's state.
+const secondaryStateSuffix = ".secondary"
+
+// FailoverClientFactory creates clients that fail over from a primary to a secondary pair of API and identity
+// endpoints, for example a read replica or disaster recovery region of a self-hosted server, when the primary cannot
+// be reached.  The primary is tried again once RetryPrimaryAfter has passed since the failover.
+type FailoverClientFactory struct {
+	Primary           BitwardenClientFactory
+	Secondary         BitwardenClientFactory
+	RetryPrimaryAfter time.Duration
+
+	mu           sync.Mutex
+	failedOverAt time.Time
+	changes      chan event.GenericEvent
+}
+
+const (
+	primaryEndpoint   = 0
+	secondaryEndpoint = 1
+)
+
+// NewFailoverClientFactory returns a factory failing over from primary to secondary.
+func NewFailoverClientFactory(primary BitwardenClientFactory, secondary BitwardenClientFactory, retryPrimaryAfter time.Duration) *FailoverClientFactory {
+	return &FailoverClientFactory{
+		Primary:           primary,
+		Secondary:         secondary,
+		RetryPrimaryAfter: retryPrimaryAfter,
+		changes:           make(chan event.GenericEvent, 1),
+	}
+}
+
+func (f *FailoverClientFactory) GetBitwardenClient() (bwclient.BitwardenClientInterface, error) {
+	return &failoverClient{factory: f}, nil
+}
+
+func (f *FailoverClientFactory) GetApiUrl() string {
+	return f.endpoint(f.preferred()).GetApiUrl()
+}
+
+func (f *FailoverClientFactory) GetIdentityApiUrl() string {
+	return f.endpoint(f.preferred()).GetIdentityApiUrl()
+}
+
+// FailedOver reports whether clients currently use the secondary endpoint.
+func (f *FailoverClientFactory) FailedOver() bool {
+	return f.preferred() == secondaryEndpoint
+}
+
+// Changes returns a channel that receives an event whenever clients fail over to the secondary endpoint or return to
+// the primary, so that the FailedOver condition of every BitwardenSecret is updated instead of only the ones that
+// happen to sync.
+func (f *FailoverClientFactory) Changes() <-chan event.GenericEvent {
+	return f.changes
+}
+
+// stableEndpoints returns the factory whose endpoints identify the clients of factory, for example in the client
+// cache and the circuit breakers.  A failover factory is identified by its primary endpoints, since the endpoints it
+// reports change with the failover.
+func stableEndpoints(factory BitwardenClientFactory) BitwardenClientFactory {
+	if failover, ok := factory.(*FailoverClientFactory); ok {
+		return failover.Primary
+	}
+
+	return factory
+}
+
+// SetFailedOverCondition marks a BitwardenSecret synced through the secondary endpoint with the FailedOver condition,
+// and clears it once the primary is used again.
+func SetFailedOverCondition(bwSecret *operatorsv1.BitwardenSecret, factory *FailoverClientFactory) {
+	if !factory.FailedOver() {
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, FailedOverCondition)
+		return
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "PrimaryUnreachable",
+		Message: fmt.Sprintf("The primary Bitwarden endpoint %s is unreachable; syncing from %s", factory.Primary.GetApiUrl(), factory.Secondary.GetApiUrl()),
+		Type:    FailedOverCondition,
+	})
+}
+
+// bitwardenSecretsForFailover returns the BitwardenSecrets synced through the failover of the operator endpoints whose
+// FailedOver condition no longer matches it.  BitwardenSecrets with their own endpoints or CA bundle never fail over.
+func (r *BitwardenSecretReconciler) bitwardenSecretsForFailover(ctx context.Context, _ client.Object) []reconcile.Request {
+	failover, ok := r.BitwardenClientFactory.(*FailoverClientFactory)
+	if !ok {
+		return nil
+	}
+
+	bwSecrets := &operatorsv1.BitwardenSecretList{}
+	if err := r.List(ctx, bwSecrets); err != nil {
+		return nil
+	}
+
+	failedOver := failover.FailedOver()
+	requests := []reconcile.Request{}
+	for i := range bwSecrets.Items {
+		bwSecret := &bwSecrets.Items[i]
+		if bwSecret.Spec.ApiUrl != "" || bwSecret.Spec.CABundleSecretRef != nil {
+			continue
+		}
+
+		if apimeta.IsStatusConditionTrue(bwSecret.Status.Conditions, FailedOverCondition) != failedOver {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: bwSecret.Namespace, Name: bwSecret.Name}})
+		}
+	}
+
+	return requests
+}
+
+func (f *FailoverClientFactory) endpoint(index int) BitwardenClientFactory {
+	if index == secondaryEndpoint {
+		return f.Secondary
+	}
+
+	return f.Primary
+}
+
+func (f *FailoverClientFactory) preferred() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failedOverAt.IsZero() || time.Since(f.failedOverAt) >= f.RetryPrimaryAfter {
+		return primaryEndpoint
+	}
+
+	return secondaryEndpoint
+}
+
+// unreachable records that an endpoint could not be reached and returns the endpoint to try instead.
+func (f *FailoverClientFactory) unreachable(index int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if index == primaryEndpoint {
+		if f.failedOverAt.IsZero() {
+			endpointFailoversTotal.Inc()
+			f.changed()
+		}
+		f.failedOverAt = time.Now()
+		endpointFailoverActive.Set(1)
+		return secondaryEndpoint
+	}
+
+	f.returned()
+	return primaryEndpoint
+}
+
+// reachable records a successful call to an endpoint.
+func (f *FailoverClientFactory) reachable(index int) {
+	if index != primaryEndpoint {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.returned()
+}
+
+// returned records that clients use the primary endpoint again.  The caller must hold f.mu.
+func (f *FailoverClientFactory) returned() {
+	if !f.failedOverAt.IsZero() {
+		f.changed()
+	}
+	f.failedOverAt = time.Time{}
+	endpointFailoverActive.Set(0)
+}
+
+// changed notifies the receiver of Changes without blocking.  A notification still pending covers this one.
+func (f *FailoverClientFactory) changed() {
+	select {
+	case f.changes <- event.GenericEvent{Object: &operatorsv1.BitwardenSecret{}}:
+	default:
+	}
+}
+
+// failoverClient holds one client per endpoint, created and logged in on first use.
+type failoverClient struct {
+	factory     *FailoverClientFactory
+	clients     [2]bwclient.BitwardenClientInterface
+	loggedIn    [2]bool
+	accessToken string
+	statePath   *string
+}
+
+func (c *failoverClient) AccessTokenLogin(accessToken string, statePath *string) error {
+	c.accessToken = accessToken
+	c.statePath = statePath
+	c.loggedIn = [2]bool{}
+
+	return c.call(func(client bwclient.BitwardenClientInterface) error {
+		return nil
+	})
+}
+
+func (c *failoverClient) Projects() bwclient.ProjectsInterface {
+	return &failoverProjects{client: c}
+}
+
+func (c *failoverClient) Secrets() bwclient.SecretsInterface {
+	return &failoverSecrets{client: c}
+}
+
+func (c *failoverClient) Close() {
+	for _, client := range c.clients {
+		if client != nil {
+			client.Close()
+		}
+	}
+}
+
+// call runs fn against a logged in client of the preferred endpoint, failing over to the other endpoint once if the
+// preferred one cannot be reached.
+func (c *failoverClient) call(fn func(client bwclient.BitwardenClientInterface) error) error {
+	index := c.factory.preferred()
+
+	err := c.callEndpoint(index, fn)
+	if bwclient.IsUnreachableError(err) {
+		index = c.factory.unreachable(index)
+		err = c.callEndpoint(index, fn)
+	}
+
+	return err
+}
+
+func (c *failoverClient) callEndpoint(index int, fn func(client bwclient.BitwardenClientInterface) error) error {
+	if err := c.login(index); err != nil {
+		return err
+	}
+
+	if err := fn(c.clients[index]); err != nil {
+		return err
+	}
+
+	c.factory.reachable(index)
+	return nil
+}
+
+func (c *failoverClient) login(index int) error {
+	if c.clients[index] == nil {
+		client, err := c.factory.endpoint(index).GetBitwardenClient()
+		if err != nil {
+			return err
+		}
+		c.clients[index] = client
+	}
+
+	if c.loggedIn[index] {
+		return nil
+	}
+
+	if err := c.clients[index].AccessTokenLogin(c.accessToken, c.endpointStatePath(index)); err != nil {
+		return err
+	}
+	c.loggedIn[index] = true
+
+	return nil
+}
+
+// endpointStatePath returns the state file of an endpoint.  The secondary endpoint keeps its state next to the one of
+// the primary.
+func (c *failoverClient) endpointStatePath(index int) *string {
+	if index == primaryEndpoint || c.statePath == nil || *c.statePath == "" {
+		return c.statePath
+	}
+
+	statePath := *c.statePath + secondaryStateSuffix
+	return &statePath
+}
+
+// failoverSecrets and failoverProjects run every call through the failover of their client.
+type failoverSecrets struct {
+	client *failoverClient
+}
+
+func (s *failoverSecrets) Create(key, value, note string, organizationID string, projectIDs []string) (res *bwclient.SecretResponse, err error) {
+	err = s.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Secrets().Create(key, value, note, organizationID, projectIDs)
+		return err
+	})
+	return res, err
+}
+
+func (s *failoverSecrets) List(organizationID string) (res *bwclient.SecretIdentifiersResponse, err error) {
+	err = s.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Secrets().List(organizationID)
+		return err
+	})
+	return res, err
+}
+
+func (s *failoverSecrets) Get(secretID string) (res *bwclient.SecretResponse, err error) {
+	err = s.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Secrets().Get(secretID)
+		return err
+	})
+	return res, err
+}
+
+func (s *failoverSecrets) GetByIDS(secretIDs []string) (res *bwclient.SecretsResponse, err error) {
+	err = s.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Secrets().GetByIDS(secretIDs)
+		return err
+	})
+	return res, err
+}
+
+func (s *failoverSecrets) Update(secretID string, key, value, note string, organizationID string, projectIDs []string) (res *bwclient.SecretResponse, err error) {
+	err = s.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Secrets().Update(secretID, key, value, note, organizationID, projectIDs)
+		return err
+	})
+	return res, err
+}
+
+func (s *failoverSecrets) Delete(secretIDs []string) (res *bwclient.SecretsDeleteResponse, err error) {
+	err = s.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Secrets().Delete(secretIDs)
+		return err
+	})
+	return res, err
+}
+
+func (s *failoverSecrets) Sync(organizationID string, lastSyncedDate *time.Time) (res *bwclient.SecretsSyncResponse, err error) {
+	err = s.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Secrets().Sync(organizationID, lastSyncedDate)
+		return err
+	})
+	return res, err
+}
+
+type failoverProjects struct {
+	client *failoverClient
+}
+
+func (p *failoverProjects) Create(organizationID string, name string) (res *bwclient.ProjectResponse, err error) {
+	err = p.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Projects().Create(organizationID, name)
+		return err
+	})
+	return res, err
+}
+
+func (p *failoverProjects) List(organizationID string) (res *bwclient.ProjectsResponse, err error) {
+	err = p.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Projects().List(organizationID)
+		return err
+	})
+	return res, err
+}
+
+func (p *failoverProjects) Get(projectID string) (res *bwclient.ProjectResponse, err error) {
+	err = p.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Projects().Get(projectID)
+		return err
+	})
+	return res, err
+}
+
+func (p *failoverProjects) Update(projectID string, organizationID string, name string) (res *bwclient.ProjectResponse, err error) {
+	err = p.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Projects().Update(projectID, organizationID, name)
+		return err
+	})
+	return res, err
+}
+
+func (p *failoverProjects) Delete(projectIDs []string) (res *bwclient.ProjectsDeleteResponse, err error) {
+	err = p.client.call(func(client bwclient.BitwardenClientInterface) error {
+		res, err = client.Projects().Delete(projectIDs)
+		return err
+	})
+	return res, err
+}
@@ -0,0 +1,158 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Condition set while keys of the Kubernetes secret fail to render with the Partial failure policy
+const KeysFailedCondition = "KeysFailed"
+
+// KeyFailures holds why keys of the Kubernetes secret failed to render, by key
+type KeyFailures map[string]string
+
+func (f KeyFailures) add(keys []string, err error) {
+	for _, key := range keys {
+		f[key] = err.Error()
+	}
+}
+
+// WritesPartially reports whether the BitwardenSecret writes the healthy keys when others fail to render.
+func WritesPartially(bwSecret *operatorsv1.BitwardenSecret) bool {
+	return bwSecret.Spec.FailurePolicy == operatorsv1.FailurePolicyPartial
+}
+
+// ApplySecretMapPartially is ApplySecretMap for the Partial failure policy.  Map entries whose secret was not pulled or
+// whose value cannot be extracted or decoded are left out and added to the failures instead of failing the map.
+func ApplySecretMapPartially(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret, failures KeyFailures) {
+	if bwSecret.Spec.SecretMap == nil {
+		return
+	}
+
+	filtered := make(map[string][]byte, len(bwSecret.Spec.SecretMap))
+	for _, m := range bwSecret.Spec.SecretMap {
+		keys := make([]string, 0, len(m.Aliases)+1)
+		for _, key := range MappedKeys(m) {
+			keys = append(keys, TransformKey(bwSecret, key))
+		}
+
+		v, ok, err := MappedValue(m, secret.Data)
+		if err != nil {
+			failures.add(keys, err)
+			continue
+		}
+		if !ok {
+			failures.add(keys, fmt.Errorf("secret %s is not accessible by the machine account", m.BwSecretId))
+			continue
+		}
+
+		for _, key := range MappedKeys(m) {
+			filtered[key] = v
+		}
+	}
+
+	secret.Data = filtered
+}
+
+// ApplyTemplatePartially is ApplyTemplate for the Partial failure policy.  Every template is rendered on its own, so
+// that one that fails is added to the failures without holding back the others.
+func ApplyTemplatePartially(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte, secret *corev1.Secret, failures KeyFailures) {
+	if bwSecret.Spec.Template == nil || len(bwSecret.Spec.Template.Data) == 0 {
+		return
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	// Templates see the data as it was before any of them were rendered, like with ApplyTemplate
+	data := make(map[string][]byte, len(secret.Data))
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+
+	for key, text := range bwSecret.Spec.Template.Data {
+		rendered, err := RenderTemplates(map[string]string{key: text}, secrets, data)
+		if err != nil {
+			failures.add([]string{key}, err)
+			continue
+		}
+		secret.Data[key] = rendered[key]
+	}
+}
+
+// KeepFailedKeys writes the previous values of the failed keys back into the data, so that a key that worked before is
+// not removed by a failure.  Failed keys without a previous value are left out.
+func KeepFailedKeys(data map[string][]byte, previous map[string][]byte, failures KeyFailures) {
+	for key := range failures {
+		if value, ok := previous[key]; ok {
+			data[key] = value
+		}
+	}
+}
+
+// SetKeysFailedCondition records the failed keys in status.failedKeys and the KeysFailed condition, or clears both when
+// no key failed.  Reasons are redacted, since they can quote pulled values.
+func SetKeysFailedCondition(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, failures KeyFailures) {
+	if len(failures) == 0 {
+		bwSecret.Status.FailedKeys = nil
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, KeysFailedCondition)
+		return
+	}
+
+	redactor := redactorFrom(ctx)
+	keys := keyFailureNames(failures)
+
+	bwSecret.Status.FailedKeys = make([]operatorsv1.KeyFailure, 0, len(keys))
+	for _, key := range keys {
+		bwSecret.Status.FailedKeys = append(bwSecret.Status.FailedKeys, operatorsv1.KeyFailure{
+			Key:    key,
+			Reason: truncate(redactor.Redact(failures[key]), maxSyncHistoryReason),
+		})
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "KeysFailed",
+		Message: fmt.Sprintf("%d keys failed to render and kept their previous values: %s", len(keys), joinTraceIds(keys)),
+		Type:    KeysFailedCondition,
+	})
+}
+
+// keyFailureNames returns the sorted failed keys.
+func keyFailureNames(failures KeyFailures) []string {
+	keys := make([]string, 0, len(failures))
+	for key := range failures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
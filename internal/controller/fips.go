@@ -0,0 +1,74 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// The REST backend hashes with NewHash as well, so that none of the operator's hashing bypasses it
+func init() {
+	bwclient.SetHash(NewHash)
+}
+
+// EnableFIPSMode checks that the operator is restricted to FIPS 140 validated cryptography,
+// which only binaries built against a validated module are (see "make build-fips"), and only
+// with the "rest" client backend: the native SDK hashes and encrypts in Rust, outside of the
+// module.  It fails otherwise, so a regulated deployment can never silently fall back to
+// unvalidated crypto.
+func EnableFIPSMode(clientBackend string) error {
+	if clientBackend != "rest" {
+		return fmt.Errorf("FIPS mode requires the rest client backend; the %s backend performs cryptography outside of the FIPS 140 validated module", clientBackend)
+	}
+
+	if !FIPSBuild() {
+		return fmt.Errorf("FIPS mode was requested but this binary was not built with a FIPS 140 validated crypto module")
+	}
+
+	return nil
+}
+
+// NewHash returns the digest used for every hash the operator computes.  SHA-256 is approved
+// under FIPS 180-4, so all hashing goes through here rather than picking an algorithm per call site.
+func NewHash() hash.Hash {
+	return sha256.New()
+}
+
+// NewStateCipher returns the AEAD used to encrypt data the operator persists.  Only AES-256-GCM
+// keys are accepted so that state encryption stays within the FIPS approved algorithm set.
+func NewStateCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("state encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,35 @@
+//go:build boringcrypto
+
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"crypto/boring"
+
+	// Restricts crypto/tls to FIPS approved protocol versions, cipher suites and curves.
+	_ "crypto/tls/fipsonly"
+)
+
+// FIPSBuild reports whether the binary is linked against the BoringCrypto FIPS 140 module.
+func FIPSBuild() bool {
+	return boring.Enabled()
+}
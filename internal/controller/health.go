@@ -0,0 +1,140 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// APIHealth tracks whether pulls reach Secrets Manager at all, for a readiness check that platform probes can react
+// to.  Pulls that got any answer, even one rejecting their machine account or organization, count as successful.
+type APIHealth struct {
+	// How long every pull must have failed for, with no pull reaching the API in between, before the check fails
+	UnreachableAfter time.Duration
+
+	mu           sync.Mutex
+	failingSince time.Time
+	lastErr      error
+}
+
+// RecordPull records the outcome of a pull.  Only pulls that could not reach the API, failed on the server, or timed
+// out count as failing.  Pulls paused by the circuit breaker tell nothing about the API and are ignored.  It does
+// nothing on a nil APIHealth.
+func (h *APIHealth) RecordPull(err error) {
+	if h == nil || IsCircuitOpenError(err) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !bwclient.IsUnreachableError(err) && !bwclient.IsServerError(err) && !IsTimeoutError(err) {
+		h.failingSince = time.Time{}
+		h.lastErr = nil
+		return
+	}
+
+	if h.failingSince.IsZero() {
+		h.failingSince = time.Now()
+	}
+	h.lastErr = err
+}
+
+// Check is a healthz.Checker that fails once pulls have been failing for longer than UnreachableAfter.
+func (h *APIHealth) Check(_ *http.Request) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failingSince.IsZero() || time.Since(h.failingSince) < h.UnreachableAfter {
+		return nil
+	}
+
+	return fmt.Errorf("no pull has reached the Bitwarden API since %s: %w", h.failingSince.UTC().Format(time.RFC3339), h.lastErr)
+}
+
+// LastSyncFailed reports whether the last sync attempt of the BitwardenSecret failed.
+func LastSyncFailed(bwSecret *operatorsv1.BitwardenSecret) bool {
+	history := bwSecret.Status.History
+	return len(history) > 0 && history[len(history)-1].Result == "Failed"
+}
+
+// FailedSync describes a BitwardenSecret whose last sync attempt failed
+type FailedSync struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Time      time.Time `json:"time"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// FailedSyncsHandler serves the BitwardenSecrets whose last sync attempt failed as JSON, sorted by namespace and
+// name, for dashboards and troubleshooting.  Reader must be set before the first request.
+type FailedSyncsHandler struct {
+	Reader client.Reader
+}
+
+func (h *FailedSyncsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	bwSecrets := &operatorsv1.BitwardenSecretList{}
+	if err := h.Reader.List(req.Context(), bwSecrets); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]FailedSync{"failedSyncs": FailedSyncs(bwSecrets.Items)})
+}
+
+// FailedSyncs returns the BitwardenSecrets whose last sync attempt failed, sorted by namespace and name.
+func FailedSyncs(bwSecrets []operatorsv1.BitwardenSecret) []FailedSync {
+	failed := []FailedSync{}
+	for i := range bwSecrets {
+		bwSecret := &bwSecrets[i]
+		if !LastSyncFailed(bwSecret) {
+			continue
+		}
+
+		attempt := bwSecret.Status.History[len(bwSecret.Status.History)-1]
+		failed = append(failed, FailedSync{
+			Namespace: bwSecret.Namespace,
+			Name:      bwSecret.Name,
+			Time:      attempt.Time.Time,
+			Reason:    attempt.Reason,
+		})
+	}
+
+	sort.Slice(failed, func(i, j int) bool {
+		if failed[i].Namespace != failed[j].Namespace {
+			return failed[i].Namespace < failed[j].Namespace
+		}
+		return failed[i].Name < failed[j].Name
+	})
+
+	return failed
+}
@@ -0,0 +1,183 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Label on history snapshots naming the secret whose previous data they hold
+const HistoryOfLabel = "k8s.bitwarden.com/history-of"
+
+// Annotation of BitwardenSecrets naming the history snapshot the Kubernetes secret is rolled back to.  Syncing is held
+// until it is removed.
+const RollbackAnnotation = "k8s.bitwarden.com/rollback-to"
+
+// Condition of BitwardenSecrets whose Kubernetes secret has been rolled back to a history snapshot
+const RolledBackCondition = "RolledBack"
+
+// HistorySnapshotNumber returns the number of the history snapshot of the secret, or false when the name is not one of
+// its snapshots.
+func HistorySnapshotNumber(secretName string, name string) (int, bool) {
+	suffix, ok := strings.CutPrefix(name, secretName+"-v")
+	if !ok {
+		return 0, false
+	}
+
+	number, err := strconv.Atoi(suffix)
+	if err != nil || number < 1 || strconv.Itoa(number) != suffix {
+		return 0, false
+	}
+	return number, true
+}
+
+// ListHistorySnapshots returns the history snapshots of the Kubernetes secret, oldest first.
+func (r *BitwardenSecretReconciler) ListHistorySnapshots(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret) ([]corev1.Secret, error) {
+	list := &corev1.SecretList{}
+	err := r.List(ctx, list, client.InNamespace(bwSecret.Namespace), client.MatchingLabels{
+		"k8s.bitwarden.com/bw-secret": string(bwSecret.UID),
+		HistoryOfLabel:                bwSecret.Spec.SecretName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []corev1.Secret{}
+	for _, snapshot := range list.Items {
+		if _, ok := HistorySnapshotNumber(bwSecret.Spec.SecretName, snapshot.Name); ok {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		a, _ := HistorySnapshotNumber(bwSecret.Spec.SecretName, snapshots[i].Name)
+		b, _ := HistorySnapshotNumber(bwSecret.Spec.SecretName, snapshots[j].Name)
+		return a < b
+	})
+	return snapshots, nil
+}
+
+// WriteHistorySnapshot keeps the data of the Kubernetes secret, before it is overwritten, in an immutable snapshot
+// numbered after the newest one, and deletes the snapshots beyond spec.history.count.
+func (r *BitwardenSecretReconciler) WriteHistorySnapshot(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, previous *corev1.Secret) (string, error) {
+	snapshots, err := r.ListHistorySnapshots(ctx, bwSecret)
+	if err != nil {
+		return "", err
+	}
+
+	number := 1
+	if len(snapshots) > 0 {
+		newest, _ := HistorySnapshotNumber(bwSecret.Spec.SecretName, snapshots[len(snapshots)-1].Name)
+		number = newest + 1
+	}
+
+	immutable := true
+	snapshot := CreateK8sSecret(bwSecret)
+	snapshot.Name = fmt.Sprintf("%s-v%d", bwSecret.Spec.SecretName, number)
+	snapshot.Labels[HistoryOfLabel] = bwSecret.Spec.SecretName
+	snapshot.Type = previous.Type
+	snapshot.Data = previous.Data
+	snapshot.Immutable = &immutable
+	snapshot.Annotations[DataChecksumAnnotation] = DataChecksum(previous.Data)
+	if revisions, ok := previous.Annotations[RevisionsAnnotation]; ok {
+		snapshot.Annotations[RevisionsAnnotation] = revisions
+	}
+
+	if err := ctrl.SetControllerReference(bwSecret, snapshot, r.Scheme); err != nil {
+		return "", err
+	}
+
+	if err := r.Create(ctx, snapshot); err != nil {
+		return "", err
+	}
+	snapshots = append(snapshots, *snapshot)
+
+	for i := 0; i < len(snapshots)-bwSecret.Spec.History.Count; i++ {
+		if err := r.Delete(ctx, &snapshots[i]); err != nil && !errors.IsNotFound(err) {
+			return snapshot.Name, err
+		}
+	}
+
+	return snapshot.Name, nil
+}
+
+// RollBackK8sSecret writes the data of the named history snapshot to the Kubernetes secret, creating it when it does
+// not exist.  It reports false when the secret already holds the data of the snapshot.
+func (r *BitwardenSecretReconciler) RollBackK8sSecret(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, live *corev1.Secret, name string) (bool, error) {
+	snapshot := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: bwSecret.Namespace, Name: name}, snapshot)
+	if err != nil && errors.IsNotFound(err) {
+		return false, fmt.Errorf("the history snapshot %s does not exist", name)
+	} else if err != nil {
+		return false, err
+	}
+
+	if snapshot.Labels[HistoryOfLabel] != bwSecret.Spec.SecretName || snapshot.Labels["k8s.bitwarden.com/bw-secret"] != string(bwSecret.UID) {
+		return false, fmt.Errorf("%s is not a history snapshot of %s", name, bwSecret.Spec.SecretName)
+	}
+
+	if live != nil && live.Type == snapshot.Type && DataChecksum(live.Data) == DataChecksum(snapshot.Data) {
+		return false, nil
+	}
+
+	created := live == nil
+	if created {
+		live = CreateK8sSecret(bwSecret)
+		if err := ctrl.SetControllerReference(bwSecret, live, r.Scheme); err != nil {
+			return false, err
+		}
+	}
+	if live.Annotations == nil {
+		live.Annotations = map[string]string{}
+	}
+
+	live.Data = snapshot.Data
+	SetDataChecksumAnnotation(live)
+	if revisions, ok := snapshot.Annotations[RevisionsAnnotation]; ok {
+		live.Annotations[RevisionsAnnotation] = revisions
+	} else {
+		delete(live.Annotations, RevisionsAnnotation)
+	}
+
+	switch {
+	case created:
+		live.Type = snapshot.Type
+		err = r.Create(ctx, live)
+	case live.Type != snapshot.Type || K8sSecretImmutable(live):
+		live.Immutable = nil
+		err = r.ReplaceK8sSecret(ctx, live, snapshot.Type)
+	default:
+		err = r.Update(ctx, live)
+	}
+	return err == nil, err
+}
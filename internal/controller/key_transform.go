@@ -0,0 +1,52 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// TransformKey returns the key a mapped or unmapped value is written to after spec.keyTransform is applied.
+func TransformKey(bwSecret *operatorsv1.BitwardenSecret, key string) string {
+	transform := bwSecret.Spec.KeyTransform
+	if transform == nil {
+		return key
+	}
+
+	return transform.Prefix + key + transform.Suffix
+}
+
+// ApplyKeyTransform renames every key of the secret written from Secrets Manager values, mapped or not, with the
+// prefix and suffix of spec.keyTransform.  Keys assembled by the operator, such as tls.crt, keep their names.
+func ApplyKeyTransform(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret) {
+	if bwSecret.Spec.KeyTransform == nil {
+		return
+	}
+
+	transformed := make(map[string][]byte, len(secret.Data))
+	for key, value := range secret.Data {
+		transformed[TransformKey(bwSecret, key)] = value
+	}
+
+	secret.Data = transformed
+}
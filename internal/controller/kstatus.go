@@ -0,0 +1,88 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Conditions following the kstatus conventions (https://github.com/kubernetes-sigs/cli-utils/tree/master/pkg/kstatus),
+// so that GitOps tools such as Flux and Argo CD report the health of a resource without custom health checks.  Ready
+// is always present once a resource was reconciled, while Reconciling and Stalled are only set while they are True.
+const (
+	ReadyCondition       = "Ready"
+	ReconcilingCondition = "Reconciling"
+	StalledCondition     = "Stalled"
+)
+
+// SetReadyCondition marks a resource as Ready after a successful sync of the given generation.
+func SetReadyCondition(conditions *[]metav1.Condition, generation int64, message string) {
+	apimeta.SetStatusCondition(conditions, metav1.Condition{
+		Status:             metav1.ConditionTrue,
+		Reason:             "ReconciliationComplete",
+		Message:            message,
+		Type:               ReadyCondition,
+		ObservedGeneration: generation,
+	})
+	apimeta.RemoveStatusCondition(conditions, ReconcilingCondition)
+	apimeta.RemoveStatusCondition(conditions, StalledCondition)
+}
+
+// SetReconcilingCondition marks a resource as not Ready while the sync of a new generation is under way, or while a
+// failed sync is retried shortly.
+func SetReconcilingCondition(conditions *[]metav1.Condition, generation int64, reason string, message string) {
+	apimeta.SetStatusCondition(conditions, metav1.Condition{
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		Type:               ReconcilingCondition,
+		ObservedGeneration: generation,
+	})
+	apimeta.SetStatusCondition(conditions, metav1.Condition{
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		Type:               ReadyCondition,
+		ObservedGeneration: generation,
+	})
+	apimeta.RemoveStatusCondition(conditions, StalledCondition)
+}
+
+// SetStalledCondition marks a resource as not Ready after a failed sync.  The sync is retried on the next refresh, but
+// the resource does not progress until the cause is fixed.
+func SetStalledCondition(conditions *[]metav1.Condition, generation int64, reason string, message string) {
+	apimeta.SetStatusCondition(conditions, metav1.Condition{
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		Type:               StalledCondition,
+		ObservedGeneration: generation,
+	})
+	apimeta.SetStatusCondition(conditions, metav1.Condition{
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		Type:               ReadyCondition,
+		ObservedGeneration: generation,
+	})
+	apimeta.RemoveStatusCondition(conditions, ReconcilingCondition)
+}
@@ -0,0 +1,137 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Defaults of the kubeconfig template, matching the CRD defaults for objects created before the fields existed
+const (
+	defaultKubeconfigKey  = "kubeconfig"
+	defaultKubeconfigName = "default"
+)
+
+// ApplyKubeconfig writes the kubeconfig assembled from the BitwardenSecret's kubeconfig template to the secret.  It
+// does nothing when no template is set.
+func ApplyKubeconfig(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte, secret *corev1.Secret) error {
+	template := bwSecret.Spec.Kubeconfig
+	if template == nil {
+		return nil
+	}
+
+	kubeconfig, err := RenderKubeconfig(template, secrets)
+	if err != nil {
+		return err
+	}
+
+	key := template.Key
+	if key == "" {
+		key = defaultKubeconfigKey
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[key] = kubeconfig
+
+	return nil
+}
+
+// RenderKubeconfig assembles a kubeconfig with a single cluster, user, and context from the pulled secrets.
+func RenderKubeconfig(template *operatorsv1.KubeconfigTemplate, secrets map[string][]byte) ([]byte, error) {
+	name := template.Name
+	if name == "" {
+		name = defaultKubeconfigName
+	}
+
+	server, err := kubeconfigValue(secrets, template.ServerSecretId, "server")
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := kubeconfigValue(secrets, template.TokenSecretId, "token")
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = strings.TrimSpace(string(server))
+
+	if template.CertificateAuthoritySecretId != "" {
+		ca, err := kubeconfigValue(secrets, template.CertificateAuthoritySecretId, "certificate authority")
+		if err != nil {
+			return nil, err
+		}
+		cluster.CertificateAuthorityData = decodeCertificateAuthority(ca)
+	}
+
+	user := clientcmdapi.NewAuthInfo()
+	user.Token = strings.TrimSpace(string(token))
+
+	kubeContext := clientcmdapi.NewContext()
+	kubeContext.Cluster = name
+	kubeContext.AuthInfo = name
+	kubeContext.Namespace = template.Namespace
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = cluster
+	config.AuthInfos[name] = user
+	config.Contexts[name] = kubeContext
+	config.CurrentContext = name
+
+	return clientcmd.Write(*config)
+}
+
+func kubeconfigValue(secrets map[string][]byte, id string, field string) ([]byte, error) {
+	if id == "" {
+		return nil, fmt.Errorf("the kubeconfig %s secret ID is not set", field)
+	}
+
+	value, ok := secrets[id]
+	if !ok {
+		return nil, fmt.Errorf("the kubeconfig %s secret %s is not accessible by the machine account", field, id)
+	}
+
+	return value, nil
+}
+
+// decodeCertificateAuthority accepts a PEM certificate as well as the base64 encoded form found in kubeconfig files.
+func decodeCertificateAuthority(value []byte) []byte {
+	trimmed := strings.TrimSpace(string(value))
+	if strings.HasPrefix(trimmed, "-----BEGIN") {
+		return []byte(trimmed + "\n")
+	}
+
+	if decoded, err := base64.StdEncoding.DecodeString(trimmed); err == nil {
+		return decoded
+	}
+
+	return value
+}
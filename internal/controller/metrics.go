@@ -0,0 +1,205 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+var (
+	clientResetsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "bitwarden",
+		Name:      "client_resets_total",
+		Help:      "Number of cached Bitwarden clients that were disposed and rebuilt after repeated failures or a panic.",
+	})
+
+	sessionReusesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "bitwarden",
+		Name:      "session_reuses_total",
+		Help:      "Number of syncs that reused an authenticated Bitwarden session instead of logging in again.",
+	})
+
+	secretUpdatesSkippedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "bitwarden",
+		Name:      "secret_updates_skipped_total",
+		Help:      "Number of syncs that left the Kubernetes secret alone because its content was already up to date.",
+	})
+
+	endpointFailoversTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "bitwarden",
+		Name:      "endpoint_failovers_total",
+		Help:      "Number of times clients failed over from the primary to the secondary Bitwarden endpoint.",
+	})
+
+	endpointFailoverActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "bitwarden",
+		Name:      "endpoint_failover_active",
+		Help:      "1 while clients use the secondary Bitwarden endpoint because the primary is unreachable, otherwise 0.",
+	})
+
+	circuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "bitwarden",
+		Name:      "api_circuit_breaker_state",
+		Help:      "State of the circuit breaker of a Bitwarden API endpoint: 0 while closed, 1 while open and calls are paused, 2 while a call probes the API.",
+	}, []string{"endpoint"})
+
+	circuitBreakerTripsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bitwarden",
+		Name:      "api_circuit_breaker_trips_total",
+		Help:      "Number of times the circuit breaker of a Bitwarden API endpoint opened after repeated failed calls.",
+	}, []string{"endpoint"})
+
+	clientCreateDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "bitwarden",
+		Name:      "client_create_duration_seconds",
+		Help:      "Time taken to create a Bitwarden client.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	loginDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "bitwarden",
+		Name:      "access_token_login_duration_seconds",
+		Help:      "Time taken by AccessTokenLogin calls that reached the identity endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	syncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "bitwarden",
+		Name:      "secrets_sync_duration_seconds",
+		Help:      "Time taken by Secrets().Sync calls to the Secrets Manager API.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	secretWriteDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "bitwarden",
+		Name:      "k8s_secret_write_duration_seconds",
+		Help:      "Time taken to read, create, and update the Kubernetes secret after a sync reported changes.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	rateLimitWaitDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "bitwarden",
+		Name:      "api_rate_limit_wait_duration_seconds",
+		Help:      "Time Bitwarden client calls waited for the --api-qps rate limiter.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	sdkTimeoutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bitwarden",
+		Name:      "sdk_timeouts_total",
+		Help:      "Number of AccessTokenLogin and Secrets().Sync calls abandoned because they exceeded the timeout of the BitwardenSecret.",
+	}, []string{"call"})
+
+	lastSuccessfulSyncTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "bitwarden",
+		Name:      "secret_last_successful_sync_timestamp_seconds",
+		Help:      "Unix time of the last successful sync of a BitwardenSecret.",
+	}, []string{"namespace", "name"})
+
+	lastFailedSyncTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "bitwarden",
+		Name:      "secret_last_failed_sync_timestamp_seconds",
+		Help:      "Unix time of the last failed sync of a BitwardenSecret.",
+	}, []string{"namespace", "name"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		clientResetsTotal,
+		sessionReusesTotal,
+		secretUpdatesSkippedTotal,
+		endpointFailoversTotal,
+		endpointFailoverActive,
+		circuitBreakerState,
+		circuitBreakerTripsTotal,
+		clientCreateDuration,
+		loginDuration,
+		syncDuration,
+		secretWriteDuration,
+		rateLimitWaitDuration,
+		sdkTimeoutsTotal,
+		lastSuccessfulSyncTimestamp,
+		lastFailedSyncTimestamp,
+	)
+}
+
+func observeDuration(histogram prometheus.Histogram, start time.Time) {
+	histogram.Observe(time.Since(start).Seconds())
+}
+
+// recordSyncTimestamp sets the last successful or failed sync timestamp of the BitwardenSecret to t.
+func recordSyncTimestamp(namespace string, name string, succeeded bool, t time.Time) {
+	gauge := lastFailedSyncTimestamp
+	if succeeded {
+		gauge = lastSuccessfulSyncTimestamp
+	}
+	gauge.WithLabelValues(namespace, name).Set(float64(t.Unix()))
+}
+
+// restoreSyncTimestamps sets the sync timestamps of the BitwardenSecret from its status, so that they survive restarts
+// of the operator.
+func restoreSyncTimestamps(bwSecret *operatorsv1.BitwardenSecret) {
+	if !bwSecret.Status.LastSuccessfulSyncTime.IsZero() {
+		recordSyncTimestamp(bwSecret.Namespace, bwSecret.Name, true, bwSecret.Status.LastSuccessfulSyncTime.Time)
+	}
+
+	for i := len(bwSecret.Status.History) - 1; i >= 0; i-- {
+		if attempt := bwSecret.Status.History[i]; attempt.Result == "Failed" {
+			recordSyncTimestamp(bwSecret.Namespace, bwSecret.Name, false, attempt.Time.Time)
+			break
+		}
+	}
+}
+
+// forgetSyncTimestamps removes the sync timestamps of a deleted BitwardenSecret.
+func forgetSyncTimestamps(namespace string, name string) {
+	lastSuccessfulSyncTimestamp.DeleteLabelValues(namespace, name)
+	lastFailedSyncTimestamp.DeleteLabelValues(namespace, name)
+}
+
+// instrumentedClient records the latency of the calls that reach Bitwarden.
+type instrumentedClient struct {
+	bwclient.BitwardenClientInterface
+}
+
+func (c *instrumentedClient) AccessTokenLogin(accessToken string, stateFile *string) error {
+	defer observeDuration(loginDuration, time.Now())
+	return c.BitwardenClientInterface.AccessTokenLogin(accessToken, stateFile)
+}
+
+func (c *instrumentedClient) Secrets() bwclient.SecretsInterface {
+	return &instrumentedSecrets{SecretsInterface: c.BitwardenClientInterface.Secrets()}
+}
+
+type instrumentedSecrets struct {
+	bwclient.SecretsInterface
+}
+
+func (s *instrumentedSecrets) Sync(organizationID string, lastSyncedDate *time.Time) (*bwclient.SecretsSyncResponse, error) {
+	defer observeDuration(syncDuration, time.Now())
+	return s.SecretsInterface.Sync(organizationID, lastSyncedDate)
+}
@@ -0,0 +1,116 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Condition set while the namespace of a BitwardenSecret is not allowed to sync by the namespace policy
+const DeniedCondition = "Denied"
+
+// NamespacePolicy decides in which namespaces BitwardenSecrets are synced.  Namespaces are matched against shell
+// patterns such as "team-*".
+type NamespacePolicy struct {
+	// Namespaces BitwardenSecrets may sync in.  Every namespace when empty.
+	Allowed []string
+	// Namespaces BitwardenSecrets never sync in, even when allowed
+	Denied []string
+}
+
+// ParseNamespacePolicy returns the policy of the comma separated allowed and denied namespace patterns.  It returns
+// nil when neither is set.
+func ParseNamespacePolicy(allowed string, denied string) (*NamespacePolicy, error) {
+	policy := &NamespacePolicy{}
+
+	var err error
+	if policy.Allowed, err = parseNamespacePatterns(allowed); err != nil {
+		return nil, err
+	}
+	if policy.Denied, err = parseNamespacePatterns(denied); err != nil {
+		return nil, err
+	}
+
+	if len(policy.Allowed) == 0 && len(policy.Denied) == 0 {
+		return nil, nil
+	}
+
+	return policy, nil
+}
+
+func parseNamespacePatterns(list string) ([]string, error) {
+	patterns := []string{}
+	for _, pattern := range strings.Split(list, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// Allows reports whether BitwardenSecrets in the namespace may sync.  A nil policy allows every namespace.
+func (p *NamespacePolicy) Allows(namespace string) bool {
+	if p == nil {
+		return true
+	}
+
+	if matchesNamespace(p.Denied, namespace) {
+		return false
+	}
+
+	return len(p.Allowed) == 0 || matchesNamespace(p.Allowed, namespace)
+}
+
+func matchesNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetDeniedCondition marks the BitwardenSecret as refused by the namespace policy.
+func SetDeniedCondition(bwSecret *operatorsv1.BitwardenSecret) {
+	message := fmt.Sprintf("BitwardenSecrets in the namespace %s are not allowed to sync by the operator's namespace policy", bwSecret.Namespace)
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "NamespaceNotAllowed",
+		Message: message,
+		Type:    DeniedCondition,
+	})
+	SetStalledCondition(&bwSecret.Status.Conditions, bwSecret.Generation, "NamespaceNotAllowed", message)
+}
@@ -0,0 +1,127 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// Condition set while secrets referenced by spec.map belong to another organization than spec.organizationId
+const OrganizationMismatchCondition = "OrganizationMismatch"
+
+// ForeignSecret is a secret that belongs to another organization than the one being synced.
+type ForeignSecret struct {
+	ID             string
+	OrganizationID string
+}
+
+// MappedSecretIDs returns the IDs of the secrets referenced by the map entries, in order and without duplicates.
+func MappedSecretIDs(secretMap []operatorsv1.SecretMap) []string {
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, m := range secretMap {
+		if !seen[m.BwSecretId] {
+			seen[m.BwSecretId] = true
+			ids = append(ids, m.BwSecretId)
+		}
+	}
+
+	return ids
+}
+
+// sameOrganization compares organization IDs case insensitively.  An unknown organization matches every other.
+func sameOrganization(a string, b string) bool {
+	return a == "" || b == "" || strings.EqualFold(a, b)
+}
+
+// FilterForeignSecrets drops the secrets that belong to another organization than orgId, so that a BitwardenSecret
+// never syncs secrets of an organization it does not name.  The second returned value lists the dropped secrets.
+func FilterForeignSecrets(secrets []bwclient.SecretResponse, orgId string) ([]bwclient.SecretResponse, []ForeignSecret) {
+	kept := make([]bwclient.SecretResponse, 0, len(secrets))
+	foreign := []ForeignSecret{}
+	for _, secret := range secrets {
+		if sameOrganization(secret.OrganizationID, orgId) {
+			kept = append(kept, secret)
+		} else {
+			foreign = append(foreign, ForeignSecret{ID: secret.ID, OrganizationID: secret.OrganizationID})
+		}
+	}
+
+	return kept, foreign
+}
+
+// findForeignMappedSecrets looks up the mapped secrets missing from a sync of orgId and returns those the machine
+// account can read in another organization.  Secrets that cannot be read at all are left to the map entries to
+// report.
+func findForeignMappedSecrets(ctx context.Context, bitwardenClient bwclient.BitwardenClientInterface, orgId string, mappedIDs []string, pulled map[string][]byte) []ForeignSecret {
+	foreign := []ForeignSecret{}
+	for _, id := range mappedIDs {
+		if _, ok := pulled[id]; ok {
+			continue
+		}
+
+		// The check is best effort and stops once the sync is interrupted
+		if ctx.Err() != nil {
+			break
+		}
+
+		secret, err := bitwardenClient.Secrets().Get(id)
+		if err != nil || secret == nil {
+			continue
+		}
+
+		if !sameOrganization(secret.OrganizationID, orgId) {
+			foreign = append(foreign, ForeignSecret{ID: id, OrganizationID: secret.OrganizationID})
+		}
+	}
+
+	return foreign
+}
+
+// SetOrganizationMismatchCondition sets or clears the condition naming the referenced secrets of other organizations.
+func SetOrganizationMismatchCondition(conditions *[]metav1.Condition, orgId string, foreign []ForeignSecret) {
+	if len(foreign) == 0 {
+		apimeta.RemoveStatusCondition(conditions, OrganizationMismatchCondition)
+		return
+	}
+
+	descriptions := make([]string, 0, len(foreign))
+	for _, secret := range foreign {
+		descriptions = append(descriptions, fmt.Sprintf("%s (organization %s)", secret.ID, secret.OrganizationID))
+	}
+	sort.Strings(descriptions)
+
+	apimeta.SetStatusCondition(conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "CrossOrganizationSecrets",
+		Message: fmt.Sprintf("Secrets that do not belong to the organization %s were not synced: %s", orgId, strings.Join(descriptions, ", ")),
+		Type:    OrganizationMismatchCondition,
+	})
+}
@@ -0,0 +1,132 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// The keys spec.output writes to when none is set
+const (
+	DefaultDotenvKey     = ".env"
+	DefaultPropertiesKey = "application.properties"
+)
+
+// Environment variable names, which are the only keys a dotenv file can hold
+var dotenvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// OutputKey returns the key spec.output writes the rendered file to.
+func OutputKey(output *operatorsv1.SecretOutput) string {
+	if output.Key != "" {
+		return output.Key
+	}
+	if output.Format == operatorsv1.OutputFormatProperties {
+		return DefaultPropertiesKey
+	}
+
+	return DefaultDotenvKey
+}
+
+// ApplyOutput replaces the keys of the secret with a single file in the format of spec.output.  It does nothing when
+// no output is set.
+func ApplyOutput(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret) error {
+	output := bwSecret.Spec.Output
+	if output == nil {
+		return nil
+	}
+
+	rendered, err := RenderOutput(output.Format, secret.Data)
+	if err != nil {
+		return err
+	}
+
+	secret.Data = map[string][]byte{OutputKey(output): rendered}
+	return nil
+}
+
+// RenderOutput renders the data as one file in the format, sorted by key so that the file only changes when a value
+// does.
+func RenderOutput(format operatorsv1.OutputFormat, data map[string][]byte) ([]byte, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out strings.Builder
+	for _, key := range keys {
+		switch format {
+		case operatorsv1.OutputFormatDotenv:
+			if !dotenvKeyPattern.MatchString(key) {
+				return nil, fmt.Errorf("%s is not a valid environment variable name", key)
+			}
+			fmt.Fprintf(&out, "%s=\"%s\"\n", key, escapeDotenv(string(data[key])))
+		case operatorsv1.OutputFormatProperties:
+			fmt.Fprintf(&out, "%s=%s\n", escapeProperties(key, true), escapeProperties(string(data[key]), false))
+		default:
+			return nil, fmt.Errorf("unknown output format %q", format)
+		}
+	}
+
+	return []byte(out.String()), nil
+}
+
+// escapeDotenv escapes a value for a double quoted dotenv value, so that it is neither expanded nor cut short.
+func escapeDotenv(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
+
+// escapeProperties escapes a key or value as java.util.Properties reads it.  Properties files are ISO 8859-1, so
+// everything outside of printable ASCII is written as a unicode escape.
+func escapeProperties(value string, isKey bool) string {
+	var out strings.Builder
+	for i, r := range value {
+		switch {
+		case r == '\\' || r == '=' || r == ':' || r == '#' || r == '!':
+			out.WriteRune('\\')
+			out.WriteRune(r)
+		case r == ' ' && (isKey || i == 0):
+			out.WriteString(`\ `)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&out, `\u%04x`, unit)
+			}
+		default:
+			out.WriteRune(r)
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,123 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Condition of BitwardenSecrets with secret map entries pinned to a revision that can no longer be written
+const PinnedRevisionUnavailableCondition = "PinnedRevisionUnavailable"
+
+// ParseRevisionsAnnotation returns the revisions recorded on the secret by SetRevisionsAnnotation.  A missing or
+// unreadable annotation records no revisions.
+func ParseRevisionsAnnotation(secret *corev1.Secret) map[string]SecretRevision {
+	revisions := map[string]SecretRevision{}
+	if secret == nil || secret.Annotations[RevisionsAnnotation] == "" {
+		return revisions
+	}
+
+	if err := json.Unmarshal([]byte(secret.Annotations[RevisionsAnnotation]), &revisions); err != nil {
+		return map[string]SecretRevision{}
+	}
+	return revisions
+}
+
+// sameRevision compares revision dates as points in time, so that a pin copied in another RFC 3339 notation still
+// matches.
+func sameRevision(a string, b string) bool {
+	timeA, errA := time.Parse(time.RFC3339Nano, a)
+	timeB, errB := time.Parse(time.RFC3339Nano, b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return timeA.Equal(timeB)
+}
+
+// ApplyPinnedRevisions holds back the keys of secret map entries pinned to a revision other than the pulled one.  Those
+// keys keep the value and revision they had in the Kubernetes secret before the sync, and are left out when they had
+// none.  The revisions of the data are updated to match, and the IDs of the secrets whose pinned revision is neither
+// pulled nor held by the Kubernetes secret any more are returned.
+func ApplyPinnedRevisions(bwSecret *operatorsv1.BitwardenSecret, revisionDates map[string]string, previousData map[string][]byte, previousRevisions map[string]SecretRevision, data map[string][]byte, revisions map[string]SecretRevision) []string {
+	unavailable := []string{}
+
+	for _, m := range bwSecret.Spec.SecretMap {
+		// Values of the ConfigMap hold plain configuration and are not pinned
+		if m.Revision == "" || !IsSensitive(m) {
+			continue
+		}
+
+		if revisionDate, ok := revisionDates[m.BwSecretId]; ok && sameRevision(revisionDate, m.Revision) {
+			continue
+		}
+
+		held := false
+		for _, key := range MappedKeys(m) {
+			key = TransformKey(bwSecret, key)
+			delete(data, key)
+			delete(revisions, key)
+
+			value, ok := previousData[key]
+			if !ok {
+				continue
+			}
+			data[key] = value
+
+			if revision, ok := previousRevisions[key]; ok {
+				revisions[key] = revision
+				held = held || (revision.ID == m.BwSecretId && sameRevision(revision.RevisionDate, m.Revision))
+			}
+		}
+
+		if !held && !slices.Contains(unavailable, m.BwSecretId) {
+			unavailable = append(unavailable, m.BwSecretId)
+		}
+	}
+
+	slices.Sort(unavailable)
+	return unavailable
+}
+
+// SetPinnedRevisionUnavailableCondition reports the secrets whose pinned revision can no longer be written, or removes
+// the condition when there are none.
+func SetPinnedRevisionUnavailableCondition(bwSecret *operatorsv1.BitwardenSecret, unavailable []string) {
+	if len(unavailable) == 0 {
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, PinnedRevisionUnavailableCondition)
+		return
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "RevisionNotFound",
+		Message: fmt.Sprintf("The pinned revisions of these secrets no longer exist and their keys keep the values they had: %s", strings.Join(unavailable, ", ")),
+		Type:    PinnedRevisionUnavailableCondition,
+	})
+}
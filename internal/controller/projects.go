@@ -0,0 +1,87 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// Condition set while a project referenced by spec.projects holds no secrets the machine account can access
+const EmptyProjectCondition = "ProjectWithoutSecrets"
+
+// FilterSecretsByProjects keeps the secrets that belong to one of the referenced projects.  A reference matches a
+// project by ID or by name.  The second returned value lists the references that matched no accessible secret.
+func FilterSecretsByProjects(secrets []bwclient.SecretResponse, projects []bwclient.ProjectResponse, references []string) ([]bwclient.SecretResponse, []string) {
+	referencedBy := map[string][]string{}
+	for _, reference := range references {
+		for _, project := range projects {
+			if project.ID == reference || project.Name == reference {
+				referencedBy[project.ID] = append(referencedBy[project.ID], reference)
+			}
+		}
+	}
+
+	matched := map[string]bool{}
+	filtered := []bwclient.SecretResponse{}
+	for _, secret := range secrets {
+		if secret.ProjectID == nil {
+			continue
+		}
+
+		if matching, ok := referencedBy[*secret.ProjectID]; ok {
+			filtered = append(filtered, secret)
+			for _, reference := range matching {
+				matched[reference] = true
+			}
+		}
+	}
+
+	empty := []string{}
+	for _, reference := range references {
+		if !matched[reference] {
+			empty = append(empty, reference)
+		}
+	}
+
+	return filtered, empty
+}
+
+// SetEmptyProjectCondition sets or clears the condition naming the referenced projects without accessible secrets.
+func SetEmptyProjectCondition(bwSecret *operatorsv1.BitwardenSecret, empty []string) {
+	if len(empty) == 0 {
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, EmptyProjectCondition)
+		return
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "NoAccessibleSecrets",
+		Message: fmt.Sprintf("No secrets the machine account can access belong to the projects: %s", strings.Join(empty, ", ")),
+		Type:    EmptyProjectCondition,
+	})
+}
@@ -0,0 +1,63 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// ExtractProperty returns the property of a JSON secret value selected by a JSONPath expression, such as user,
+// db.password, or hosts[0].  The braces and leading dot of kubectl style expressions are optional.  Strings are
+// returned as they are and other values as JSON.
+func ExtractProperty(value []byte, property string) ([]byte, error) {
+	var document interface{}
+	if err := json.Unmarshal(value, &document); err != nil {
+		return nil, fmt.Errorf("the value is not JSON: %w", err)
+	}
+
+	expression := strings.TrimSuffix(strings.TrimPrefix(property, "{"), "}")
+	expression = "{." + strings.TrimPrefix(expression, ".") + "}"
+
+	path := jsonpath.New("property")
+	if err := path.Parse(expression); err != nil {
+		return nil, fmt.Errorf("invalid property %q: %w", property, err)
+	}
+
+	results, err := path.FindResults(document)
+	if err != nil {
+		return nil, fmt.Errorf("property %q: %w", property, err)
+	}
+
+	if len(results) != 1 || len(results[0]) != 1 {
+		return nil, fmt.Errorf("property %q must select exactly one value", property)
+	}
+
+	selected := results[0][0].Interface()
+	if text, ok := selected.(string); ok {
+		return []byte(text), nil
+	}
+
+	return json.Marshal(selected)
+}
@@ -0,0 +1,83 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+)
+
+// PullWorkerPool runs Secrets Manager pulls on a fixed number of workers, independent of the number of controller
+// workers.  This bounds how many native clients are in use at once while still letting reconciles of unrelated
+// BitwardenSecrets progress in parallel.
+type PullWorkerPool struct {
+	size int
+	jobs chan func()
+}
+
+func NewPullWorkerPool(size int) *PullWorkerPool {
+	return &PullWorkerPool{
+		size: size,
+		jobs: make(chan func()),
+	}
+}
+
+// Start runs the workers until the context is cancelled.  It implements manager.Runnable.
+func (p *PullWorkerPool) Start(ctx context.Context) error {
+	if p.size < 1 {
+		return fmt.Errorf("pull worker pool size must be at least 1, got %d", p.size)
+	}
+
+	for i := 0; i < p.size; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-p.jobs:
+					job()
+				}
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// Run executes fn on the next free worker and waits for it to finish.  It returns an error without running fn if
+// the context is cancelled before a worker becomes available.
+func (p *PullWorkerPool) Run(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+	job := func() {
+		defer close(done)
+		fn()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case p.jobs <- job:
+	}
+
+	<-done
+	return nil
+}
@@ -0,0 +1,175 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// Shared by every client the operator creates, so the request rate stays within the Secrets Manager limits no matter
+// how many BitwardenSecrets sync at the same time.  Nil while rate limiting is disabled.
+var apiLimiter *rate.Limiter
+
+// SetAPIRateLimit limits the calls of all Bitwarden clients created afterwards to qps calls per second, allowing
+// bursts of up to burst calls.  A qps of zero or less removes the limit.
+func SetAPIRateLimit(qps float64, burst int) {
+	if qps <= 0 {
+		apiLimiter = nil
+		return
+	}
+
+	apiLimiter = rate.NewLimiter(rate.Limit(qps), burst)
+}
+
+// rateLimitedClient waits for the API rate limiter before every call that reaches Bitwarden.
+type rateLimitedClient struct {
+	bwclient.BitwardenClientInterface
+	limiter *rate.Limiter
+}
+
+func newRateLimitedClient(inner bwclient.BitwardenClientInterface, limiter *rate.Limiter) bwclient.BitwardenClientInterface {
+	if limiter == nil {
+		return inner
+	}
+
+	return &rateLimitedClient{BitwardenClientInterface: inner, limiter: limiter}
+}
+
+// wait blocks until the limiter allows the next call.  The client interface carries no context, so calls cannot be
+// cancelled while they wait.
+func wait(limiter *rate.Limiter) error {
+	defer observeDuration(rateLimitWaitDuration, time.Now())
+	return limiter.Wait(context.Background())
+}
+
+func (c *rateLimitedClient) AccessTokenLogin(accessToken string, stateFile *string) error {
+	if err := wait(c.limiter); err != nil {
+		return err
+	}
+	return c.BitwardenClientInterface.AccessTokenLogin(accessToken, stateFile)
+}
+
+func (c *rateLimitedClient) Secrets() bwclient.SecretsInterface {
+	return &rateLimitedSecrets{SecretsInterface: c.BitwardenClientInterface.Secrets(), limiter: c.limiter}
+}
+
+func (c *rateLimitedClient) Projects() bwclient.ProjectsInterface {
+	return &rateLimitedProjects{ProjectsInterface: c.BitwardenClientInterface.Projects(), limiter: c.limiter}
+}
+
+type rateLimitedSecrets struct {
+	bwclient.SecretsInterface
+	limiter *rate.Limiter
+}
+
+func (s *rateLimitedSecrets) Create(key, value, note string, organizationID string, projectIDs []string) (*bwclient.SecretResponse, error) {
+	if err := wait(s.limiter); err != nil {
+		return nil, err
+	}
+	return s.SecretsInterface.Create(key, value, note, organizationID, projectIDs)
+}
+
+func (s *rateLimitedSecrets) List(organizationID string) (*bwclient.SecretIdentifiersResponse, error) {
+	if err := wait(s.limiter); err != nil {
+		return nil, err
+	}
+	return s.SecretsInterface.List(organizationID)
+}
+
+func (s *rateLimitedSecrets) Get(secretID string) (*bwclient.SecretResponse, error) {
+	if err := wait(s.limiter); err != nil {
+		return nil, err
+	}
+	return s.SecretsInterface.Get(secretID)
+}
+
+func (s *rateLimitedSecrets) GetByIDS(secretIDs []string) (*bwclient.SecretsResponse, error) {
+	if err := wait(s.limiter); err != nil {
+		return nil, err
+	}
+	return s.SecretsInterface.GetByIDS(secretIDs)
+}
+
+func (s *rateLimitedSecrets) Update(secretID string, key, value, note string, organizationID string, projectIDs []string) (*bwclient.SecretResponse, error) {
+	if err := wait(s.limiter); err != nil {
+		return nil, err
+	}
+	return s.SecretsInterface.Update(secretID, key, value, note, organizationID, projectIDs)
+}
+
+func (s *rateLimitedSecrets) Delete(secretIDs []string) (*bwclient.SecretsDeleteResponse, error) {
+	if err := wait(s.limiter); err != nil {
+		return nil, err
+	}
+	return s.SecretsInterface.Delete(secretIDs)
+}
+
+func (s *rateLimitedSecrets) Sync(organizationID string, lastSyncedDate *time.Time) (*bwclient.SecretsSyncResponse, error) {
+	if err := wait(s.limiter); err != nil {
+		return nil, err
+	}
+	return s.SecretsInterface.Sync(organizationID, lastSyncedDate)
+}
+
+type rateLimitedProjects struct {
+	bwclient.ProjectsInterface
+	limiter *rate.Limiter
+}
+
+func (p *rateLimitedProjects) Create(organizationID string, name string) (*bwclient.ProjectResponse, error) {
+	if err := wait(p.limiter); err != nil {
+		return nil, err
+	}
+	return p.ProjectsInterface.Create(organizationID, name)
+}
+
+func (p *rateLimitedProjects) List(organizationID string) (*bwclient.ProjectsResponse, error) {
+	if err := wait(p.limiter); err != nil {
+		return nil, err
+	}
+	return p.ProjectsInterface.List(organizationID)
+}
+
+func (p *rateLimitedProjects) Get(projectID string) (*bwclient.ProjectResponse, error) {
+	if err := wait(p.limiter); err != nil {
+		return nil, err
+	}
+	return p.ProjectsInterface.Get(projectID)
+}
+
+func (p *rateLimitedProjects) Update(projectID string, organizationID string, name string) (*bwclient.ProjectResponse, error) {
+	if err := wait(p.limiter); err != nil {
+		return nil, err
+	}
+	return p.ProjectsInterface.Update(projectID, organizationID, name)
+}
+
+func (p *rateLimitedProjects) Delete(projectIDs []string) (*bwclient.ProjectsDeleteResponse, error) {
+	if err := wait(p.limiter); err != nil {
+		return nil, err
+	}
+	return p.ProjectsInterface.Delete(projectIDs)
+}
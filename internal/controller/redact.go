@@ -0,0 +1,226 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// Text that replaces secret values and tokens in redacted messages
+const Redacted = "[REDACTED]"
+
+// Values shorter than this are not redacted, since values such as true or a port number would mask ordinary words and
+// numbers of the message
+const minRedactedLength = 6
+
+// Machine account access tokens, 0.<access token ID>.<client secret>:<encryption key>, even where the token is not known
+var accessTokenPattern = regexp.MustCompile(`0\.[0-9a-fA-F-]{36}\.[0-9A-Za-z]+:[0-9A-Za-z+/=]+`)
+
+// Redactor scrubs known sensitive values, such as the auth token and the pulled secret values, and anything that looks
+// like an access token from messages before they are written to logs, events, or the status.  A nil Redactor only
+// scrubs access tokens.
+type Redactor struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+func NewRedactor(values ...string) *Redactor {
+	r := &Redactor{}
+	r.Add(values...)
+	return r
+}
+
+// Add registers sensitive values to be scrubbed.
+func (r *Redactor) Add(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, value := range values {
+		if len(value) >= minRedactedLength {
+			r.values = append(r.values, value)
+		}
+	}
+
+	// Longer values first, so that a value containing another is scrubbed as a whole
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+}
+
+// AddSecrets registers the values of pulled secrets.
+func (r *Redactor) AddSecrets(secrets map[string][]byte) {
+	values := make([]string, 0, len(secrets))
+	for _, value := range secrets {
+		values = append(values, string(value))
+	}
+	r.Add(values...)
+}
+
+// Redact returns s with every sensitive value replaced by Redacted.
+func (r *Redactor) Redact(s string) string {
+	if r != nil {
+		r.mu.RLock()
+		for _, value := range r.values {
+			s = strings.ReplaceAll(s, value, Redacted)
+		}
+		r.mu.RUnlock()
+	}
+
+	return accessTokenPattern.ReplaceAllString(s, Redacted)
+}
+
+// RedactError returns err with a redacted message.  The original error stays available to errors.Is and errors.As.
+func (r *Redactor) RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	message := r.Redact(err.Error())
+	if message == err.Error() {
+		return err
+	}
+
+	return &redactedError{err: err, message: message}
+}
+
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+type redactorKey struct{}
+
+// withRedactor returns a context carrying the redactor of the current reconcile.
+func withRedactor(ctx context.Context, r *Redactor) context.Context {
+	return context.WithValue(ctx, redactorKey{}, r)
+}
+
+// redactorFrom returns the redactor of the current reconcile, or nil if there is none.
+func redactorFrom(ctx context.Context) *Redactor {
+	r, _ := ctx.Value(redactorKey{}).(*Redactor)
+	return r
+}
+
+// zeroSecrets overwrites the pulled secret values once they have been written, so that they do not linger in memory
+// until the garbage collector reuses it.
+func zeroSecrets(secrets map[string][]byte) {
+	for _, value := range secrets {
+		for i := range value {
+			value[i] = 0
+		}
+	}
+}
+
+// redactingClient scrubs the access token it logged in with from the errors of the SDK.
+type redactingClient struct {
+	bwclient.BitwardenClientInterface
+	redactor *Redactor
+}
+
+func newRedactingClient(inner bwclient.BitwardenClientInterface) bwclient.BitwardenClientInterface {
+	return &redactingClient{BitwardenClientInterface: inner, redactor: NewRedactor()}
+}
+
+func (c *redactingClient) AccessTokenLogin(accessToken string, stateFile *string) error {
+	c.redactor.Add(accessToken)
+	return c.redactor.RedactError(c.BitwardenClientInterface.AccessTokenLogin(accessToken, stateFile))
+}
+
+func (c *redactingClient) Secrets() bwclient.SecretsInterface {
+	return &redactingSecrets{SecretsInterface: c.BitwardenClientInterface.Secrets(), redactor: c.redactor}
+}
+
+func (c *redactingClient) Projects() bwclient.ProjectsInterface {
+	return &redactingProjects{ProjectsInterface: c.BitwardenClientInterface.Projects(), redactor: c.redactor}
+}
+
+type redactingSecrets struct {
+	bwclient.SecretsInterface
+	redactor *Redactor
+}
+
+func (s *redactingSecrets) Sync(organizationID string, lastSyncedDate *time.Time) (*bwclient.SecretsSyncResponse, error) {
+	res, err := s.SecretsInterface.Sync(organizationID, lastSyncedDate)
+	return res, s.redactor.RedactError(err)
+}
+
+type redactingProjects struct {
+	bwclient.ProjectsInterface
+	redactor *Redactor
+}
+
+func (p *redactingProjects) List(organizationID string) (*bwclient.ProjectsResponse, error) {
+	res, err := p.ProjectsInterface.List(organizationID)
+	return res, p.redactor.RedactError(err)
+}
+
+// RedactingClient returns a Kubernetes client that scrubs the values of the secrets it writes from the errors of the
+// writes, for example when an admission webhook echoes the rejected object.
+func RedactingClient(c client.Client) client.Client {
+	return &redactingK8sClient{Client: c}
+}
+
+type redactingK8sClient struct {
+	client.Client
+}
+
+func (c *redactingK8sClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return redactWriteError(obj, c.Client.Create(ctx, obj, opts...))
+}
+
+func (c *redactingK8sClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return redactWriteError(obj, c.Client.Update(ctx, obj, opts...))
+}
+
+func (c *redactingK8sClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return redactWriteError(obj, c.Client.Patch(ctx, obj, patch, opts...))
+}
+
+func redactWriteError(obj client.Object, err error) error {
+	secret, ok := obj.(*corev1.Secret)
+	if err == nil || !ok {
+		return err
+	}
+
+	redactor := NewRedactor()
+	redactor.AddSecrets(secret.Data)
+	for _, value := range secret.StringData {
+		redactor.Add(value)
+	}
+
+	return redactor.RedactError(err)
+}
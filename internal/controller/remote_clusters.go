@@ -0,0 +1,296 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Condition set while the targets of a cluster referenced by spec.targets[].clusterRef cannot be written
+const ClusterUnreachableCondition = "ClusterUnreachable"
+
+// Key of the kubeconfig in the secret referenced by a clusterRef without a key
+const DefaultKubeconfigKey = "kubeconfig"
+
+// Finalizer of BitwardenSecrets that wrote targets to other clusters, which deletes them there before the
+// BitwardenSecret is removed
+const RemoteTargetsFinalizer = "k8s.bitwarden.com/remote-targets"
+
+// Timeout of the requests to other clusters, so that an unreachable cluster does not hold up the sync for long
+const remoteClusterTimeout = 30 * time.Second
+
+// NewRemoteClusterClient returns a client of the cluster of the kubeconfig.  Kubeconfigs that read files or run
+// credential plugins are rejected, as they would run in the operator pod with its files and permissions.
+func NewRemoteClusterClient(kubeconfig []byte) (client.Client, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	for name, authInfo := range config.AuthInfos {
+		if authInfo.Exec != nil || authInfo.AuthProvider != nil {
+			return nil, fmt.Errorf("user %s of the kubeconfig uses a credential plugin, which is not supported", name)
+		}
+		if authInfo.TokenFile != "" || authInfo.ClientCertificate != "" || authInfo.ClientKey != "" {
+			return nil, fmt.Errorf("user %s of the kubeconfig reads its credentials from files, which must be embedded instead", name)
+		}
+	}
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("cluster %s of the kubeconfig reads its certificate authority from a file, which must be embedded instead", name)
+		}
+	}
+
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	restConfig.Timeout = remoteClusterTimeout
+
+	return client.New(restConfig, client.Options{Scheme: scheme.Scheme})
+}
+
+// NormalizeClusterRef returns the clusterRef with its defaults filled in: the kubeconfig key and the namespace of the
+// BitwardenSecret.
+func NormalizeClusterRef(bwSecret *operatorsv1.BitwardenSecret, ref operatorsv1.ClusterRef) operatorsv1.ClusterRef {
+	if ref.Key == "" {
+		ref.Key = DefaultKubeconfigKey
+	}
+	if ref.Namespace == "" {
+		ref.Namespace = bwSecret.Namespace
+	}
+
+	return ref
+}
+
+// RemoteClusterCache keeps the clients of the clusters referenced by spec.targets[].clusterRef, so that a client is
+// only created again once its kubeconfig secret changes.
+type RemoteClusterCache struct {
+	mu      sync.Mutex
+	clients map[remoteClusterKey]*remoteCluster
+}
+
+type remoteClusterKey struct {
+	namespace string
+	name      string
+	key       string
+}
+
+type remoteCluster struct {
+	resourceVersion string
+	client          client.Client
+}
+
+func NewRemoteClusterCache() *RemoteClusterCache {
+	return &RemoteClusterCache{clients: map[remoteClusterKey]*remoteCluster{}}
+}
+
+// Get returns the client of the kubeconfig under key of the secret, which is created with newClient unless one was
+// created from the same resourceVersion of the secret before.
+func (c *RemoteClusterCache) Get(kubeconfigSecret *corev1.Secret, key string, newClient func(kubeconfig []byte) (client.Client, error)) (client.Client, error) {
+	cacheKey := remoteClusterKey{namespace: kubeconfigSecret.Namespace, name: kubeconfigSecret.Name, key: key}
+
+	c.mu.Lock()
+	cached, ok := c.clients[cacheKey]
+	c.mu.Unlock()
+	if ok && cached.resourceVersion == kubeconfigSecret.ResourceVersion {
+		return cached.client, nil
+	}
+
+	remoteClient, err := newClient(kubeconfigSecret.Data[key])
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.clients[cacheKey] = &remoteCluster{resourceVersion: kubeconfigSecret.ResourceVersion, client: remoteClient}
+	c.mu.Unlock()
+
+	return remoteClient, nil
+}
+
+// clusterClient returns the client of the cluster referenced by the clusterRef.  The kubeconfig secret is read from
+// the namespace of the BitwardenSecret on every sync so that rotated credentials are picked up.
+func (r *BitwardenSecretReconciler) clusterClient(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, ref operatorsv1.ClusterRef) (client.Client, error) {
+	kubeconfigSecret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: bwSecret.Namespace, Name: ref.SecretName}, kubeconfigSecret); err != nil {
+		return nil, fmt.Errorf("failed to read the kubeconfig secret %s: %w", ref.SecretName, err)
+	}
+
+	if _, ok := kubeconfigSecret.Data[ref.Key]; !ok {
+		return nil, fmt.Errorf("the kubeconfig secret %s has no key %s", ref.SecretName, ref.Key)
+	}
+
+	newClient := r.RemoteClusterClient
+	if newClient == nil {
+		newClient = NewRemoteClusterClient
+	}
+
+	if r.RemoteClusters == nil {
+		return newClient(kubeconfigSecret.Data[ref.Key])
+	}
+	return r.RemoteClusters.Get(kubeconfigSecret, ref.Key, newClient)
+}
+
+// HasRemoteTargets reports whether the BitwardenSecret writes targets to other clusters, or still has to delete the
+// targets it wrote there.
+func HasRemoteTargets(bwSecret *operatorsv1.BitwardenSecret) bool {
+	for _, target := range bwSecret.Spec.Targets {
+		if target.ClusterRef != nil {
+			return true
+		}
+	}
+
+	return len(bwSecret.Status.Clusters) > 0
+}
+
+// DeleteRemoteTargets deletes the targets the BitwardenSecret wrote to the clusters recorded in status.clusters, since
+// owner references cannot point into another cluster.  Clusters whose kubeconfig secret is gone can no longer be
+// reached and are skipped.
+func (r *BitwardenSecretReconciler) DeleteRemoteTargets(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret) error {
+	logger := log.FromContext(ctx)
+
+	var errs []error
+	for _, status := range bwSecret.Status.Clusters {
+		c, err := r.clusterClient(ctx, bwSecret, status.ClusterRef)
+		if apierrors.IsNotFound(err) {
+			logger.Info(fmt.Sprintf("The kubeconfig secret %s/%s is gone.  Leaving the targets of %s/%s in that cluster.", bwSecret.Namespace, status.ClusterRef.SecretName, bwSecret.Namespace, bwSecret.Name))
+			continue
+		}
+		if err == nil {
+			err = pruneTargets(ctx, c, status.ClusterRef.Namespace, bwSecret, nil)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", status.ClusterRef.SecretName, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// clusterTargets are the rendered objects of the targets written to one cluster and namespace
+type clusterTargets struct {
+	ref      operatorsv1.ClusterRef
+	targets  []operatorsv1.SecretTarget
+	rendered []client.Object
+}
+
+// writeClusterTargets writes the targets of every referenced cluster and deletes the targets removed from it,
+// including from clusters that are no longer referenced.  The outcome for every cluster is recorded in
+// status.clusters and the ClusterUnreachable condition.  Clusters that cannot be reached do not stop the others.
+func (r *BitwardenSecretReconciler) writeClusterTargets(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, clusters []*clusterTargets) error {
+	previous := map[operatorsv1.ClusterRef]operatorsv1.ClusterTargetStatus{}
+	for _, status := range bwSecret.Status.Clusters {
+		previous[status.ClusterRef] = status
+	}
+
+	// Clusters no longer referenced are kept until their targets could be deleted
+	referenced := map[operatorsv1.ClusterRef]bool{}
+	for _, cluster := range clusters {
+		referenced[cluster.ref] = true
+	}
+	for _, status := range bwSecret.Status.Clusters {
+		if !referenced[status.ClusterRef] {
+			clusters = append(clusters, &clusterTargets{ref: status.ClusterRef})
+		}
+	}
+
+	var statuses []operatorsv1.ClusterTargetStatus
+	var errs []error
+	for _, cluster := range clusters {
+		status := operatorsv1.ClusterTargetStatus{ClusterRef: cluster.ref, LastSyncTime: previous[cluster.ref].LastSyncTime}
+		for _, target := range cluster.targets {
+			status.Targets = append(status.Targets, target.SecretName)
+		}
+
+		err := r.writeCluster(ctx, bwSecret, cluster)
+		if err != nil {
+			status.Message = truncate(redactorFrom(ctx).Redact(err.Error()), maxSyncHistoryReason)
+			errs = append(errs, fmt.Errorf("cluster %s: %w", cluster.ref.SecretName, err))
+		} else {
+			now := metav1.Now()
+			status.Connected = true
+			status.LastSyncTime = &now
+		}
+
+		if err != nil || referenced[cluster.ref] {
+			statuses = append(statuses, status)
+		}
+	}
+
+	bwSecret.Status.Clusters = statuses
+	SetClusterUnreachableCondition(bwSecret)
+
+	return errors.Join(errs...)
+}
+
+func (r *BitwardenSecretReconciler) writeCluster(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, cluster *clusterTargets) error {
+	c, err := r.clusterClient(ctx, bwSecret, cluster.ref)
+	if err != nil {
+		return err
+	}
+
+	if err := r.writeTargetSecretsParallel(ctx, c, false, bwSecret, cluster.rendered); err != nil {
+		return err
+	}
+
+	return pruneTargets(ctx, c, cluster.ref.Namespace, bwSecret, cluster.targets)
+}
+
+// SetClusterUnreachableCondition sets or clears the condition naming the clusters whose targets could not be written
+// by the last sync.
+func SetClusterUnreachableCondition(bwSecret *operatorsv1.BitwardenSecret) {
+	var unreachable []string
+	for _, status := range bwSecret.Status.Clusters {
+		if !status.Connected {
+			unreachable = append(unreachable, fmt.Sprintf("%s (%s)", status.ClusterRef.SecretName, status.Message))
+		}
+	}
+
+	if len(unreachable) == 0 {
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, ClusterUnreachableCondition)
+		return
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "TargetsNotWritten",
+		Message: fmt.Sprintf("Failed to write the targets of the clusters: %s", strings.Join(unreachable, ", ")),
+		Type:    ClusterUnreachableCondition,
+	})
+}
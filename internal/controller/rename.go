@@ -0,0 +1,102 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// CleanUpRenamedSecret cleans up the secret the BitwardenSecret wrote before spec.secretName changed, along with its
+// versions, and records the rename in an event.  Secrets merged into lose the keys the operator wrote, retained
+// secrets are released from the BitwardenSecret, and all others are deleted.  A secret of the previous name that is
+// no longer labeled as written by the BitwardenSecret is left alone.
+func (r *BitwardenSecretReconciler) CleanUpRenamedSecret(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret) error {
+	previous := bwSecret.Status.SecretName
+	if previous == "" || previous == bwSecret.Spec.SecretName {
+		return nil
+	}
+
+	retain := bwSecret.Spec.DeletionPolicy == operatorsv1.DeletionPolicyRetain
+	action := "deleted"
+	if MergesIntoK8sSecret(bwSecret) {
+		action = "stripped of the synced keys"
+	} else if retain {
+		action = "retained"
+	}
+
+	old := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: bwSecret.Namespace, Name: previous}, old)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if err == nil && old.Labels["k8s.bitwarden.com/bw-secret"] == string(bwSecret.UID) {
+		switch {
+		case MergesIntoK8sSecret(bwSecret):
+			for _, key := range ManagedKeys(old) {
+				delete(old.Data, key)
+			}
+			delete(old.Annotations, ManagedKeysAnnotation)
+			err = r.Update(ctx, old)
+		case retain:
+			err = r.releaseObject(ctx, bwSecret, old)
+		default:
+			uid := old.UID
+			err = r.Delete(ctx, old, client.Preconditions{UID: &uid})
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	// Versions and history snapshots are named after the previous secret
+	for _, label := range []string{VersionOfLabel, HistoryOfLabel} {
+		versions := &corev1.SecretList{}
+		err = r.List(ctx, versions, client.InNamespace(bwSecret.Namespace), client.MatchingLabels{
+			label:                         previous,
+			"k8s.bitwarden.com/bw-secret": string(bwSecret.UID),
+		})
+		if err != nil {
+			return err
+		}
+		for i := range versions.Items {
+			if retain {
+				err = r.releaseObject(ctx, bwSecret, &versions.Items[i])
+			} else {
+				err = r.Delete(ctx, &versions.Items[i])
+			}
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	recordEvent(ctx, r.Recorder, bwSecret, corev1.EventTypeNormal, SecretRenamedReason, fmt.Sprintf("Secret name changed from %s to %s, the previous secret was %s", previous, bwSecret.Spec.SecretName, action))
+	return nil
+}
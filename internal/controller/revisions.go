@@ -0,0 +1,86 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Annotation of written secrets mapping each key to the Secrets Manager secret and revision its value was written from
+const RevisionsAnnotation = "k8s.bitwarden.com/revisions"
+
+// SecretRevision identifies the revision of a Secrets Manager secret.
+type SecretRevision struct {
+	ID           string `json:"id"`
+	RevisionDate string `json:"revisionDate"`
+}
+
+// KeyRevisions returns the revision every key of the data was written from, given the revision dates of the pulled
+// secrets by ID.  Keys assembled from several secrets, such as rendered templates, have no single revision and are left
+// out.
+func KeyRevisions(bwSecret *operatorsv1.BitwardenSecret, revisionDates map[string]string, data map[string][]byte) map[string]SecretRevision {
+	revisions := map[string]SecretRevision{}
+	add := func(key string, id string) {
+		key = TransformKey(bwSecret, key)
+		if _, ok := data[key]; !ok {
+			return
+		}
+
+		if revisionDate, ok := revisionDates[id]; ok {
+			revisions[key] = SecretRevision{ID: id, RevisionDate: revisionDate}
+		}
+	}
+
+	if bwSecret.Spec.SecretMap == nil {
+		for id := range revisionDates {
+			add(id, id)
+		}
+		return revisions
+	}
+
+	for _, m := range bwSecret.Spec.SecretMap {
+		for _, key := range MappedKeys(m) {
+			add(key, m.BwSecretId)
+		}
+	}
+
+	return revisions
+}
+
+// SetRevisionsAnnotation records the revisions of the keys on the secret, as a JSON object keyed by secret key.
+func SetRevisionsAnnotation(secret *corev1.Secret, revisions map[string]SecretRevision) {
+	if len(revisions) == 0 {
+		delete(secret.Annotations, RevisionsAnnotation)
+		return
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+
+	// Marshalling a map of strings to structs of strings cannot fail.  Maps are marshalled with sorted keys.
+	bytes, _ := json.Marshal(revisions)
+	secret.Annotations[RevisionsAnnotation] = string(bytes)
+}
@@ -0,0 +1,144 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Annotation of BitwardenSecrets that enables rollout restarts like spec.rolloutRestart.enabled
+const RolloutRestartAnnotation = "k8s.bitwarden.com/rollout-restart"
+
+// Annotation of the pod templates of restarted workloads, holding the time of the restart
+const RestartedAtAnnotation = "k8s.bitwarden.com/restartedAt"
+
+// Annotation of pods the injection webhook adds the secrets of BitwardenSecrets to
+const injectAnnotation = "k8s.bitwarden.com/inject"
+
+// RolloutRestartEnabled reports whether workloads using the secret of the BitwardenSecret are restarted when its
+// data changes.
+func RolloutRestartEnabled(bwSecret *operatorsv1.BitwardenSecret) bool {
+	if bwSecret.Spec.RolloutRestart != nil && bwSecret.Spec.RolloutRestart.Enabled {
+		return true
+	}
+
+	return bwSecret.Annotations[RolloutRestartAnnotation] == "true"
+}
+
+// RestartWorkloads triggers a rollout of the Deployments and StatefulSets in the namespace of the BitwardenSecret whose
+// pods use secretName, the same way kubectl rollout restart does.  It returns the names of the restarted workloads.
+func (r *BitwardenSecretReconciler) RestartWorkloads(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, secretName string) ([]string, error) {
+	restartedAt := time.Now().UTC().Format(time.RFC3339)
+	var restarted []string
+
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(bwSecret.Namespace)); err != nil {
+		return restarted, err
+	}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		if !PodTemplateUsesSecret(&deployment.Spec.Template, bwSecret.Name, secretName) {
+			continue
+		}
+
+		if err := r.restartWorkload(ctx, deployment, &deployment.Spec.Template, restartedAt); err != nil {
+			return restarted, fmt.Errorf("deployment %s: %w", deployment.Name, err)
+		}
+		restarted = append(restarted, "deployment/"+deployment.Name)
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(bwSecret.Namespace)); err != nil {
+		return restarted, err
+	}
+	for i := range statefulSets.Items {
+		statefulSet := &statefulSets.Items[i]
+		if !PodTemplateUsesSecret(&statefulSet.Spec.Template, bwSecret.Name, secretName) {
+			continue
+		}
+
+		if err := r.restartWorkload(ctx, statefulSet, &statefulSet.Spec.Template, restartedAt); err != nil {
+			return restarted, fmt.Errorf("statefulset %s: %w", statefulSet.Name, err)
+		}
+		restarted = append(restarted, "statefulset/"+statefulSet.Name)
+	}
+
+	return restarted, nil
+}
+
+// restartWorkload annotates the pod template of the workload, which rolls out new pods.
+func (r *BitwardenSecretReconciler) restartWorkload(ctx context.Context, workload client.Object, template *corev1.PodTemplateSpec, restartedAt string) error {
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[RestartedAtAnnotation] = restartedAt
+
+	return r.Patch(ctx, workload, patch)
+}
+
+// PodTemplateUsesSecret reports whether pods of the template reference the secret through environment variables or
+// volumes, or get the secrets of the BitwardenSecret from the injection webhook.
+func PodTemplateUsesSecret(template *corev1.PodTemplateSpec, bwSecretName string, secretName string) bool {
+	for _, name := range strings.Split(template.Annotations[injectAnnotation], ",") {
+		if strings.TrimSpace(name) == bwSecretName {
+			return true
+		}
+	}
+
+	for _, volume := range template.Spec.Volumes {
+		if volume.Secret != nil && volume.Secret.SecretName == secretName {
+			return true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil && source.Secret.Name == secretName {
+					return true
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, template.Spec.InitContainers...), template.Spec.Containers...)
+	for _, container := range containers {
+		for _, source := range container.EnvFrom {
+			if source.SecretRef != nil && source.SecretRef.Name == secretName {
+				return true
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == secretName {
+				return true
+			}
+		}
+	}
+
+	return false
+}
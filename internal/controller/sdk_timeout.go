@@ -0,0 +1,144 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// Condition set while the last sync failed because a Secrets Manager call did not complete in time
+const TimedOutCondition = "TimedOut"
+
+// Default of --sdk-timeout
+const DefaultSDKTimeout = 2 * time.Minute
+
+// TimeoutError is returned when a Secrets Manager call did not complete within the timeout of the BitwardenSecret.
+type TimeoutError struct {
+	// The call that timed out, AccessTokenLogin or Secrets.Sync
+	Call    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s did not complete within %s", e.Call, e.Timeout)
+}
+
+// IsTimeoutError reports whether the error was caused by a Secrets Manager call that timed out.
+func IsTimeoutError(err error) bool {
+	var timeoutErr *TimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// InterruptedError is returned when a Secrets Manager call was abandoned because the context of the sync was done,
+// for example when the operator shuts down.
+type InterruptedError struct {
+	// The call that was interrupted
+	Call string
+	Err  error
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("%s was interrupted: %s", e.Call, e.Err.Error())
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// abandonsClient reports whether the error was returned for a call that is still running on the client, which must
+// then not be used again.
+func abandonsClient(err error) bool {
+	var interruptedErr *InterruptedError
+	return IsTimeoutError(err) || errors.As(err, &interruptedErr)
+}
+
+// SDKCallTimeout returns how long each Secrets Manager call of the BitwardenSecret may take: its own timeout when set,
+// otherwise the operator SDK timeout.  Zero waits indefinitely.
+func (r *BitwardenSecretReconciler) SDKCallTimeout(bwSecret *operatorsv1.BitwardenSecret) time.Duration {
+	if bwSecret.Spec.Timeout != nil && bwSecret.Spec.Timeout.Duration > 0 {
+		return bwSecret.Spec.Timeout.Duration
+	}
+
+	return r.SDKTimeout
+}
+
+// callWithContext runs a call of the client until it returns, the timeout passes, or the context is done, for example
+// because the operator shuts down.  A call is not started once the context is done.  The native SDK cannot interrupt
+// a call, so an abandoned call keeps running in the background and the client is closed once it returns.  The client
+// must not be used again after a TimeoutError or InterruptedError.  A timeout of zero waits for the context only.
+func callWithContext(ctx context.Context, bitwardenClient bwclient.BitwardenClientInterface, call string, timeout time.Duration, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s was not started: %w", call, err)
+	}
+
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-callCtx.Done():
+		go func() {
+			<-done
+			bitwardenClient.Close()
+		}()
+
+		if ctx.Err() != nil {
+			return &InterruptedError{Call: call, Err: ctx.Err()}
+		}
+		sdkTimeoutsTotal.WithLabelValues(call).Inc()
+		return &TimeoutError{Call: call, Timeout: timeout}
+	}
+}
+
+// SetTimedOutCondition marks the BitwardenSecret with the TimedOut condition when err is a timeout, and clears the
+// condition otherwise.
+func SetTimedOutCondition(bwSecret *operatorsv1.BitwardenSecret, err error) {
+	if !IsTimeoutError(err) {
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, TimedOutCondition)
+		return
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "SDKTimeout",
+		Message: fmt.Sprintf("%s.  The sync is retried shortly", err.Error()),
+		Type:    TimedOutCondition,
+	})
+}
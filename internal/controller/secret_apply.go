@@ -0,0 +1,161 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Annotations the operator writes on the secret of spec.secretName, including the ones it only removes
+var operatorAnnotations = []string{
+	SyncTimeAnnotation,
+	DataChecksumAnnotation,
+	ManagedKeysAnnotation,
+	RevisionsAnnotation,
+	SourceOrganizationAnnotation,
+	SourceKeysAnnotation,
+	SourceProjectsAnnotation,
+	"k8s.bitwarden.com/custom-map",
+}
+
+// ApplyK8sSecret writes the fields the operator manages on the secret of spec.secretName with a server-side apply
+// patch owned by FieldManager.  current is the secret as read before rendering and rendered is the secret to write.
+// Labels and annotations of other field managers are kept, and so are their keys when merging into the secret.  A
+// field changed by another field manager fails the write as a conflict, unless force restores a secret that was
+// modified outside of the operator.
+func (r *BitwardenSecretReconciler) ApplyK8sSecret(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, current *corev1.Secret, rendered *corev1.Secret, force bool) error {
+	applied := AppliedK8sSecret(bwSecret, rendered)
+	intended := applied.DeepCopy()
+
+	opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+	// Secrets created or last written before the operator used server-side apply hold fields of our own earlier
+	// writes, which would otherwise conflict with the first apply
+	if force || !appliedBy(current, FieldManager) {
+		opts = append(opts, client.ForceOwnership)
+	}
+
+	err := r.Patch(ctx, applied, client.Apply, opts...)
+	if apierrors.IsConflict(err) {
+		return fmt.Errorf("secret %s/%s was changed by another field manager: %w", rendered.Namespace, rendered.Name, err)
+	} else if err != nil {
+		return err
+	}
+
+	// Fields written by Create or by earlier versions of the operator are also owned by an Update entry, so leaving
+	// them out of the apply does not remove them
+	pruned := applied.DeepCopy()
+	pruneK8sSecret(bwSecret, current, intended, pruned)
+	if !equality.Semantic.DeepEqual(applied, pruned) {
+		err = r.Patch(ctx, pruned, client.MergeFromWithOptions(applied, client.MergeFromWithOptimisticLock{}), client.FieldOwner(FieldManager))
+		applied = pruned
+	}
+
+	rendered.ResourceVersion = applied.ResourceVersion
+	rendered.UID = applied.UID
+	return err
+}
+
+// AppliedK8sSecret returns the apply configuration of the secret of spec.secretName, holding only the fields the
+// operator manages.  When merging into the secret only the keys recorded in the managed keys annotation are included.
+func AppliedK8sSecret(bwSecret *operatorsv1.BitwardenSecret, rendered *corev1.Secret) *corev1.Secret {
+	applied := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        rendered.Name,
+			Namespace:   rendered.Namespace,
+			Labels:      map[string]string{"k8s.bitwarden.com/bw-secret": string(bwSecret.UID)},
+			Annotations: map[string]string{},
+		},
+		Type:      rendered.Type,
+		Immutable: rendered.Immutable,
+		Data:      rendered.Data,
+	}
+
+	if bwSecret.Spec.SecretMetadata != nil {
+		for key := range bwSecret.Spec.SecretMetadata.Labels {
+			applied.Labels[key] = rendered.Labels[key]
+		}
+		for key := range bwSecret.Spec.SecretMetadata.Annotations {
+			applied.Annotations[key] = rendered.Annotations[key]
+		}
+	}
+	for _, key := range operatorAnnotations {
+		if value, ok := rendered.Annotations[key]; ok {
+			applied.Annotations[key] = value
+		}
+	}
+
+	if MergesIntoK8sSecret(bwSecret) {
+		applied.Data = map[string][]byte{}
+		for _, key := range ManagedKeys(rendered) {
+			if value, ok := rendered.Data[key]; ok {
+				applied.Data[key] = value
+			}
+		}
+	}
+
+	if OwnsK8sSecret(bwSecret) {
+		for _, owner := range rendered.OwnerReferences {
+			if owner.UID == bwSecret.UID {
+				applied.OwnerReferences = append(applied.OwnerReferences, owner)
+			}
+		}
+	}
+
+	return applied
+}
+
+// pruneK8sSecret removes the keys and annotations the operator no longer writes from the applied secret.  Without
+// merging the operator owns every key of the secret.
+func pruneK8sSecret(bwSecret *operatorsv1.BitwardenSecret, current *corev1.Secret, intended *corev1.Secret, applied *corev1.Secret) {
+	if MergesIntoK8sSecret(bwSecret) {
+		for _, key := range ManagedKeys(current) {
+			if _, ok := intended.Data[key]; !ok {
+				delete(applied.Data, key)
+			}
+		}
+	} else {
+		for key := range applied.Data {
+			if _, ok := intended.Data[key]; !ok {
+				delete(applied.Data, key)
+			}
+		}
+	}
+
+	for _, key := range operatorAnnotations {
+		if _, ok := intended.Annotations[key]; !ok {
+			delete(applied.Annotations, key)
+		}
+	}
+
+	if !OwnsK8sSecret(bwSecret) {
+		RemoveOwnerReference(bwSecret, applied)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// PullSelection narrows down the secrets pulled from Secrets Manager.
+type PullSelection struct {
+	// The IDs or names of the projects to pull secrets from.  Every accessible secret when empty.
+	Projects []string
+	// Optional filter matched against the keys of the secrets
+	Filter *operatorsv1.SecretFilter
+	// The IDs of the secrets referenced by spec.map, which are checked to belong to the synced organization
+	MappedIDs []string
+	// The IDs of the secrets whose note is mapped, which are pulled with their note
+	NoteIDs []string
+	// Whether the keys and project names of the pulled secrets are reported
+	SourceMetadata bool
+	// How long AccessTokenLogin and Secrets().Sync may take each before the pull fails.  No limit when zero.
+	Timeout time.Duration
+}
+
+// PullReport describes how the selection applied to the pulled secrets.
+type PullReport struct {
+	// The referenced projects that hold no secret the machine account can access
+	EmptyProjects []string
+	// The number of secrets left out by the filter
+	Filtered int
+	// The pulled or referenced secrets that belong to another organization
+	ForeignSecrets []ForeignSecret
+	// The revision dates of the pulled secrets by ID
+	Revisions map[string]string
+	// The keys and project names of the pulled secrets by ID, when the selection asks for them
+	Sources map[string]SecretOrigin
+}
+
+// SelectionFor returns the selection of the secrets pulled for the BitwardenSecret.
+func SelectionFor(bwSecret *operatorsv1.BitwardenSecret) PullSelection {
+	return PullSelection{
+		Projects:       bwSecret.Spec.Projects,
+		Filter:         bwSecret.Spec.Filter,
+		MappedIDs:      MappedSecretIDs(bwSecret.Spec.SecretMap),
+		NoteIDs:        NoteSecretIDs(bwSecret.Spec.SecretMap),
+		SourceMetadata: bwSecret.Spec.SourceMetadata,
+	}
+}
+
+type compiledSecretFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// compileSecretFilter compiles the regular expressions of the filter.  A nil filter keeps every secret.
+func compileSecretFilter(filter *operatorsv1.SecretFilter) (*compiledSecretFilter, error) {
+	compiled := &compiledSecretFilter{}
+	if filter == nil {
+		return compiled, nil
+	}
+
+	var err error
+	if filter.IncludeRegex != "" {
+		if compiled.include, err = regexp.Compile(filter.IncludeRegex); err != nil {
+			return nil, fmt.Errorf("invalid includeRegex: %w", err)
+		}
+	}
+
+	if filter.ExcludeRegex != "" {
+		if compiled.exclude, err = regexp.Compile(filter.ExcludeRegex); err != nil {
+			return nil, fmt.Errorf("invalid excludeRegex: %w", err)
+		}
+	}
+
+	return compiled, nil
+}
+
+// ValidateSecretFilter checks that the regular expressions of the filter compile.
+func ValidateSecretFilter(filter *operatorsv1.SecretFilter) error {
+	_, err := compileSecretFilter(filter)
+	return err
+}
+
+// FilterSecretsByKey keeps the secrets whose key matches includeRegex, if set, and does not match excludeRegex.  The
+// second returned value is the number of secrets left out.
+func FilterSecretsByKey(secrets []bwclient.SecretResponse, filter *operatorsv1.SecretFilter) ([]bwclient.SecretResponse, int, error) {
+	compiled, err := compileSecretFilter(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if compiled.include == nil && compiled.exclude == nil {
+		return secrets, 0, nil
+	}
+
+	kept := []bwclient.SecretResponse{}
+	for _, secret := range secrets {
+		if compiled.include != nil && !compiled.include.MatchString(secret.Key) {
+			continue
+		}
+		if compiled.exclude != nil && compiled.exclude.MatchString(secret.Key) {
+			continue
+		}
+		kept = append(kept, secret)
+	}
+
+	return kept, len(secrets) - len(kept), nil
+}
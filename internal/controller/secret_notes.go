@@ -0,0 +1,65 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// NoteKey returns the key the note of a secret is pulled to, next to the values keyed by secret ID.  The slash keeps
+// it from clashing with a secret ID or being written to a Kubernetes secret as a key of its own.
+func NoteKey(bwSecretId string) string {
+	return bwSecretId + "/note"
+}
+
+// NoteSecretIDs returns the IDs of the secrets whose note a map entry writes.
+func NoteSecretIDs(secretMap []operatorsv1.SecretMap) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, m := range secretMap {
+		if m.Source == operatorsv1.SecretSourceNote && !seen[m.BwSecretId] {
+			seen[m.BwSecretId] = true
+			ids = append(ids, m.BwSecretId)
+		}
+	}
+
+	return ids
+}
+
+// AddNotes adds the notes of the pulled secrets listed in noteIDs to the pulled values under their NoteKey.  Notes
+// of other secrets are left out, so they never end up in a Kubernetes secret without a map.
+func AddNotes(secrets map[string][]byte, smSecretVals []bwclient.SecretResponse, noteIDs []string) {
+	if len(noteIDs) == 0 {
+		return
+	}
+
+	wanted := make(map[string]bool, len(noteIDs))
+	for _, id := range noteIDs {
+		wanted[id] = true
+	}
+
+	for _, smSecretVal := range smSecretVals {
+		if wanted[smSecretVal.ID] {
+			secrets[NoteKey(smSecretVal.ID)] = []byte(smSecretVal.Note)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// TargetSecretType returns the type of the Kubernetes secret holding the data of the BitwardenSecret.
+func TargetSecretType(bwSecret *operatorsv1.BitwardenSecret) corev1.SecretType {
+	if bwSecret.Spec.SecretType == "" {
+		return corev1.SecretTypeOpaque
+	}
+
+	return bwSecret.Spec.SecretType
+}
+
+// ValidateSecretType checks that the data of the secret is valid for the type of the BitwardenSecret, whether it was
+// assembled by the operator or mapped from Secrets Manager secrets, so that a broken secret is never written.
+func ValidateSecretType(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret) error {
+	secretType := TargetSecretType(bwSecret)
+	if err := operatorsv1.ValidateSecretTypeName(secretType); err != nil {
+		return err
+	}
+
+	switch secretType {
+	case corev1.SecretTypeDockerConfigJson:
+		return ValidateDockerConfig(secret.Data[corev1.DockerConfigJsonKey])
+	case corev1.SecretTypeTLS:
+		return ValidateTLS(secret.Data)
+	case corev1.SecretTypeDockercfg:
+		if !json.Valid(secret.Data[corev1.DockerConfigKey]) {
+			return fmt.Errorf("a secret of type %s requires the %s key to hold JSON", secretType, corev1.DockerConfigKey)
+		}
+	case corev1.SecretTypeBasicAuth:
+		_, username := secret.Data[corev1.BasicAuthUsernameKey]
+		_, password := secret.Data[corev1.BasicAuthPasswordKey]
+		if !username && !password {
+			return fmt.Errorf("a secret of type %s requires the %s or the %s key", secretType, corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+		}
+	case corev1.SecretTypeSSHAuth:
+		if len(secret.Data[corev1.SSHAuthPrivateKey]) == 0 {
+			return fmt.Errorf("a secret of type %s requires the %s key", secretType, corev1.SSHAuthPrivateKey)
+		}
+		return ValidateSSHPrivateKey(secret.Data[corev1.SSHAuthPrivateKey])
+	case corev1.SecretTypeBootstrapToken:
+		if !bootstrapTokenID.Match(secret.Data["token-id"]) || !bootstrapTokenSecret.Match(secret.Data["token-secret"]) {
+			return fmt.Errorf("a secret of type %s requires a token-id of 6 and a token-secret of 16 lower case letters or digits", secretType)
+		}
+	}
+
+	return nil
+}
+
+// Formats of the parts of bootstrap tokens
+var (
+	bootstrapTokenID     = regexp.MustCompile(`^[a-z0-9]{6}$`)
+	bootstrapTokenSecret = regexp.MustCompile(`^[a-z0-9]{16}$`)
+)
+
+// TargetImmutable returns the immutable field of the Kubernetes secret holding the data of the BitwardenSecret.
+func TargetImmutable(bwSecret *operatorsv1.BitwardenSecret) *bool {
+	if !bwSecret.Spec.Immutable {
+		return nil
+	}
+
+	immutable := true
+	return &immutable
+}
+
+// K8sSecretImmutable reports whether the data of the secret can no longer be updated in place.
+func K8sSecretImmutable(secret *corev1.Secret) bool {
+	return secret.Immutable != nil && *secret.Immutable
+}
+
+// ReplaceK8sSecret deletes the secret and creates it again with the given type, since neither the type of an existing
+// secret nor any part of an immutable secret can be changed.
+func (r *BitwardenSecretReconciler) ReplaceK8sSecret(ctx context.Context, secret *corev1.Secret, secretType corev1.SecretType) error {
+	return replaceK8sSecret(ctx, r.Client, secret, secretType)
+}
+
+func replaceK8sSecret(ctx context.Context, c client.Client, secret *corev1.Secret, secretType corev1.SecretType) error {
+	uid := secret.UID
+	err := c.Delete(ctx, secret, client.Preconditions{UID: &uid})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	secret.ResourceVersion = ""
+	secret.UID = ""
+	secret.Type = secretType
+	return c.Create(ctx, secret)
+}
@@ -0,0 +1,111 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+// Annotations of written secrets with spec.sourceMetadata naming where their keys come from in Secrets Manager
+const (
+	SourceOrganizationAnnotation = "k8s.bitwarden.com/source-organization-id"
+	SourceKeysAnnotation         = "k8s.bitwarden.com/source-keys"
+	SourceProjectsAnnotation     = "k8s.bitwarden.com/source-projects"
+)
+
+// SecretOrigin identifies a Secrets Manager secret by its key and the name of its project, if any.
+type SecretOrigin struct {
+	Key         string
+	ProjectName string
+}
+
+// SecretSources returns the origin of every pulled secret by ID.  Secrets of projects missing from projects have no
+// project name.
+func SecretSources(smSecretVals []bwclient.SecretResponse, projects []bwclient.ProjectResponse) map[string]SecretOrigin {
+	projectNames := make(map[string]string, len(projects))
+	for _, project := range projects {
+		projectNames[project.ID] = project.Name
+	}
+
+	sources := make(map[string]SecretOrigin, len(smSecretVals))
+	for _, smSecretVal := range smSecretVals {
+		origin := SecretOrigin{Key: smSecretVal.Key}
+		if smSecretVal.ProjectID != nil {
+			origin.ProjectName = projectNames[*smSecretVal.ProjectID]
+		}
+		sources[smSecretVal.ID] = origin
+	}
+
+	return sources
+}
+
+// SetSourceMetadataAnnotations annotates the secret with the organization of the BitwardenSecret, the Secrets Manager
+// key every key with a single revision was written from, and the names of the projects of those secrets.  Without
+// spec.sourceMetadata the annotations are removed.
+func SetSourceMetadataAnnotations(bwSecret *operatorsv1.BitwardenSecret, secret *corev1.Secret, revisions map[string]SecretRevision, sources map[string]SecretOrigin) {
+	delete(secret.Annotations, SourceOrganizationAnnotation)
+	delete(secret.Annotations, SourceKeysAnnotation)
+	delete(secret.Annotations, SourceProjectsAnnotation)
+	if !bwSecret.Spec.SourceMetadata {
+		return
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[SourceOrganizationAnnotation] = bwSecret.Spec.OrganizationId
+
+	keys := map[string]string{}
+	projects := map[string]bool{}
+	for key, revision := range revisions {
+		origin, ok := sources[revision.ID]
+		if !ok {
+			continue
+		}
+
+		keys[key] = origin.Key
+		if origin.ProjectName != "" {
+			projects[origin.ProjectName] = true
+		}
+	}
+
+	if len(keys) > 0 {
+		// Marshalling a map of strings cannot fail.  Maps are marshalled with sorted keys.
+		bytes, _ := json.Marshal(keys)
+		secret.Annotations[SourceKeysAnnotation] = string(bytes)
+	}
+
+	if len(projects) > 0 {
+		names := make([]string, 0, len(projects))
+		for name := range projects {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		secret.Annotations[SourceProjectsAnnotation] = strings.Join(names, ",")
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Keys of SSH secrets as read by Flux source controllers, next to the ssh-privatekey of the kubernetes.io/ssh-auth type
+const (
+	sshIdentityKey   = "identity"
+	sshKnownHostsKey = "known_hosts"
+)
+
+// ApplySSHAuth writes the private key and known hosts of the BitwardenSecret's SSH template to the secret.  The key is
+// written to ssh-privatekey and to identity, where Flux looks for it.  Without a map only the SSH keys are written.  It
+// does nothing when no template is set.
+func ApplySSHAuth(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte, secret *corev1.Secret) error {
+	template := bwSecret.Spec.SSHAuth
+	if template == nil {
+		return nil
+	}
+
+	if secretType := TargetSecretType(bwSecret); secretType != corev1.SecretTypeSSHAuth {
+		return fmt.Errorf("spec.sshAuth requires spec.secretType %s, not %s", corev1.SecretTypeSSHAuth, secretType)
+	}
+
+	key, err := sshAuthValue(secrets, template.PrivateKeySecretId, "private key")
+	if err != nil {
+		return err
+	}
+
+	if bwSecret.Spec.SecretMap == nil || secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[corev1.SSHAuthPrivateKey] = key
+	secret.Data[sshIdentityKey] = key
+
+	if template.KnownHostsSecretId != "" {
+		knownHosts, err := sshAuthValue(secrets, template.KnownHostsSecretId, "known hosts")
+		if err != nil {
+			return err
+		}
+		secret.Data[sshKnownHostsKey] = knownHosts
+	}
+
+	return nil
+}
+
+func sshAuthValue(secrets map[string][]byte, id string, field string) ([]byte, error) {
+	if id == "" {
+		return nil, fmt.Errorf("the SSH %s secret ID is not set", field)
+	}
+
+	value, ok := secrets[id]
+	if !ok {
+		return nil, fmt.Errorf("the SSH %s secret %s is not accessible by the machine account", field, id)
+	}
+
+	return value, nil
+}
+
+// ValidateSSHPrivateKey checks that the value is an unencrypted private key in the OpenSSH format written by
+// ssh-keygen, or a PEM encoded PKCS #1, PKCS #8, or EC private key.  Keys protected by a passphrase are rejected, since
+// nothing can enter the passphrase when the key is used.
+func ValidateSSHPrivateKey(value []byte) error {
+	block, _ := pem.Decode(value)
+	if block == nil {
+		return fmt.Errorf("%s does not hold a PEM encoded private key", corev1.SSHAuthPrivateKey)
+	}
+
+	if _, encrypted := block.Headers["Proc-Type"]; encrypted || block.Type == "ENCRYPTED PRIVATE KEY" {
+		return fmt.Errorf("%s holds a private key protected by a passphrase", corev1.SSHAuthPrivateKey)
+	}
+
+	var err error
+	switch block.Type {
+	case "OPENSSH PRIVATE KEY":
+		err = parseOpenSSHPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		_, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		_, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		_, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("%s holds an invalid private key: %w", corev1.SSHAuthPrivateKey, err)
+	}
+
+	return nil
+}
+
+// Magic of the openssh-key-v1 format, as described in PROTOCOL.key of OpenSSH
+const openSSHKeyMagic = "openssh-key-v1\x00"
+
+// parseOpenSSHPrivateKey checks the structure of an openssh-key-v1 container and that its private section is not
+// encrypted and belongs to its first public key.
+func parseOpenSSHPrivateKey(data []byte) error {
+	rest, ok := bytes.CutPrefix(data, []byte(openSSHKeyMagic))
+	if !ok {
+		return errors.New("not an openssh-key-v1 key")
+	}
+
+	cipher, rest, err := readSSHString(rest)
+	if err != nil {
+		return err
+	}
+	if string(cipher) != "none" {
+		return errors.New("the key is protected by a passphrase")
+	}
+
+	// KDF name and options
+	for i := 0; i < 2; i++ {
+		if _, rest, err = readSSHString(rest); err != nil {
+			return err
+		}
+	}
+
+	if len(rest) < 4 {
+		return errors.New("truncated key")
+	}
+	count := binary.BigEndian.Uint32(rest)
+	rest = rest[4:]
+	if count == 0 {
+		return errors.New("the key holds no keys")
+	}
+
+	var publicKey []byte
+	for i := uint32(0); i < count; i++ {
+		var key []byte
+		if key, rest, err = readSSHString(rest); err != nil {
+			return err
+		}
+		if i == 0 {
+			publicKey = key
+		}
+	}
+
+	private, _, err := readSSHString(rest)
+	if err != nil {
+		return err
+	}
+	if len(private) < 8 || len(private)%8 != 0 {
+		return errors.New("invalid private section")
+	}
+	if binary.BigEndian.Uint32(private) != binary.BigEndian.Uint32(private[4:]) {
+		return errors.New("corrupted private section")
+	}
+
+	privateType, _, err := readSSHString(private[8:])
+	if err != nil {
+		return err
+	}
+	publicType, _, err := readSSHString(publicKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(privateType, publicType) {
+		return fmt.Errorf("the private key of type %s does not match the public key of type %s", privateType, publicType)
+	}
+
+	return nil
+}
+
+// readSSHString reads a string of the SSH wire format, a length followed by that many bytes.
+func readSSHString(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, errors.New("truncated key")
+	}
+
+	length := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(length) {
+		return nil, nil, errors.New("truncated key")
+	}
+
+	return data[4 : 4+length], data[4+length:], nil
+}
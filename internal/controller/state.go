@@ -0,0 +1,276 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+)
+
+const (
+	// StatePersistencePlain lets the SDK write its login state to the state path as is
+	StatePersistencePlain = "plain"
+	// StatePersistenceEncrypted keeps the login state on the state path encrypted with AES-256-GCM
+	StatePersistenceEncrypted = "encrypted"
+	// StatePersistenceNone never writes login state, so every client logs in from scratch
+	StatePersistenceNone = "none"
+
+	// Name of the encrypted state file when the state path is a directory
+	encryptedStateFileName = "state.enc"
+)
+
+var (
+	statePersistence = StatePersistencePlain
+	stateKey         []byte
+	// One lock per encrypted state file, serializing its decryption and encryption by the clients of a machine account
+	stateFileLocks sync.Map
+)
+
+// SetStatePersistence selects how the login state of all Bitwarden clients created afterwards is persisted.  The
+// encrypted mode requires a 32 byte key, either raw or base64 encoded.
+func SetStatePersistence(mode string, key []byte) error {
+	switch mode {
+	case "", StatePersistencePlain, StatePersistenceNone:
+		if len(key) > 0 {
+			return fmt.Errorf("a state encryption key is only used with the %q state persistence", StatePersistenceEncrypted)
+		}
+		if mode == "" {
+			mode = StatePersistencePlain
+		}
+		stateKey = nil
+	case StatePersistenceEncrypted:
+		decoded, err := ParseStateKey(key)
+		if err != nil {
+			return err
+		}
+		stateKey = decoded
+	default:
+		return fmt.Errorf("unknown state persistence %q, expected %s, %s, or %s", mode, StatePersistencePlain, StatePersistenceEncrypted, StatePersistenceNone)
+	}
+
+	statePersistence = mode
+	return nil
+}
+
+// ParseStateKey returns the 32 byte state encryption key held in key, which is either the raw key or its base64
+// encoding.
+func ParseStateKey(key []byte) ([]byte, error) {
+	if len(key) == 32 {
+		return key, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(key)))
+	if err != nil || len(decoded) != 32 {
+		return nil, fmt.Errorf("state encryption key must be 32 bytes or their base64 encoding")
+	}
+
+	return decoded, nil
+}
+
+// newStatePersistenceClient applies the configured state persistence to the logins of inner.
+func newStatePersistenceClient(inner bwclient.BitwardenClientInterface) bwclient.BitwardenClientInterface {
+	if statePersistence == StatePersistencePlain {
+		return inner
+	}
+
+	return &statePersistenceClient{BitwardenClientInterface: inner, mode: statePersistence, key: stateKey}
+}
+
+// lockStateFile locks the encrypted state file at path and returns the function unlocking it.  The lock is only held
+// while the file is read or written, never during a login, so a hung login does not block other logins.
+func lockStateFile(path string) func() {
+	lock, _ := stateFileLocks.LoadOrStore(path, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+// statePersistenceClient keeps the SDK from ever leaving readable login state on the state path.  With encryption,
+// the state is decrypted to a private temporary directory on the first login, where the SDK keeps refreshing it for
+// as long as the client lives.  It is encrypted back after every login and once more when the client is closed, so
+// that tokens refreshed by long lived clients are not lost.
+type statePersistenceClient struct {
+	bwclient.BitwardenClientInterface
+	mode string
+	key  []byte
+
+	plainDir      string
+	encryptedPath string
+}
+
+func (c *statePersistenceClient) AccessTokenLogin(accessToken string, statePath *string) error {
+	if c.mode == StatePersistenceNone || statePath == nil || *statePath == "" {
+		return c.BitwardenClientInterface.AccessTokenLogin(accessToken, nil)
+	}
+
+	if c.plainDir == "" {
+		plainDir, err := os.MkdirTemp("", "bitwarden-state-")
+		if err != nil {
+			return err
+		}
+		c.plainDir = plainDir
+		c.encryptedPath = encryptedStatePath(*statePath)
+
+		if err := c.loadState(); err != nil {
+			os.RemoveAll(c.plainDir)
+			c.plainDir = ""
+			return err
+		}
+	}
+
+	plainPath := c.plainPath()
+	if err := c.BitwardenClientInterface.AccessTokenLogin(accessToken, &plainPath); err != nil {
+		return err
+	}
+
+	return c.saveState()
+}
+
+func (c *statePersistenceClient) Close() {
+	c.BitwardenClientInterface.Close()
+
+	if c.plainDir == "" {
+		return
+	}
+
+	if err := c.saveState(); err != nil {
+		ctrl.Log.WithName("state").Error(err, "Failed to save the login state", "path", c.encryptedPath)
+	}
+	os.RemoveAll(c.plainDir)
+	c.plainDir = ""
+}
+
+func (c *statePersistenceClient) plainPath() string {
+	return filepath.Join(c.plainDir, "state")
+}
+
+// loadState decrypts the state of the machine account to the private state file of the client.
+func (c *statePersistenceClient) loadState() error {
+	unlock := lockStateFile(c.encryptedPath)
+	defer unlock()
+
+	if err := decryptStateFile(c.key, c.encryptedPath, c.plainPath()); err != nil {
+		// State that cannot be decrypted, such as a plain file left by an earlier version, is discarded and the
+		// client logs in from scratch
+		ctrl.Log.WithName("state").Info("Discarding unreadable login state", "path", c.encryptedPath, "reason", err.Error())
+		if err := os.Remove(c.encryptedPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveState encrypts the private state file of the client, including tokens the SDK refreshed since the login, back
+// to the state path.
+func (c *statePersistenceClient) saveState() error {
+	unlock := lockStateFile(c.encryptedPath)
+	defer unlock()
+
+	return encryptStateFile(c.key, c.plainPath(), c.encryptedPath)
+}
+
+// encryptedStatePath returns the file the encrypted state is kept in.  The default state path is a directory.
+func encryptedStatePath(statePath string) string {
+	if info, err := os.Stat(statePath); err == nil && info.IsDir() {
+		return filepath.Join(statePath, encryptedStateFileName)
+	}
+
+	return statePath
+}
+
+// decryptStateFile writes the plaintext of the encrypted state at encryptedPath to plainPath.  A missing state file is
+// not an error.
+func decryptStateFile(key []byte, encryptedPath string, plainPath string) error {
+	ciphertext, err := os.ReadFile(encryptedPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	aead, err := NewStateCipher(key)
+	if err != nil {
+		return err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return fmt.Errorf("state file is too short to be encrypted")
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return fmt.Errorf("state file cannot be decrypted with the state encryption key")
+	}
+
+	return os.WriteFile(plainPath, plaintext, 0600)
+}
+
+// encryptStateFile replaces the state at encryptedPath with the encrypted content of plainPath.  Nothing is written
+// when the SDK did not produce any state.
+func encryptStateFile(key []byte, plainPath string, encryptedPath string) error {
+	plaintext, err := os.ReadFile(plainPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	aead, err := NewStateCipher(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	// Written to a temporary file first, so a crash never leaves a truncated state file behind
+	temp, err := os.CreateTemp(filepath.Dir(encryptedPath), ".state-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(aead.Seal(nonce, nonce, plaintext, nil)); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), encryptedPath)
+}
@@ -0,0 +1,179 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Name of the state file within the state directory of a machine account
+const tokenStateFileName = "state"
+
+var tokenStateDirPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// tokenStateDir returns the name of the state directory of the machine account the access token belongs to.  Access
+// tokens have the form 0.<access token ID>.<secret>:<key>, and only the ID is hashed, so the directory does not change
+// when a token is re-encoded and the directory name reveals nothing about the token.
+func tokenStateDir(accessToken string) string {
+	id := accessToken
+	if parts := strings.SplitN(accessToken, ".", 3); len(parts) == 3 {
+		id = parts[1]
+	}
+
+	hash := NewHash()
+	hash.Write([]byte(id))
+	return hex.EncodeToString(hash.Sum(nil))[:32]
+}
+
+// tokenStatePath returns the state file of the machine account within statePath, creating its directory.  When
+// statePath is an existing file rather than a directory, every machine account keeps sharing it.
+func tokenStatePath(statePath string, accessToken string) (string, error) {
+	if statePath == "" {
+		return statePath, nil
+	}
+
+	if info, err := os.Stat(statePath); err == nil && !info.IsDir() {
+		return statePath, nil
+	}
+
+	dir := filepath.Join(statePath, tokenStateDir(accessToken))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, tokenStateFileName), nil
+}
+
+// StateDirCleaner removes the state directories of machine accounts that are no longer referenced by any
+// BitwardenSecret or ClusterBitwardenSecret.
+type StateDirCleaner struct {
+	Reader         client.Reader
+	StatePath      string
+	AuthTokenFiles AuthTokenFiles
+	// How often the state directories are cleaned up.  Directories changed within the last interval are kept, so a
+	// machine account that just started syncing is never cleaned up.
+	Interval time.Duration
+}
+
+// Start cleans up the state directories every interval until the context is cancelled.  It implements
+// manager.Runnable.
+func (c *StateDirCleaner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.Clean(ctx); err != nil {
+				ctrl.Log.WithName("state").Error(err, "Failed to clean up the state directories")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection runs the cleaner on every replica, since each keeps its own state.
+func (c *StateDirCleaner) NeedLeaderElection() bool {
+	return false
+}
+
+// Clean removes every state directory that belongs to none of the referenced machine accounts.  Removing state
+// never breaks a sync, the machine account merely logs in from scratch the next time.
+func (c *StateDirCleaner) Clean(ctx context.Context) error {
+	entries, err := os.ReadDir(c.StatePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	referenced, err := c.referencedStateDirs(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-c.Interval)
+	for _, entry := range entries {
+		if !entry.IsDir() || !tokenStateDirPattern.MatchString(entry.Name()) || referenced[entry.Name()] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(c.StatePath, entry.Name())); err != nil {
+			return err
+		}
+		ctrl.Log.WithName("state").Info("Removed the state of an unreferenced machine account", "dir", entry.Name())
+	}
+
+	return nil
+}
+
+// referencedStateDirs returns the state directories of the tokens of all BitwardenSecrets and ClusterBitwardenSecrets.
+// Tokens that cannot be read, for example because their secret was deleted, are not referenced.
+func (c *StateDirCleaner) referencedStateDirs(ctx context.Context) (map[string]bool, error) {
+	referenced := map[string]bool{}
+
+	bwSecrets := &operatorsv1.BitwardenSecretList{}
+	if err := c.Reader.List(ctx, bwSecrets); err != nil {
+		return nil, err
+	}
+	for i := range bwSecrets.Items {
+		token, err := ReadAuthToken(ctx, c.Reader, &bwSecrets.Items[i], c.AuthTokenFiles)
+		if err != nil {
+			continue
+		}
+		referenced[tokenStateDir(token)] = true
+	}
+
+	clusterSecrets := &operatorsv1.ClusterBitwardenSecretList{}
+	if err := c.Reader.List(ctx, clusterSecrets); err != nil {
+		return nil, err
+	}
+	for _, clusterSecret := range clusterSecrets.Items {
+		authToken := clusterSecret.Spec.AuthToken
+		authK8sSecret := &corev1.Secret{}
+		if err := c.Reader.Get(ctx, types.NamespacedName{Namespace: authToken.Namespace, Name: authToken.SecretName}, authK8sSecret); err != nil {
+			continue
+		}
+		referenced[tokenStateDir(string(authK8sSecret.Data[authToken.SecretKey]))] = true
+	}
+
+	return referenced, nil
+}
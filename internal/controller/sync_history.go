@@ -0,0 +1,88 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Number of sync attempts kept in status.history
+const MaxSyncHistory = 10
+
+// Longest failure reason kept in status.history
+const maxSyncHistoryReason = 256
+
+// Longest error kept in status.lastError
+const maxLastError = 1024
+
+type syncAttemptStartKey struct{}
+
+func withSyncAttemptStart(ctx context.Context, start time.Time) context.Context {
+	return context.WithValue(ctx, syncAttemptStartKey{}, start)
+}
+
+// RecordSyncAttempt appends the outcome of the current sync attempt to status.history, dropping the oldest attempts
+// beyond MaxSyncHistory.  Consecutive NoChanges attempts are counted in a single entry.
+func RecordSyncAttempt(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, result string, reason string) {
+	now := time.Now().UTC()
+
+	var duration time.Duration
+	if start, ok := ctx.Value(syncAttemptStartKey{}).(time.Time); ok {
+		duration = now.Sub(start)
+	}
+
+	attempt := operatorsv1.SyncAttempt{
+		Time:     metav1.Time{Time: now},
+		Result:   result,
+		Duration: metav1.Duration{Duration: duration.Round(time.Millisecond)},
+		Reason:   truncate(reason, maxSyncHistoryReason),
+	}
+
+	// Refreshes without changes would otherwise push the attempts worth seeing out of the history
+	if last := len(bwSecret.Status.History) - 1; result == "NoChanges" && last >= 0 && bwSecret.Status.History[last].Result == result {
+		attempt.Count = max(bwSecret.Status.History[last].Count, 1) + 1
+		bwSecret.Status.History[last] = attempt
+	} else {
+		bwSecret.Status.History = append(bwSecret.Status.History, attempt)
+	}
+	bwSecret.Status.LastError = truncate(reason, maxLastError)
+	if bwSecret.Name != "" {
+		recordSyncTimestamp(bwSecret.Namespace, bwSecret.Name, result == "Succeeded", now)
+	}
+
+	if len(bwSecret.Status.History) > MaxSyncHistory {
+		bwSecret.Status.History = bwSecret.Status.History[len(bwSecret.Status.History)-MaxSyncHistory:]
+	}
+}
+
+// truncate shortens s to at most max bytes, marking the cut with an ellipsis.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	return s[:max-3] + "..."
+}
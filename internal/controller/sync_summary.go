@@ -0,0 +1,81 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// SyncSummary collects the outcome of one sync attempt so that it can be logged as a single structured record.
+type SyncSummary struct {
+	// Succeeded, NoChanges, Ignored, or Failed
+	Result         string
+	FullSync       bool
+	SecretsFetched int
+	KeysAdded      int
+	KeysUpdated    int
+	KeysRemoved    int
+	PullDuration   time.Duration
+	WriteDuration  time.Duration
+
+	start time.Time
+}
+
+// NewSyncSummary starts the summary of a sync attempt.  Its result is Failed until set otherwise.
+func NewSyncSummary() *SyncSummary {
+	return &SyncSummary{Result: "Failed", start: time.Now()}
+}
+
+// RecordKeyChanges counts the keys added, updated, and removed between the previous and the new secret data.
+func (s *SyncSummary) RecordKeyChanges(previous map[string][]byte, current map[string][]byte) {
+	for key, value := range current {
+		previousValue, ok := previous[key]
+		if !ok {
+			s.KeysAdded++
+		} else if !bytes.Equal(previousValue, value) {
+			s.KeysUpdated++
+		}
+	}
+
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			s.KeysRemoved++
+		}
+	}
+}
+
+// Log writes the summary as one log record.
+func (s *SyncSummary) Log(logger logr.Logger) {
+	logger.Info("Sync summary",
+		"result", s.Result,
+		"fullSync", s.FullSync,
+		"secretsFetched", s.SecretsFetched,
+		"keysAdded", s.KeysAdded,
+		"keysUpdated", s.KeysUpdated,
+		"keysRemoved", s.KeysRemoved,
+		"pullMs", s.PullDuration.Milliseconds(),
+		"writeMs", s.WriteDuration.Milliseconds(),
+		"durationMs", time.Since(s.start).Milliseconds(),
+	)
+}
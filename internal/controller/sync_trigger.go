@@ -0,0 +1,165 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Largest sync trigger request body accepted
+const maxSyncTriggerBody = 4096
+
+// SyncTriggerRequest names the BitwardenSecret a sync is triggered for.
+type SyncTriggerRequest struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// SyncTriggerResponse reports the k8s.bitwarden.com/force-sync value that was set to trigger the sync.
+type SyncTriggerResponse struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	ForceSync string `json:"forceSync"`
+}
+
+// SyncTriggerHandler triggers an immediate full sync of the BitwardenSecret named by a POSTed SyncTriggerRequest, by
+// setting its k8s.bitwarden.com/force-sync annotation.  Requests must carry the token of TokenFile as a bearer token.
+// The file is read on every request, so that a mounted secret can be rotated.
+type SyncTriggerHandler struct {
+	Client    client.Client
+	TokenFile string
+}
+
+func (h *SyncTriggerHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, err := os.ReadFile(h.TokenFile)
+	if err != nil {
+		http.Error(w, "the sync trigger token cannot be read", http.StatusInternalServerError)
+		return
+	}
+	if !validBearerToken(req.Header.Get("Authorization"), strings.TrimSpace(string(token))) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	target := SyncTriggerRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxSyncTriggerBody)).Decode(&target); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %s", err), http.StatusBadRequest)
+		return
+	}
+	if target.Namespace == "" || target.Name == "" {
+		http.Error(w, "namespace and name are required", http.StatusBadRequest)
+		return
+	}
+
+	forceSync, err := TriggerSync(req.Context(), h.Client, types.NamespacedName{Namespace: target.Namespace, Name: target.Name})
+	if apierrors.IsNotFound(err) {
+		http.Error(w, fmt.Sprintf("BitwardenSecret %s/%s not found", target.Namespace, target.Name), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(SyncTriggerResponse{Namespace: target.Namespace, Name: target.Name, ForceSync: forceSync})
+}
+
+// validBearerToken compares the bearer token of the Authorization header with the expected token in constant time.  An
+// empty expected token accepts nothing.
+func validBearerToken(header string, expected string) bool {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || expected == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(expected)) == 1
+}
+
+// TriggerSync sets the k8s.bitwarden.com/force-sync annotation of the BitwardenSecret to the current time and returns
+// the value.
+func TriggerSync(ctx context.Context, k8sClient client.Client, name types.NamespacedName) (string, error) {
+	bwSecret := &operatorsv1.BitwardenSecret{}
+	if err := k8sClient.Get(ctx, name, bwSecret); err != nil {
+		return "", err
+	}
+
+	patch := client.MergeFrom(bwSecret.DeepCopy())
+	forceSync := time.Now().UTC().Format(time.RFC3339Nano)
+	if bwSecret.Annotations == nil {
+		bwSecret.Annotations = map[string]string{}
+	}
+	bwSecret.Annotations[ForceSyncAnnotation] = forceSync
+
+	return forceSync, k8sClient.Patch(ctx, bwSecret, patch)
+}
+
+// SyncTriggerServer serves the handler on Addr.  It implements manager.Runnable and runs on every replica, since the
+// annotation it sets reaches the leader through the API server.
+type SyncTriggerServer struct {
+	Addr    string
+	Handler http.Handler
+}
+
+func (s *SyncTriggerServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: s.Handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	ctrl.Log.WithName("sync-trigger").Info("Serving sync triggers", "addr", listener.Addr().String())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *SyncTriggerServer) NeedLeaderElection() bool {
+	return false
+}
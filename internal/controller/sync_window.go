@@ -0,0 +1,112 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	// Time zones must resolve in minimal images without a zoneinfo database
+	_ "time/tzdata"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Condition set while changes are held back by spec.syncWindow
+const SyncWindowClosedCondition = "SyncWindowClosed"
+
+var weekdays = map[string]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// SyncWindowOpen reports whether now falls inside the sync window.  When it does not, the second value is the time
+// the window opens next.
+func SyncWindowOpen(window *operatorsv1.SyncWindow, now time.Time) (bool, time.Time, error) {
+	location := time.UTC
+	if window.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(window.TimeZone)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+	}
+
+	startHour, startMinute, err := parseTimeOfDay(window.Start)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	endHour, endMinute, err := parseTimeOfDay(window.End)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	days := map[time.Weekday]bool{}
+	for _, day := range window.Days {
+		weekday, ok := weekdays[day]
+		if !ok {
+			return false, time.Time{}, fmt.Errorf("unknown day %q in sync window", day)
+		}
+		days[weekday] = true
+	}
+
+	local := now.In(location)
+
+	// Yesterday's window may span midnight into today, and the next opening is at most a week away
+	for offset := -1; offset <= 7; offset++ {
+		// Built from the date rather than by adding durations so that windows follow daylight saving changes
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, location)
+		if len(days) > 0 && !days[day.Weekday()] {
+			continue
+		}
+
+		opens := time.Date(day.Year(), day.Month(), day.Day(), startHour, startMinute, 0, 0, location)
+		closes := time.Date(day.Year(), day.Month(), day.Day(), endHour, endMinute, 0, 0, location)
+		if !closes.After(opens) {
+			closes = closes.AddDate(0, 0, 1)
+		}
+
+		if !local.Before(opens) && local.Before(closes) {
+			return true, time.Time{}, nil
+		}
+
+		if opens.After(local) {
+			return false, opens, nil
+		}
+	}
+
+	return false, time.Time{}, fmt.Errorf("sync window never opens")
+}
+
+func parseTimeOfDay(value string) (int, int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q in sync window, expected HH:MM", value)
+	}
+
+	return parsed.Hour(), parsed.Minute(), nil
+}
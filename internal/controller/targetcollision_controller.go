@@ -0,0 +1,157 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// TargetCollisionReconciler finds BitwardenSecrets that write to the same Kubernetes secret, for example because they
+// were created while the admission webhook was unavailable, and marks each of them with a TargetCollision condition
+// naming the other claimants.  Requests are keyed by the namespace and name of the Kubernetes secret, not of a
+// BitwardenSecret.  The manager's cache must be indexed with operatorsv1.IndexSecretName.
+type TargetCollisionReconciler struct {
+	client.Client
+}
+
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardensecrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=k8s.bitwarden.com,resources=bitwardensecrets/status,verbs=get;update;patch
+
+func (r *TargetCollisionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	claims := &operatorsv1.BitwardenSecretList{}
+	err := r.List(ctx, claims, client.InNamespace(req.Namespace), client.MatchingFields{operatorsv1.SecretNameIndexField: req.Name})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	names := make([]string, 0, len(claims.Items))
+	for _, claim := range claims.Items {
+		names = append(names, claim.Name)
+	}
+	sort.Strings(names)
+
+	if len(names) > 1 {
+		logger.Info(fmt.Sprintf("Secret %s/%s is written by more than one BitwardenSecret", req.Namespace, req.Name), "claimants", names)
+	}
+
+	for i := range claims.Items {
+		bwSecret := &claims.Items[i]
+		if !SetTargetCollisionCondition(bwSecret, names) {
+			continue
+		}
+
+		if err := r.Status().Update(ctx, bwSecret); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetTargetCollisionCondition sets or clears the TargetCollision condition of a BitwardenSecret given the names of
+// all BitwardenSecrets writing to its Kubernetes secret.  It reports whether the conditions changed.
+func SetTargetCollisionCondition(bwSecret *operatorsv1.BitwardenSecret, claimants []string) bool {
+	others := []string{}
+	for _, name := range claimants {
+		if name != bwSecret.Name {
+			others = append(others, name)
+		}
+	}
+
+	if len(others) == 0 {
+		if apimeta.FindStatusCondition(bwSecret.Status.Conditions, "TargetCollision") == nil {
+			return false
+		}
+
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, "TargetCollision")
+		return true
+	}
+
+	condition := metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "SharedTargetSecret",
+		Message: fmt.Sprintf("Secret %s/%s is also written by BitwardenSecret %s", bwSecret.Namespace, bwSecret.Spec.SecretName, strings.Join(others, ", ")),
+		Type:    "TargetCollision",
+	}
+
+	existing := apimeta.FindStatusCondition(bwSecret.Status.Conditions, condition.Type)
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+		return false
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, condition)
+	return true
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TargetCollisionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("targetcollision").
+		Watches(&operatorsv1.BitwardenSecret{}, targetSecretHandler()).
+		Complete(r)
+}
+
+// targetSecretHandler enqueues the Kubernetes secrets a BitwardenSecret writes to.  Updates enqueue both the old and
+// the new secret so that a BitwardenSecret moving away from a shared secret clears the condition on the others.
+func targetSecretHandler() handler.EventHandler {
+	enqueue := func(q workqueue.RateLimitingInterface, obj client.Object) {
+		bwSecret, ok := obj.(*operatorsv1.BitwardenSecret)
+		if !ok {
+			return
+		}
+
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: bwSecret.Namespace, Name: bwSecret.Spec.SecretName}})
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.Object)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.ObjectOld)
+			enqueue(q, e.ObjectNew)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.Object)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.Object)
+		},
+	}
+}
@@ -0,0 +1,376 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Label of the secrets written for spec.targets, holding the name of the BitwardenSecret
+const TargetOfLabel = "k8s.bitwarden.com/target-of"
+
+// Field manager of the server-side apply requests writing the secrets of spec.secretName and spec.targets
+const FieldManager = "bitwarden-sm-operator"
+
+// TargetTemplate returns the BitwardenSecret rendering the secret of one of the targets of bwSecret.
+func TargetTemplate(bwSecret *operatorsv1.BitwardenSecret, target operatorsv1.SecretTarget) *operatorsv1.BitwardenSecret {
+	secretType := target.SecretType
+	if IsConfigMapTarget(target) {
+		secretType = corev1.SecretTypeOpaque
+	}
+
+	return &operatorsv1.BitwardenSecret{
+		ObjectMeta: bwSecret.ObjectMeta,
+		Spec: operatorsv1.BitwardenSecretSpec{
+			OrganizationId: bwSecret.Spec.OrganizationId,
+			SecretName:     target.SecretName,
+			SecretMap:      target.SecretMap,
+			SecretType:     secretType,
+			SecretMetadata: bwSecret.Spec.SecretMetadata,
+			Immutable:      bwSecret.Spec.Immutable,
+		},
+	}
+}
+
+// IsConfigMapTarget reports whether the target is written to a ConfigMap instead of a Kubernetes secret.
+func IsConfigMapTarget(target operatorsv1.SecretTarget) bool {
+	return target.Kind == operatorsv1.TargetKindConfigMap
+}
+
+// TargetConfigMap returns the ConfigMap holding the rendered secret of a ConfigMap target.  Values that are not valid
+// UTF-8 are written to binaryData.
+func TargetConfigMap(secret *corev1.Secret) *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secret.Name,
+			Namespace:   secret.Namespace,
+			Labels:      secret.Labels,
+			Annotations: secret.Annotations,
+		},
+		Immutable: secret.Immutable,
+	}
+
+	for key, value := range secret.Data {
+		if utf8.Valid(value) {
+			if configMap.Data == nil {
+				configMap.Data = map[string]string{}
+			}
+			configMap.Data[key] = string(value)
+		} else {
+			if configMap.BinaryData == nil {
+				configMap.BinaryData = map[string][]byte{}
+			}
+			configMap.BinaryData[key] = value
+		}
+	}
+
+	return configMap
+}
+
+// WriteTargetSecrets renders and writes the secret or ConfigMap of every target of the BitwardenSecret from the pulled
+// values and deletes the objects of targets that were removed.  Nothing is written unless every target renders.  Up
+// to TargetWriteParallelism objects are written at the same time.  Targets of other clusters are written after the
+// local ones.
+func (r *BitwardenSecretReconciler) WriteTargetSecrets(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte) error {
+	rendered := make([]client.Object, 0, len(bwSecret.Spec.Targets))
+	var clusters []*clusterTargets
+	byCluster := map[operatorsv1.ClusterRef]*clusterTargets{}
+	for _, target := range bwSecret.Spec.Targets {
+		secret, err := RenderK8sSecret(TargetTemplate(bwSecret, target), secrets)
+		if err != nil {
+			return fmt.Errorf("target %s: %w", target.SecretName, err)
+		}
+		secret.Labels[TargetOfLabel] = bwSecret.Name
+		SetDataChecksumAnnotation(secret)
+
+		var obj client.Object = secret
+		if IsConfigMapTarget(target) {
+			obj = TargetConfigMap(secret)
+		}
+
+		if target.ClusterRef == nil {
+			rendered = append(rendered, obj)
+			continue
+		}
+
+		ref := NormalizeClusterRef(bwSecret, *target.ClusterRef)
+		obj.SetNamespace(ref.Namespace)
+		cluster, ok := byCluster[ref]
+		if !ok {
+			cluster = &clusterTargets{ref: ref}
+			byCluster[ref] = cluster
+			clusters = append(clusters, cluster)
+		}
+		cluster.targets = append(cluster.targets, target)
+		cluster.rendered = append(cluster.rendered, obj)
+	}
+
+	if err := r.writeTargetSecretsParallel(ctx, r.Client, OwnsTargets(bwSecret), bwSecret, rendered); err != nil {
+		return err
+	}
+
+	if err := r.PruneTargetSecrets(ctx, bwSecret); err != nil {
+		return err
+	}
+
+	return r.writeClusterTargets(ctx, bwSecret, clusters)
+}
+
+// writeTargetSecretsParallel writes the rendered target objects with the client, with bounded parallelism.  Owned
+// objects get a controller reference to the BitwardenSecret, which is only possible in its own cluster.  Every object
+// is attempted, and the errors are returned in the order of spec.targets so that the message stays stable between
+// reconciles.
+func (r *BitwardenSecretReconciler) writeTargetSecretsParallel(ctx context.Context, c client.Client, owned bool, bwSecret *operatorsv1.BitwardenSecret, rendered []client.Object) error {
+	parallelism := r.TargetWriteParallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	errs := make([]error, len(rendered))
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, obj := range rendered {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, obj client.Object) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			var err error
+			if owned {
+				// Cascading delete
+				err = ctrl.SetControllerReference(bwSecret, obj, r.Scheme)
+			}
+			if err == nil {
+				switch obj := obj.(type) {
+				case *corev1.ConfigMap:
+					err = writeTargetConfigMap(ctx, c, bwSecret, obj)
+				case *corev1.Secret:
+					err = writeTargetSecret(ctx, c, bwSecret, obj)
+				}
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("target %s: %w", obj.GetName(), err)
+			}
+		}(i, obj)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// writeTargetSecret creates the secret of a target, or updates it with server-side apply so that fields written by
+// other controllers are kept and conflicting changes are reported instead of overwritten.  A secret of the same name
+// that the BitwardenSecret does not manage is left untouched.
+func writeTargetSecret(ctx context.Context, c client.Client, bwSecret *operatorsv1.BitwardenSecret, rendered *corev1.Secret) error {
+	existing := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: rendered.Namespace, Name: rendered.Name}, existing)
+	if err != nil && apierrors.IsNotFound(err) {
+		return c.Create(ctx, rendered, client.FieldOwner(FieldManager))
+	} else if err != nil {
+		return err
+	}
+
+	if existing.Labels[TargetOfLabel] != bwSecret.Name || existing.Labels["k8s.bitwarden.com/bw-secret"] != string(bwSecret.UID) {
+		return fmt.Errorf("secret %s/%s is not managed by BitwardenSecret %s", existing.Namespace, existing.Name, bwSecret.Name)
+	}
+
+	if err := releaseTarget(ctx, c, bwSecret, existing, rendered); err != nil {
+		return err
+	}
+
+	// The type and the data of immutable secrets cannot change in place
+	if existing.Type != rendered.Type || K8sSecretImmutable(existing) {
+		existing.Data = rendered.Data
+		existing.Immutable = rendered.Immutable
+		for key, value := range rendered.Labels {
+			existing.Labels[key] = value
+		}
+		for key, value := range rendered.Annotations {
+			if existing.Annotations == nil {
+				existing.Annotations = map[string]string{}
+			}
+			existing.Annotations[key] = value
+		}
+
+		return replaceK8sSecret(ctx, c, existing, rendered.Type)
+	}
+
+	opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+	// Secrets created or last written before the operator used server-side apply hold fields of our own earlier
+	// writes, which would otherwise conflict with the first apply
+	if !appliedBy(existing, FieldManager) {
+		opts = append(opts, client.ForceOwnership)
+	}
+
+	rendered.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}
+	err = c.Patch(ctx, rendered, client.Apply, opts...)
+	if apierrors.IsConflict(err) {
+		return fmt.Errorf("secret %s/%s was changed by another field manager: %w", existing.Namespace, existing.Name, err)
+	}
+
+	return err
+}
+
+// writeTargetConfigMap creates the ConfigMap of a target, or updates it with server-side apply like writeTargetSecret.
+// Immutable ConfigMaps are replaced.  A ConfigMap of the same name that the BitwardenSecret does not manage is left
+// untouched.
+func writeTargetConfigMap(ctx context.Context, c client.Client, bwSecret *operatorsv1.BitwardenSecret, rendered *corev1.ConfigMap) error {
+	existing := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: rendered.Namespace, Name: rendered.Name}, existing)
+	if err != nil && apierrors.IsNotFound(err) {
+		return c.Create(ctx, rendered, client.FieldOwner(FieldManager))
+	} else if err != nil {
+		return err
+	}
+
+	if existing.Labels[TargetOfLabel] != bwSecret.Name || existing.Labels["k8s.bitwarden.com/bw-secret"] != string(bwSecret.UID) {
+		return fmt.Errorf("ConfigMap %s/%s is not managed by BitwardenSecret %s", existing.Namespace, existing.Name, bwSecret.Name)
+	}
+
+	if err := releaseTarget(ctx, c, bwSecret, existing, rendered); err != nil {
+		return err
+	}
+
+	// The data of immutable ConfigMaps cannot change in place
+	if existing.Immutable != nil && *existing.Immutable {
+		if err := c.Delete(ctx, existing, client.Preconditions{UID: &existing.UID}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return c.Create(ctx, rendered, client.FieldOwner(FieldManager))
+	}
+
+	opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if !appliedBy(existing, FieldManager) {
+		opts = append(opts, client.ForceOwnership)
+	}
+
+	rendered.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+	err = c.Patch(ctx, rendered, client.Apply, opts...)
+	if apierrors.IsConflict(err) {
+		return fmt.Errorf("ConfigMap %s/%s was changed by another field manager: %w", existing.Namespace, existing.Name, err)
+	}
+
+	return err
+}
+
+// releaseTarget removes the owner reference to the BitwardenSecret from the existing object of a target that is no
+// longer owned, like the main secret once ownerReference is turned off.  The reference was written by a create rather
+// than an apply, so leaving it out of the applied object would not remove it.
+func releaseTarget(ctx context.Context, c client.Client, bwSecret *operatorsv1.BitwardenSecret, existing client.Object, rendered client.Object) error {
+	if metav1.IsControlledBy(rendered, bwSecret) {
+		return nil
+	}
+
+	original := existing.DeepCopyObject().(client.Object)
+	if !RemoveOwnerReference(bwSecret, existing) {
+		return nil
+	}
+
+	return c.Patch(ctx, existing, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+}
+
+// appliedBy reports whether the field manager has written the object with server-side apply before.
+func appliedBy(obj metav1.Object, manager string) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == manager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PruneTargetSecrets deletes the secrets and ConfigMaps of targets that are no longer in the spec of the
+// BitwardenSecret, including those whose target changed its kind or moved to another cluster.
+func (r *BitwardenSecretReconciler) PruneTargetSecrets(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret) error {
+	var local []operatorsv1.SecretTarget
+	for _, target := range bwSecret.Spec.Targets {
+		if target.ClusterRef == nil {
+			local = append(local, target)
+		}
+	}
+
+	return pruneTargets(ctx, r.Client, bwSecret.Namespace, bwSecret, local)
+}
+
+// pruneTargets deletes the secrets and ConfigMaps written for the BitwardenSecret in the namespace with the client that
+// are not objects of the targets.
+func pruneTargets(ctx context.Context, c client.Client, namespace string, bwSecret *operatorsv1.BitwardenSecret, targets []operatorsv1.SecretTarget) error {
+	wantedSecrets := map[string]bool{}
+	wantedConfigMaps := map[string]bool{}
+	for _, target := range targets {
+		if IsConfigMapTarget(target) {
+			wantedConfigMaps[target.SecretName] = true
+		} else {
+			wantedSecrets[target.SecretName] = true
+		}
+	}
+
+	selector := []client.ListOption{
+		client.InNamespace(namespace),
+		client.MatchingLabels{
+			TargetOfLabel:                 bwSecret.Name,
+			"k8s.bitwarden.com/bw-secret": string(bwSecret.UID),
+		},
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, selector...); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		if !wantedSecrets[secrets.Items[i].Name] {
+			if err := c.Delete(ctx, &secrets.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(ctx, configMaps, selector...); err != nil {
+		return err
+	}
+	for i := range configMaps.Items {
+		if !wantedConfigMaps[configMaps.Items[i].Name] {
+			if err := c.Delete(ctx, &configMaps.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"text/template"
+
+	sprig "github.com/go-task/slim-sprig"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Condition set while a template of the BitwardenSecret fails to render
+const TemplateErrorCondition = "TemplateError"
+
+// templateFuncs are available to secret templates in addition to the text/template builtins and secret, which returns
+// a pulled value by secret ID.  They are the repeatable functions of the sprig library, so that a template renders
+// the same secret on every sync and cannot read the environment of the operator.  b64dec fails on invalid input
+// instead of rendering the error.
+var templateFuncs = func() template.FuncMap {
+	funcs := sprig.HermeticTxtFuncMap()
+	funcs["b64enc"] = func(value string) string { return base64.StdEncoding.EncodeToString([]byte(value)) }
+	funcs["b64dec"] = func(value string) (string, error) {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		return string(decoded), err
+	}
+	return funcs
+}()
+
+// templateContext is what a secret template is executed against
+type templateContext struct {
+	// The pulled values by secret ID
+	Secrets map[string]string
+	// The keys of the Kubernetes secret written before the templates are rendered
+	Data map[string]string
+}
+
+// ApplyTemplate renders the templates of the BitwardenSecret into the secret.  It does nothing when no template is
+// set, and writes nothing when any template fails.
+func ApplyTemplate(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte, secret *corev1.Secret) error {
+	if bwSecret.Spec.Template == nil || len(bwSecret.Spec.Template.Data) == 0 {
+		return nil
+	}
+
+	rendered, err := RenderTemplates(bwSecret.Spec.Template.Data, secrets, secret.Data)
+	if err != nil {
+		return err
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	for key, value := range rendered {
+		secret.Data[key] = value
+	}
+
+	return nil
+}
+
+// RenderTemplates renders every template, keyed by the secret key it is written to.  Missing values are errors rather
+// than empty strings, so a typo cannot silently produce a broken connection string.
+func RenderTemplates(templates map[string]string, secrets map[string][]byte, data map[string][]byte) (map[string][]byte, error) {
+	values := templateContext{Secrets: map[string]string{}, Data: map[string]string{}}
+	for id, value := range secrets {
+		values.Secrets[id] = string(value)
+	}
+	for key, value := range data {
+		values.Data[key] = string(value)
+	}
+
+	// Render in a stable order so the reported error does not change between syncs
+	keys := make([]string, 0, len(templates))
+	for key := range templates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rendered := make(map[string][]byte, len(templates))
+	for _, key := range keys {
+		tmpl, err := template.New(key).Funcs(templateFuncs).Funcs(template.FuncMap{
+			"secret": func(id string) (string, error) {
+				value, ok := values.Secrets[id]
+				if !ok {
+					return "", fmt.Errorf("secret %s was not pulled from Secrets Manager", id)
+				}
+				return value, nil
+			},
+		}).Option("missingkey=error").Parse(templates[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the template of %s: %w", key, err)
+		}
+
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, values); err != nil {
+			return nil, fmt.Errorf("failed to render the template of %s: %w", key, err)
+		}
+		rendered[key] = out.Bytes()
+	}
+
+	return rendered, nil
+}
+
+// SetTemplateErrorCondition sets or clears the condition reporting a template that failed to render.
+func SetTemplateErrorCondition(bwSecret *operatorsv1.BitwardenSecret, err error) {
+	if err == nil {
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, TemplateErrorCondition)
+		return
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "RenderFailed",
+		Message: err.Error(),
+		Type:    TemplateErrorCondition,
+	})
+}
@@ -12,7 +12,7 @@ package controller_test_mocks
 import (
 	reflect "reflect"
 
-	sdk "github.com/bitwarden/sdk-go"
+	bwclient "github.com/bitwarden/sm-kubernetes/internal/bwclient"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -54,10 +54,10 @@ func (mr *MockBitwardenClientFactoryMockRecorder) GetApiUrl() *gomock.Call {
 }
 
 // GetBitwardenClient mocks base method.
-func (m *MockBitwardenClientFactory) GetBitwardenClient() (sdk.BitwardenClientInterface, error) {
+func (m *MockBitwardenClientFactory) GetBitwardenClient() (bwclient.BitwardenClientInterface, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetBitwardenClient")
-	ret0, _ := ret[0].(sdk.BitwardenClientInterface)
+	ret0, _ := ret[0].(bwclient.BitwardenClientInterface)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -0,0 +1,164 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Condition set while the certificate and key of a TLS secret are invalid.  The secret is not written until they are
+// fixed.
+const InvalidTLSCondition = "InvalidTLS"
+
+// Key of the certificate authority in a TLS secret, as used by cert-manager and ingress controllers
+const tlsCAKey = "ca.crt"
+
+// ApplyTLS writes the certificate, private key, and certificate authority of the BitwardenSecret's TLS template to the
+// secret.  Without a map only the TLS keys are written.  It does nothing when no template is set.
+func ApplyTLS(bwSecret *operatorsv1.BitwardenSecret, secrets map[string][]byte, secret *corev1.Secret) error {
+	template := bwSecret.Spec.TLS
+	if template == nil {
+		return nil
+	}
+
+	certificate, err := tlsValue(secrets, template.CertificateSecretId, "certificate")
+	if err != nil {
+		return err
+	}
+
+	key, err := tlsValue(secrets, template.PrivateKeySecretId, "private key")
+	if err != nil {
+		return err
+	}
+
+	if bwSecret.Spec.SecretMap == nil || secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[corev1.TLSCertKey] = certificate
+	secret.Data[corev1.TLSPrivateKeyKey] = key
+
+	if template.CertificateAuthoritySecretId != "" {
+		ca, err := tlsValue(secrets, template.CertificateAuthoritySecretId, "certificate authority")
+		if err != nil {
+			return err
+		}
+		secret.Data[tlsCAKey] = ca
+	}
+
+	return nil
+}
+
+// ValidateTLS checks that tls.crt and tls.key hold PEM encoded certificates and a private key that belong together,
+// and that ca.crt, when present, holds PEM encoded certificates.
+func ValidateTLS(data map[string][]byte) error {
+	certificate, key := data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]
+	if len(certificate) == 0 || len(key) == 0 {
+		return fmt.Errorf("a secret of type %s requires the %s and %s keys", corev1.SecretTypeTLS, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+
+	if err := validateCertificates(certificate, corev1.TLSCertKey); err != nil {
+		return err
+	}
+
+	if block, _ := pem.Decode(key); block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		return fmt.Errorf("%s does not hold a PEM encoded private key", corev1.TLSPrivateKeyKey)
+	}
+
+	if _, err := tls.X509KeyPair(certificate, key); err != nil {
+		return fmt.Errorf("%s and %s do not match: %w", corev1.TLSCertKey, corev1.TLSPrivateKeyKey, err)
+	}
+
+	if ca, ok := data[tlsCAKey]; ok {
+		if err := validateCertificates(ca, tlsCAKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetInvalidTLSCondition sets or clears the condition reporting an invalid TLS secret.
+func SetInvalidTLSCondition(bwSecret *operatorsv1.BitwardenSecret, err error) {
+	if err == nil {
+		apimeta.RemoveStatusCondition(&bwSecret.Status.Conditions, InvalidTLSCondition)
+		return
+	}
+
+	apimeta.SetStatusCondition(&bwSecret.Status.Conditions, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  "ValidationFailed",
+		Message: err.Error(),
+		Type:    InvalidTLSCondition,
+	})
+}
+
+// validateCertificates checks that the value holds only PEM encoded certificates, and at least one.
+func validateCertificates(value []byte, key string) error {
+	count := 0
+	for rest := value; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			if strings.TrimSpace(string(rest)) != "" {
+				return fmt.Errorf("%s holds data that is not PEM encoded", key)
+			}
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("%s holds a %s block instead of a certificate", key, block.Type)
+		}
+
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("%s holds an invalid certificate: %w", key, err)
+		}
+		count++
+	}
+
+	if count == 0 {
+		return fmt.Errorf("%s does not hold a PEM encoded certificate", key)
+	}
+
+	return nil
+}
+
+func tlsValue(secrets map[string][]byte, id string, field string) ([]byte, error) {
+	if id == "" {
+		return nil, fmt.Errorf("the TLS %s secret ID is not set", field)
+	}
+
+	value, ok := secrets[id]
+	if !ok {
+		return nil, fmt.Errorf("the TLS %s secret %s is not accessible by the machine account", field, id)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,111 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/hex"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Label on versioned secrets naming the alias secret they belong to
+const VersionOfLabel = "k8s.bitwarden.com/version-of"
+
+// Key of the alias secret holding the name of the active version
+const VersionAliasKey = "version"
+
+// VersionedSecretName names a version after its contents, so that an unchanged sync maps to the existing version.
+func VersionedSecretName(secretName string, data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	digest := NewHash()
+	for _, key := range keys {
+		digest.Write([]byte(key))
+		digest.Write([]byte{0})
+		digest.Write(data[key])
+		digest.Write([]byte{0})
+	}
+
+	return secretName + "-" + hex.EncodeToString(digest.Sum(nil))[:10]
+}
+
+// WriteSecretVersion stores data in an immutable versioned secret and returns its name.
+func (r *BitwardenSecretReconciler) WriteSecretVersion(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, data map[string][]byte) (string, error) {
+	immutable := true
+	version := CreateK8sSecret(bwSecret)
+	version.Name = VersionedSecretName(bwSecret.Spec.SecretName, data)
+	version.Labels[VersionOfLabel] = bwSecret.Spec.SecretName
+	version.Type = TargetSecretType(bwSecret)
+	version.Data = data
+	version.Immutable = &immutable
+
+	if err := ctrl.SetControllerReference(bwSecret, version, r.Scheme); err != nil {
+		return "", err
+	}
+
+	if err := r.Create(ctx, version); err != nil && !errors.IsAlreadyExists(err) {
+		return "", err
+	}
+
+	return version.Name, nil
+}
+
+// PruneSecretVersions deletes all but the active version and the newest spec.versioning.keep previous versions.
+func (r *BitwardenSecretReconciler) PruneSecretVersions(ctx context.Context, bwSecret *operatorsv1.BitwardenSecret, active string) error {
+	versions := &corev1.SecretList{}
+	err := r.List(ctx, versions, client.InNamespace(bwSecret.Namespace), client.MatchingLabels{
+		"k8s.bitwarden.com/bw-secret": string(bwSecret.UID),
+		VersionOfLabel:                bwSecret.Spec.SecretName,
+	})
+	if err != nil {
+		return err
+	}
+
+	previous := []corev1.Secret{}
+	for _, version := range versions.Items {
+		if version.Name != active {
+			previous = append(previous, version)
+		}
+	}
+
+	sort.Slice(previous, func(i, j int) bool {
+		return previous[j].CreationTimestamp.Before(&previous[i].CreationTimestamp)
+	})
+
+	for i := bwSecret.Spec.Versioning.Keep; i < len(previous); i++ {
+		if err := r.Delete(ctx, &previous[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
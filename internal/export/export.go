@@ -0,0 +1,175 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+// Package export renders the secret a BitwardenSecret would produce as a manifest that can be committed to a GitOps
+// repository, either in plain form for an external encryption tool or sealed for the Sealed Secrets controller.
+package export
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/controller"
+)
+
+const (
+	// A plain Secret manifest, for example to pipe into sops --encrypt
+	FormatSecret = "secret"
+	// A SealedSecret manifest encrypted for a Sealed Secrets controller
+	FormatSealedSecret = "sealedsecret"
+)
+
+// Options selects the BitwardenSecret to export and how the output is encoded.
+type Options struct {
+	Namespace string
+	Name      string
+	Format    string
+	// PEM encoded certificate or public key of the Sealed Secrets controller, as printed by kubeseal --fetch-cert
+	CertPath string
+}
+
+// ParseArgs parses the command line of the export command.
+func ParseArgs(args []string) (*Options, error) {
+	opts := &Options{}
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.StringVar(&opts.Namespace, "namespace", "default", "Namespace of the BitwardenSecret to export.")
+	flags.StringVar(&opts.Name, "name", "", "Name of the BitwardenSecret to export.")
+	flags.StringVar(&opts.Format, "format", FormatSealedSecret,
+		fmt.Sprintf("Output format, %q or %q.", FormatSealedSecret, FormatSecret))
+	flags.StringVar(&opts.CertPath, "cert", "",
+		"Path to the Sealed Secrets controller certificate, as printed by kubeseal --fetch-cert. Required for the sealedsecret format.")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("--name is required")
+	}
+
+	switch opts.Format {
+	case FormatSecret:
+	case FormatSealedSecret:
+		if opts.CertPath == "" {
+			return nil, fmt.Errorf("--cert is required for the %s format", FormatSealedSecret)
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected %q or %q", opts.Format, FormatSealedSecret, FormatSecret)
+	}
+
+	return opts, nil
+}
+
+// Export pulls every secret the BitwardenSecret's machine account can access, renders the Kubernetes secret the
+// operator would write and writes it to out as a YAML manifest, preceded by the ConfigMap of non-sensitive values if
+// there are any.  Nothing is written to the cluster.
+func Export(ctx context.Context, k8sClient client.Reader, factory controller.BitwardenClientFactory, statePath string, opts *Options, out io.Writer) error {
+	bwSecret := &operatorsv1.BitwardenSecret{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: opts.Namespace, Name: opts.Name}, bwSecret); err != nil {
+		return err
+	}
+
+	// Tokens mounted into the operator pod are not available to the export command
+	authToken, err := controller.ReadAuthToken(ctx, k8sClient, bwSecret, controller.AuthTokenFiles{})
+	if err != nil {
+		return err
+	}
+
+	factory, err = controller.ResolveClientFactory(ctx, k8sClient, factory, bwSecret)
+	if err != nil {
+		return err
+	}
+
+	reconciler := &controller.BitwardenSecretReconciler{
+		BitwardenClientFactory: factory,
+		StatePath:              statePath,
+	}
+
+	// A zero last sync time always returns every secret
+	_, secrets, _, err := reconciler.PullSecretManagerSecretDeltas(ctx, ctrl.Log.WithName("export"), bwSecret.Spec.OrganizationId, authToken, time.Time{}, controller.SelectionFor(bwSecret))
+	if err != nil {
+		return err
+	}
+
+	secret, err := controller.RenderK8sSecret(bwSecret, secrets)
+	if err != nil {
+		return err
+	}
+	// The exported objects are delivered by GitOps and are not owned by this BitwardenSecret
+	secret.Labels = nil
+	secret.Annotations = nil
+
+	// Non-sensitive values are exported as a plain ConfigMap next to the secret
+	manifests := []interface{}{}
+	if configMap := controller.SplitConfigMap(bwSecret, secret); configMap != nil {
+		configMap.Labels = nil
+		manifests = append(manifests, configMap)
+	}
+
+	var manifest interface{} = secret
+	if opts.Format == FormatSealedSecret {
+		pem, err := os.ReadFile(opts.CertPath)
+		if err != nil {
+			return err
+		}
+
+		publicKey, err := ParsePublicKey(pem)
+		if err != nil {
+			return err
+		}
+
+		manifest, err = SealSecret(secret, publicKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifests = append(manifests, manifest)
+
+	for i, manifest := range manifests {
+		bytes, err := yaml.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			if _, err := io.WriteString(out, "---\n"); err != nil {
+				return err
+			}
+		}
+
+		if _, err := out.Write(bytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package export
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bitwarden/sm-kubernetes/internal/controller"
+)
+
+// Size of the AES-256 session key encrypted for the controller
+const sessionKeyBytes = 32
+
+// SealedSecret is the subset of the bitnami.com/v1alpha1 SealedSecret resource written by the export.
+type SealedSecret struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Metadata   SealedSecretMeta `json:"metadata"`
+	Spec       SealedSecretSpec `json:"spec"`
+}
+
+type SealedSecretMeta struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type SealedSecretSpec struct {
+	EncryptedData map[string]string    `json:"encryptedData"`
+	Template      SealedSecretTemplate `json:"template"`
+}
+
+type SealedSecretTemplate struct {
+	Metadata SealedSecretMeta  `json:"metadata"`
+	Type     corev1.SecretType `json:"type"`
+}
+
+// ParsePublicKey reads the RSA public key of a Sealed Secrets controller from a PEM encoded certificate or public key.
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	case "PUBLIC KEY":
+		var err error
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the Sealed Secrets key must be an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// SealSecret encrypts every value of the secret for the Sealed Secrets controller owning publicKey.  The values use
+// the strict scope, so the controller only unseals them into a secret with the same name and namespace.
+func SealSecret(secret *corev1.Secret, publicKey *rsa.PublicKey) (*SealedSecret, error) {
+	meta := SealedSecretMeta{Name: secret.Name, Namespace: secret.Namespace}
+	label := []byte(fmt.Sprintf("%s/%s", secret.Namespace, secret.Name))
+
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	encrypted := make(map[string]string, len(secret.Data))
+	for _, key := range keys {
+		ciphertext, err := hybridEncrypt(rand.Reader, publicKey, secret.Data[key], label)
+		if err != nil {
+			return nil, err
+		}
+		encrypted[key] = base64.StdEncoding.EncodeToString(ciphertext)
+	}
+
+	return &SealedSecret{
+		APIVersion: "bitnami.com/v1alpha1",
+		Kind:       "SealedSecret",
+		Metadata:   meta,
+		Spec: SealedSecretSpec{
+			EncryptedData: encrypted,
+			Template: SealedSecretTemplate{
+				Metadata: meta,
+				Type:     secret.Type,
+			},
+		},
+	}, nil
+}
+
+// hybridEncrypt implements the Sealed Secrets value format: the length prefixed RSA-OAEP encryption of a random
+// session key, followed by the AES-GCM encryption of the value with that key.
+func hybridEncrypt(rnd io.Reader, publicKey *rsa.PublicKey, plaintext []byte, label []byte) ([]byte, error) {
+	sessionKey := make([]byte, sessionKeyBytes)
+	if _, err := io.ReadFull(rnd, sessionKey); err != nil {
+		return nil, err
+	}
+
+	// The format requires SHA-256, which is also the operator's approved digest
+	rsaCiphertext, err := rsa.EncryptOAEP(controller.NewHash(), rnd, publicKey, sessionKey, label)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, 2)
+	binary.BigEndian.PutUint16(ciphertext, uint16(len(rsaCiphertext)))
+	ciphertext = append(ciphertext, rsaCiphertext...)
+
+	aead, err := controller.NewStateCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every session key encrypts a single value, so a zero nonce is never reused
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(ciphertext, nonce, plaintext, nil), nil
+}
@@ -0,0 +1,205 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/bwclient"
+	controller_test_mocks "github.com/bitwarden/sm-kubernetes/internal/controller/test_mocks"
+)
+
+func TestExport(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Export Suite")
+}
+
+// hybridDecrypt reverses hybridEncrypt the way the Sealed Secrets controller does.
+func hybridDecrypt(privateKey *rsa.PrivateKey, ciphertext []byte, label []byte) ([]byte, error) {
+	rsaLen := int(binary.BigEndian.Uint16(ciphertext))
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), nil, privateKey, ciphertext[2:2+rsaLen], label)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext[2+rsaLen:], nil)
+}
+
+var _ = Describe("Export", func() {
+	var privateKey *rsa.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).Should(BeNil())
+	})
+
+	It("Validates the command line", func() {
+		_, err := ParseArgs([]string{"--format", FormatSecret})
+		Expect(err).ShouldNot(BeNil())
+
+		_, err = ParseArgs([]string{"--name", "bw-secret"})
+		Expect(err).ShouldNot(BeNil())
+
+		_, err = ParseArgs([]string{"--name", "bw-secret", "--format", "sops"})
+		Expect(err).ShouldNot(BeNil())
+
+		opts, err := ParseArgs([]string{"--name", "bw-secret", "--namespace", "apps", "--cert", "cert.pem"})
+		Expect(err).Should(BeNil())
+		Expect(*opts).Should(Equal(Options{Namespace: "apps", Name: "bw-secret", Format: FormatSealedSecret, CertPath: "cert.pem"}))
+	})
+
+	It("Reads the public key from a certificate or a public key", func() {
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "sealed-secret"},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+		Expect(err).Should(BeNil())
+
+		key, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+		Expect(err).Should(BeNil())
+		Expect(key.Equal(&privateKey.PublicKey)).Should(BeTrue())
+
+		der, err = x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+		Expect(err).Should(BeNil())
+		key, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		Expect(err).Should(BeNil())
+		Expect(key.Equal(&privateKey.PublicKey)).Should(BeTrue())
+
+		_, err = ParsePublicKey([]byte("not a key"))
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("Seals values for the target secret only", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "apps"},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"password": []byte("hunter2")},
+		}
+
+		sealed, err := SealSecret(secret, &privateKey.PublicKey)
+		Expect(err).Should(BeNil())
+		Expect(sealed.Kind).Should(Equal("SealedSecret"))
+		Expect(sealed.Spec.Template.Metadata.Name).Should(Equal("app-secret"))
+		Expect(sealed.Spec.Template.Type).Should(Equal(corev1.SecretTypeOpaque))
+
+		ciphertext, err := base64.StdEncoding.DecodeString(sealed.Spec.EncryptedData["password"])
+		Expect(err).Should(BeNil())
+
+		plaintext, err := hybridDecrypt(privateKey, ciphertext, []byte("apps/app-secret"))
+		Expect(err).Should(BeNil())
+		Expect(string(plaintext)).Should(Equal("hunter2"))
+
+		_, err = hybridDecrypt(privateKey, ciphertext, []byte("other/app-secret"))
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("Renders the mapped secret without writing to the cluster", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).Should(Succeed())
+		Expect(operatorsv1.AddToScheme(scheme)).Should(Succeed())
+
+		bwSecret := &operatorsv1.BitwardenSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bw-secret", Namespace: "apps"},
+			Spec: operatorsv1.BitwardenSecretSpec{
+				OrganizationId: "org",
+				SecretName:     "app-secret",
+				AuthToken:      operatorsv1.AuthToken{SecretName: "bw-token", SecretKey: "token"},
+				SecretMap:      []operatorsv1.SecretMap{{BwSecretId: "id-1", SecretKeyName: "PASSWORD"}},
+			},
+		}
+		authSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bw-token", Namespace: "apps"},
+			Data:       map[string][]byte{"token": []byte("access-token")},
+		}
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bwSecret, authSecret).Build()
+
+		mockCtrl := gomock.NewController(GinkgoT())
+		mockFactory := controller_test_mocks.NewMockBitwardenClientFactory(mockCtrl)
+		mockClient := controller_test_mocks.NewMockBitwardenClientInterface(mockCtrl)
+		mockSecrets := controller_test_mocks.NewMockSecretsInterface(mockCtrl)
+
+		mockFactory.EXPECT().GetBitwardenClient().Return(mockClient, nil)
+		mockClient.EXPECT().AccessTokenLogin("access-token", gomock.Any()).Return(nil)
+		mockClient.EXPECT().Secrets().Return(mockSecrets)
+		mockSecrets.EXPECT().Sync("org", gomock.Any()).Return(&bwclient.SecretsSyncResponse{
+			HasChanges: true,
+			Secrets: []bwclient.SecretResponse{
+				{ID: "id-1", Value: "hunter2"},
+				{ID: "id-2", Value: "unmapped"},
+			},
+		}, nil)
+		mockClient.EXPECT().Close()
+
+		out := &bytes.Buffer{}
+		opts := &Options{Namespace: "apps", Name: "bw-secret", Format: FormatSecret}
+		Expect(Export(context.Background(), k8sClient, mockFactory, GinkgoT().TempDir(), opts, out)).Should(Succeed())
+
+		exported := &corev1.Secret{}
+		Expect(yaml.Unmarshal(out.Bytes(), exported)).Should(Succeed())
+		Expect(exported.Name).Should(Equal("app-secret"))
+		Expect(exported.Namespace).Should(Equal("apps"))
+		Expect(exported.Labels).Should(BeEmpty())
+		Expect(exported.Data).Should(Equal(map[string][]byte{"PASSWORD": []byte("hunter2")}))
+
+		// The rendered secret is only printed
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "app-secret"}, &corev1.Secret{})).ShouldNot(Succeed())
+	})
+})
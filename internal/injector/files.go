@@ -0,0 +1,369 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package injector
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Annotation of pods selecting how secrets are injected, InjectModeEnv or InjectModeFiles
+const InjectModeAnnotation = "k8s.bitwarden.com/inject-mode"
+
+// Annotation of pods in files mode holding the directory the files are mounted at.  Defaults to DefaultInjectPath.
+const InjectPathAnnotation = "k8s.bitwarden.com/inject-path"
+
+// Annotation of pods in files mode holding the template of the file names, for example {{ .Key | lower }}.pem
+const InjectFileNameAnnotation = "k8s.bitwarden.com/inject-file-name"
+
+// Annotation of pods in files mode holding the octal mode of the files.  Defaults to DefaultFileMode.
+const InjectFileModeAnnotation = "k8s.bitwarden.com/inject-file-mode"
+
+// Annotation of pods in files mode that, when "true", keeps the files in memory instead of on the node disk
+const InjectTmpfsAnnotation = "k8s.bitwarden.com/inject-tmpfs"
+
+const (
+	InjectModeEnv   = "env"
+	InjectModeFiles = "files"
+)
+
+const DefaultInjectPath = "/bitwarden/secrets"
+
+const DefaultFileMode os.FileMode = 0400
+
+// Mode of files shared by containers running as different users, which read them through the fsGroup of the pod
+const sharedFileMode os.FileMode = 0440
+
+// Name of the init container rendering the files, and of the volume holding them
+const RenderContainerName = "bitwarden-render-files"
+
+const filesVolumeName = "bitwarden-secrets"
+
+const sourceVolumePrefix = "bitwarden-source-"
+
+const sourceMountPath = "/bitwarden/source"
+
+var (
+	allowPrivilegeEscalation = false
+	readOnlyRootFilesystem   = true
+	runAsNonRoot             = true
+)
+
+var fileNameFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	// replace is ordered for pipelines: {{ .Key | replace "_" "-" }}
+	"replace": func(old string, new string, value string) string {
+		return strings.ReplaceAll(value, old, new)
+	},
+}
+
+// FileOptions are the settings of the render-files init container.
+type FileOptions struct {
+	// Directories of the mounted Kubernetes secrets, in the order of the inject annotation
+	Sources []string
+	// Directory the files are written to
+	Target string
+	// Template of the file names, executed with the key of each value as .Key
+	FileName string
+	Mode     os.FileMode
+	// Keys to write.  Every key is written when empty.
+	Keys []string
+}
+
+// ParseFileName parses a file name template.  Empty templates name files after their key.
+func ParseFileName(text string) (*template.Template, error) {
+	if text == "" {
+		text = "{{ .Key }}"
+	}
+
+	return template.New("file-name").Funcs(fileNameFuncs).Option("missingkey=error").Parse(text)
+}
+
+// ParseFileMode parses an octal file mode such as 0440.  Empty values default to DefaultFileMode.
+func ParseFileMode(text string) (os.FileMode, error) {
+	if text == "" {
+		return DefaultFileMode, nil
+	}
+
+	mode, err := strconv.ParseUint(text, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode %q, expected an octal mode such as 0400", text)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// InjectFiles adds an init container to the pod that renders the secrets to files in a shared emptyDir volume, and
+// mounts that volume read only into the containers accepted by selected.  Pods that already have the init container
+// are left alone.
+func InjectFiles(pod *corev1.Pod, image string, secretNames []string, selected func(*corev1.Container) bool) error {
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == RenderContainerName {
+			return nil
+		}
+	}
+
+	fileName := pod.Annotations[InjectFileNameAnnotation]
+	if _, err := ParseFileName(fileName); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", InjectFileNameAnnotation, err)
+	}
+	mode, err := ParseFileMode(pod.Annotations[InjectFileModeAnnotation])
+	if err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", InjectFileModeAnnotation, err)
+	}
+	path := pod.Annotations[InjectPathAnnotation]
+	if path == "" {
+		path = DefaultInjectPath
+	}
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("invalid %s annotation: %q is not an absolute path", InjectPathAnnotation, path)
+	}
+
+	// The files belong to the user the render container runs as, so it runs as the user of the selected containers.
+	// Containers running as different users share the files through the fsGroup of the pod instead.
+	user, group, shared := selectedUser(pod, selected)
+	if !shared && pod.Annotations[InjectFileModeAnnotation] == "" {
+		mode = sharedFileMode
+	}
+
+	filesVolume := corev1.Volume{Name: filesVolumeName, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	if pod.Annotations[InjectTmpfsAnnotation] == "true" {
+		filesVolume.EmptyDir.Medium = corev1.StorageMediumMemory
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, filesVolume)
+
+	render := corev1.Container{
+		Name:  RenderContainerName,
+		Image: image,
+		Args: []string{
+			"render-files",
+			"--target=" + DefaultInjectPath,
+			"--file-name=" + fileName,
+			fmt.Sprintf("--file-mode=%04o", mode),
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: filesVolumeName, MountPath: DefaultInjectPath}},
+		// Admissible under the restricted pod security standard
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			ReadOnlyRootFilesystem:   &readOnlyRootFilesystem,
+			RunAsNonRoot:             &runAsNonRoot,
+			RunAsUser:                user,
+			RunAsGroup:               group,
+			SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+	}
+	if keys := pod.Annotations[InjectKeysAnnotation]; keys != "" {
+		render.Args = append(render.Args, "--keys="+keys)
+	}
+
+	for i, secretName := range secretNames {
+		volumeName := fmt.Sprintf("%s%d", sourceVolumePrefix, i)
+		mountPath := fmt.Sprintf("%s/%d", sourceMountPath, i)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{Name: volumeName, VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: secretName},
+		}})
+		render.VolumeMounts = append(render.VolumeMounts, corev1.VolumeMount{Name: volumeName, MountPath: mountPath, ReadOnly: true})
+		render.Args = append(render.Args, "--source="+mountPath)
+	}
+
+	// The files must be in place before any other init container starts
+	pod.Spec.InitContainers = append([]corev1.Container{render}, pod.Spec.InitContainers...)
+
+	for c := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[c]
+		if selected(container) {
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: filesVolumeName, MountPath: path, ReadOnly: true})
+		}
+	}
+
+	return nil
+}
+
+// selectedUser returns the user and group the containers accepted by selected run as, taking the security context of
+// the pod into account.  shared is false when they run as different users.  Containers running as root, or as the
+// user of the image, need no user of their own.
+func selectedUser(pod *corev1.Pod, selected func(*corev1.Container) bool) (user *int64, group *int64, shared bool) {
+	first := true
+	for c := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[c]
+		if !selected(container) {
+			continue
+		}
+
+		containerUser, containerGroup := effectiveUser(pod, container)
+		if first {
+			user, group, first = containerUser, containerGroup, false
+			continue
+		}
+
+		if !equalID(user, containerUser) || !equalID(group, containerGroup) {
+			return nil, nil, false
+		}
+	}
+
+	if user != nil && *user == 0 {
+		return nil, nil, true
+	}
+
+	return user, group, true
+}
+
+// effectiveUser returns the user and group a container runs as, as far as they are set by the container or the pod.
+func effectiveUser(pod *corev1.Pod, container *corev1.Container) (user *int64, group *int64) {
+	if podContext := pod.Spec.SecurityContext; podContext != nil {
+		user, group = podContext.RunAsUser, podContext.RunAsGroup
+	}
+
+	if containerContext := container.SecurityContext; containerContext != nil {
+		if containerContext.RunAsUser != nil {
+			user = containerContext.RunAsUser
+		}
+		if containerContext.RunAsGroup != nil {
+			group = containerContext.RunAsGroup
+		}
+	}
+
+	return user, group
+}
+
+func equalID(a *int64, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// RunRenderFiles implements the render-files command run by the injected init container.
+func RunRenderFiles(args []string) error {
+	opts := FileOptions{}
+	var sources stringList
+	var mode string
+	var keys string
+
+	flags := flag.NewFlagSet("render-files", flag.ContinueOnError)
+	flags.Var(&sources, "source", "Directory of a mounted Kubernetes secret. May be repeated; earlier sources win.")
+	flags.StringVar(&opts.Target, "target", DefaultInjectPath, "Directory the files are written to.")
+	flags.StringVar(&opts.FileName, "file-name", "", "Template of the file names, executed with the key of each value as .Key.")
+	flags.StringVar(&mode, "file-mode", "", "Octal mode of the files.")
+	flags.StringVar(&keys, "keys", "", "Comma separated keys to write. Every key is written when empty.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	opts.Sources = sources
+	opts.Keys = splitList(keys)
+	if opts.Mode, err = ParseFileMode(mode); err != nil {
+		return err
+	}
+
+	return RenderFiles(opts)
+}
+
+// RenderFiles writes the values of the mounted secrets to files named with the file name template.
+func RenderFiles(opts FileOptions) error {
+	fileName, err := ParseFileName(opts.FileName)
+	if err != nil {
+		return err
+	}
+
+	values := map[string][]byte{}
+	for i := len(opts.Sources) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(opts.Sources[i])
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			// Secret volumes keep their data in hidden directories that the keys link to
+			if strings.HasPrefix(entry.Name(), ".") || entry.IsDir() {
+				continue
+			}
+
+			value, err := os.ReadFile(filepath.Join(opts.Sources[i], entry.Name()))
+			if err != nil {
+				return err
+			}
+			values[entry.Name()] = value
+		}
+	}
+
+	keys := opts.Keys
+	if len(keys) == 0 {
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+
+	for _, key := range keys {
+		value, ok := values[key]
+		if !ok {
+			return fmt.Errorf("key %s is not in any of the injected secrets", key)
+		}
+
+		name := &bytes.Buffer{}
+		if err := fileName.Execute(name, map[string]string{"Key": key}); err != nil {
+			return fmt.Errorf("file name of %s: %w", key, err)
+		}
+		if !filepath.IsLocal(name.String()) {
+			return fmt.Errorf("file name %q of %s is not a relative path within the target directory", name.String(), key)
+		}
+
+		path := filepath.Join(opts.Target, name.String())
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		// Files of an earlier run of the init container may not be writable
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.WriteFile(path, value, 0600); err != nil {
+			return err
+		}
+		// WriteFile is subject to the umask
+		if err := os.Chmod(path, opts.Mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stringList collects the values of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,232 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+// Package injector contains the admission webhook that injects the Kubernetes secrets of BitwardenSecrets into pods
+// as environment variables.
+package injector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// Label pods opt in to injection with, set to InjectionEnabled.  The webhook is only called for pods with the label,
+// so that it can reject pods when the operator is unavailable without holding up every other pod of the cluster.
+const InjectionLabel = "k8s.bitwarden.com/injection"
+
+// Value of InjectionLabel that opts pods in to injection
+const InjectionEnabled = "enabled"
+
+// Annotation of pods naming the BitwardenSecrets, separated by commas, whose secrets are injected
+const InjectAnnotation = "k8s.bitwarden.com/inject"
+
+// Annotation of pods naming the containers, separated by commas, that are injected into.  Defaults to every container.
+const InjectContainersAnnotation = "k8s.bitwarden.com/inject-containers"
+
+// Annotation of pods naming the keys, separated by commas, that are injected as single variables instead of the whole
+// secret
+const InjectKeysAnnotation = "k8s.bitwarden.com/inject-keys"
+
+var injectorlog = logf.Log.WithName("pod-injector")
+
+// SetupWebhookWithManager registers the pod injection webhook.  Files are injected by init containers running image,
+// normally the operator image.  File injection is disabled when image is empty.
+func SetupWebhookWithManager(mgr ctrl.Manager, image string) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&corev1.Pod{}).
+		WithDefaulter(&PodInjector{Reader: mgr.GetClient(), Image: image}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod.k8s.bitwarden.com,admissionReviewVersions=v1
+
+// PodInjector adds environment variables referencing the Kubernetes secret of a BitwardenSecret to the containers of
+// pods labelled with InjectionLabel and annotated with InjectAnnotation, so that application teams do not have to wire
+// up every secretKeyRef.
+// +kubebuilder:object:generate=false
+type PodInjector struct {
+	Reader client.Reader
+	// Image of the init container rendering injected files.  File injection is disabled when empty.
+	Image string
+}
+
+var _ webhook.CustomDefaulter = &PodInjector{}
+
+// Default injects the secrets named by the annotations of the pod.  Pods naming a BitwardenSecret that does not exist
+// are rejected, so that a typo fails the rollout instead of starting pods without their secrets.
+func (i *PodInjector) Default(ctx context.Context, obj runtime.Object) error {
+	pod := obj.(*corev1.Pod)
+	// Webhook configurations without the object selector also send pods that did not opt in
+	if pod.Labels[InjectionLabel] != InjectionEnabled {
+		return nil
+	}
+
+	names := splitList(pod.Annotations[InjectAnnotation])
+	if len(names) == 0 {
+		return nil
+	}
+
+	// The namespace of pods created by controllers is only set on the request
+	namespace := pod.Namespace
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Namespace != "" {
+		namespace = req.Namespace
+	}
+
+	secretNames := make([]string, 0, len(names))
+	for _, name := range names {
+		bwSecret := &operatorsv1.BitwardenSecret{}
+		if err := i.Reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, bwSecret); err != nil {
+			return fmt.Errorf("unable to inject BitwardenSecret %s/%s: %w", namespace, name, err)
+		}
+
+		secretName, err := InjectedSecretName(bwSecret)
+		if err != nil {
+			return err
+		}
+		secretNames = append(secretNames, secretName)
+	}
+
+	injectorlog.V(1).Info("inject", "namespace", namespace, "pod", pod.GenerateName+pod.Name, "secrets", secretNames)
+
+	containers := splitList(pod.Annotations[InjectContainersAnnotation])
+	selected := func(container *corev1.Container) bool {
+		return len(containers) == 0 || contains(containers, container.Name)
+	}
+
+	switch mode := pod.Annotations[InjectModeAnnotation]; mode {
+	case "", InjectModeEnv:
+		InjectEnv(pod, secretNames, splitList(pod.Annotations[InjectKeysAnnotation]), selected)
+		return nil
+	case InjectModeFiles:
+		if i.Image == "" {
+			return fmt.Errorf("file injection is disabled, start the operator with --injector-image to enable it")
+		}
+		return InjectFiles(pod, i.Image, secretNames, selected)
+	default:
+		return fmt.Errorf("invalid %s annotation %q, expected %q or %q", InjectModeAnnotation, mode, InjectModeEnv, InjectModeFiles)
+	}
+}
+
+// InjectEnv adds environment variables referencing the secrets to the containers accepted by selected: the listed
+// keys, or every key of the secrets when keys is empty.
+func InjectEnv(pod *corev1.Pod, secretNames []string, keys []string, selected func(*corev1.Container) bool) {
+	for c := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[c]
+		if !selected(container) {
+			continue
+		}
+
+		for _, secretName := range secretNames {
+			if len(keys) > 0 {
+				InjectKeys(container, secretName, keys)
+			} else {
+				InjectEnvFrom(container, secretName)
+			}
+		}
+	}
+}
+
+// InjectedSecretName returns the name of the Kubernetes secret holding the values of the BitwardenSecret.  Pods of
+// versioned BitwardenSecrets are pinned to the version that is active when they are created.
+func InjectedSecretName(bwSecret *operatorsv1.BitwardenSecret) (string, error) {
+	if bwSecret.Spec.Versioning == nil {
+		return bwSecret.Spec.SecretName, nil
+	}
+
+	if bwSecret.Status.CurrentVersion == "" {
+		return "", fmt.Errorf("BitwardenSecret %s/%s has not written a version yet", bwSecret.Namespace, bwSecret.Name)
+	}
+
+	return bwSecret.Status.CurrentVersion, nil
+}
+
+// InjectEnvFrom adds an envFrom entry for the whole secret, unless the container already has one.
+func InjectEnvFrom(container *corev1.Container, secretName string) {
+	for _, source := range container.EnvFrom {
+		if source.SecretRef != nil && source.SecretRef.Name == secretName {
+			return
+		}
+	}
+
+	container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{
+		SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secretName}},
+	})
+}
+
+// InjectKeys adds a variable named after each key that references the key of the secret.  Variables the container
+// already sets are left alone.
+func InjectKeys(container *corev1.Container, secretName string, keys []string) {
+	for _, key := range keys {
+		if hasEnv(container, key) {
+			continue
+		}
+
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name: key,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			}},
+		})
+	}
+}
+
+func hasEnv(container *corev1.Container, name string) bool {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func contains(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+
+	return false
+}
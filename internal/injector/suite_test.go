@@ -0,0 +1,270 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package injector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+func TestInjector(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Injector Suite")
+}
+
+var _ = Describe("Pod injector", func() {
+	var injector *PodInjector
+	var ctx context.Context
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).Should(Succeed())
+		Expect(operatorsv1.AddToScheme(scheme)).Should(Succeed())
+
+		injector = &PodInjector{Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&operatorsv1.BitwardenSecret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "db"},
+				Spec:       operatorsv1.BitwardenSecretSpec{SecretName: "db-secrets"},
+			},
+			&operatorsv1.BitwardenSecret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "versioned"},
+				Spec:       operatorsv1.BitwardenSecretSpec{SecretName: "api-secrets", Versioning: &operatorsv1.SecretVersioning{}},
+				Status:     operatorsv1.BitwardenSecretStatus{CurrentVersion: "api-secrets-0123456789"},
+			},
+		).Build()}
+
+		// Pods created by controllers only carry their namespace on the admission request
+		ctx = admission.NewContextWithRequest(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Namespace: "apps"}})
+	})
+
+	newPod := func(annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "app-", Labels: map[string]string{InjectionLabel: InjectionEnabled}, Annotations: annotations},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app", EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}}}},
+				{Name: "sidecar"},
+			}},
+		}
+	}
+
+	It("Leaves pods without the annotation alone", func() {
+		pod := newPod(nil)
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+		Expect(pod.Spec.Containers[0].EnvFrom).Should(HaveLen(1))
+		Expect(pod.Spec.Containers[1].EnvFrom).Should(BeEmpty())
+	})
+
+	It("Leaves pods that did not opt in alone", func() {
+		pod := newPod(map[string]string{InjectAnnotation: "missing"})
+		delete(pod.Labels, InjectionLabel)
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+		Expect(pod.Spec.Containers[1].EnvFrom).Should(BeEmpty())
+	})
+
+	It("Injects the secret into every container once", func() {
+		pod := newPod(map[string]string{InjectAnnotation: "db"})
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+
+		for _, container := range pod.Spec.Containers {
+			Expect(container.EnvFrom).Should(ContainElement(corev1.EnvFromSource{
+				SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db-secrets"}},
+			}))
+		}
+		Expect(pod.Spec.Containers[0].EnvFrom).Should(HaveLen(2))
+		Expect(pod.Spec.Containers[1].EnvFrom).Should(HaveLen(1))
+	})
+
+	It("Injects single keys into the selected containers", func() {
+		pod := newPod(map[string]string{
+			InjectAnnotation:           "db",
+			InjectContainersAnnotation: "sidecar",
+			InjectKeysAnnotation:       "DB_PASSWORD, DB_USER",
+		})
+		pod.Spec.Containers[1].Env = []corev1.EnvVar{{Name: "DB_USER", Value: "readonly"}}
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+
+		Expect(pod.Spec.Containers[0].Env).Should(BeEmpty())
+		Expect(pod.Spec.Containers[1].EnvFrom).Should(BeEmpty())
+		Expect(pod.Spec.Containers[1].Env).Should(Equal([]corev1.EnvVar{
+			{Name: "DB_USER", Value: "readonly"},
+			{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "db-secrets"},
+				Key:                  "DB_PASSWORD",
+			}}},
+		}))
+	})
+
+	It("Pins pods of versioned BitwardenSecrets to the active version", func() {
+		pod := newPod(map[string]string{InjectAnnotation: "db,versioned"})
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+		Expect(pod.Spec.Containers[1].EnvFrom).Should(HaveLen(2))
+		Expect(pod.Spec.Containers[1].EnvFrom[1].SecretRef.Name).Should(Equal("api-secrets-0123456789"))
+
+		_, err := InjectedSecretName(&operatorsv1.BitwardenSecret{Spec: operatorsv1.BitwardenSecretSpec{Versioning: &operatorsv1.SecretVersioning{}}})
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("Rejects pods naming a missing BitwardenSecret", func() {
+		pod := newPod(map[string]string{InjectAnnotation: "missing"})
+		err := injector.Default(ctx, pod)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("apps/missing"))
+	})
+})
+
+var _ = Describe("File injection", func() {
+	var injector *PodInjector
+	var ctx context.Context
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).Should(Succeed())
+		Expect(operatorsv1.AddToScheme(scheme)).Should(Succeed())
+
+		injector = &PodInjector{Image: "sm-operator:test", Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&operatorsv1.BitwardenSecret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "tls"},
+				Spec:       operatorsv1.BitwardenSecretSpec{SecretName: "tls-secrets"},
+			},
+		).Build()}
+		ctx = admission.NewContextWithRequest(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Namespace: "apps"}})
+	})
+
+	newPod := func(annotations map[string]string) *corev1.Pod {
+		annotations[InjectAnnotation] = "tls"
+		annotations[InjectModeAnnotation] = InjectModeFiles
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{InjectionLabel: InjectionEnabled}, Annotations: annotations},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "migrate"}},
+				Containers:     []corev1.Container{{Name: "app"}},
+			},
+		}
+	}
+
+	It("Adds an init container rendering the files to a shared volume", func() {
+		pod := newPod(map[string]string{
+			InjectPathAnnotation:     "/etc/tls",
+			InjectFileNameAnnotation: "{{ .Key | lower }}.pem",
+			InjectFileModeAnnotation: "0440",
+			InjectTmpfsAnnotation:    "true",
+		})
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+
+		Expect(pod.Spec.InitContainers).Should(HaveLen(2))
+		render := pod.Spec.InitContainers[0]
+		Expect(render.Name).Should(Equal(RenderContainerName))
+		Expect(render.Image).Should(Equal("sm-operator:test"))
+		Expect(render.Args).Should(Equal([]string{
+			"render-files",
+			"--target=" + DefaultInjectPath,
+			"--file-name={{ .Key | lower }}.pem",
+			"--file-mode=0440",
+			"--source=/bitwarden/source/0",
+		}))
+
+		Expect(pod.Spec.Volumes).Should(HaveLen(2))
+		Expect(pod.Spec.Volumes[0].EmptyDir.Medium).Should(Equal(corev1.StorageMediumMemory))
+		Expect(pod.Spec.Volumes[1].Secret.SecretName).Should(Equal("tls-secrets"))
+		Expect(pod.Spec.Containers[0].VolumeMounts).Should(Equal([]corev1.VolumeMount{{Name: "bitwarden-secrets", MountPath: "/etc/tls", ReadOnly: true}}))
+	})
+
+	It("Renders the files as the user of the selected containers", func() {
+		imageUser, appUser, appGroup := int64(65532), int64(1001), int64(2001)
+		pod := newPod(map[string]string{})
+		pod.Spec.SecurityContext = &corev1.PodSecurityContext{RunAsUser: &imageUser, RunAsGroup: &appGroup}
+		pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{RunAsUser: &appUser}
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+
+		render := pod.Spec.InitContainers[0]
+		Expect(*render.SecurityContext.RunAsUser).Should(Equal(appUser))
+		Expect(*render.SecurityContext.RunAsGroup).Should(Equal(appGroup))
+		Expect(render.Args).Should(ContainElement("--file-mode=0400"))
+	})
+
+	It("Shares the files through the fsGroup with containers running as different users", func() {
+		appUser, sidecarUser := int64(1001), int64(1002)
+		pod := newPod(map[string]string{})
+		pod.Spec.Containers = []corev1.Container{
+			{Name: "app", SecurityContext: &corev1.SecurityContext{RunAsUser: &appUser}},
+			{Name: "sidecar", SecurityContext: &corev1.SecurityContext{RunAsUser: &sidecarUser}},
+		}
+		Expect(injector.Default(ctx, pod)).Should(Succeed())
+
+		render := pod.Spec.InitContainers[0]
+		Expect(render.SecurityContext.RunAsUser).Should(BeNil())
+		Expect(render.Args).Should(ContainElement("--file-mode=0440"))
+	})
+
+	It("Rejects invalid file settings", func() {
+		Expect(injector.Default(ctx, newPod(map[string]string{InjectFileModeAnnotation: "rw"}))).ShouldNot(Succeed())
+		Expect(injector.Default(ctx, newPod(map[string]string{InjectFileNameAnnotation: "{{ .Key"}))).ShouldNot(Succeed())
+		Expect(injector.Default(ctx, newPod(map[string]string{InjectPathAnnotation: "secrets"}))).ShouldNot(Succeed())
+
+		injector.Image = ""
+		Expect(injector.Default(ctx, newPod(map[string]string{}))).ShouldNot(Succeed())
+	})
+
+	It("Renders the keys of mounted secrets to files", func() {
+		// Secret volumes link their keys to a hidden data directory
+		source := GinkgoT().TempDir()
+		Expect(os.Mkdir(filepath.Join(source, "..data"), 0755)).Should(Succeed())
+		Expect(os.WriteFile(filepath.Join(source, "..data", "TLS_CERT"), []byte("cert"), 0644)).Should(Succeed())
+		Expect(os.Symlink(filepath.Join("..data", "TLS_CERT"), filepath.Join(source, "TLS_CERT"))).Should(Succeed())
+		Expect(os.WriteFile(filepath.Join(source, "TLS_KEY"), []byte("key"), 0644)).Should(Succeed())
+		target := GinkgoT().TempDir()
+
+		args := []string{"--source=" + source, "--target=" + target, "--file-name={{ .Key | lower | replace \"_\" \".\" }}", "--file-mode=0440"}
+		Expect(RunRenderFiles(args)).Should(Succeed())
+		// A restarted init container replaces its read only files
+		Expect(RunRenderFiles(args)).Should(Succeed())
+
+		cert, err := os.ReadFile(filepath.Join(target, "tls.cert"))
+		Expect(err).Should(BeNil())
+		Expect(string(cert)).Should(Equal("cert"))
+		info, err := os.Stat(filepath.Join(target, "tls.key"))
+		Expect(err).Should(BeNil())
+		Expect(info.Mode().Perm()).Should(Equal(os.FileMode(0440)))
+		_, err = os.Stat(filepath.Join(target, "..data"))
+		Expect(os.IsNotExist(err)).Should(BeTrue())
+
+		Expect(RenderFiles(FileOptions{Sources: []string{source}, Target: target, Keys: []string{"MISSING"}})).ShouldNot(Succeed())
+		Expect(RenderFiles(FileOptions{Sources: []string{source}, Target: target, FileName: "../{{ .Key }}"})).ShouldNot(Succeed())
+	})
+})
@@ -0,0 +1,184 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+// Package logging changes the log level of the running operator from a ConfigMap, so that debug logs can be switched
+// on during an incident without restarting the operator and losing the state being investigated.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var log = ctrl.Log.WithName("logging")
+
+// Keys of the log level ConfigMap
+const (
+	// The log level: debug, info, error, or a positive verbosity such as 2
+	LevelKey = "level"
+	// Optional duration, such as 30m, after which the startup level is restored
+	DurationKey = "duration"
+)
+
+// DynamicLevel makes the level of the logger built from opts changeable at runtime and returns it.  It keeps the
+// level set with --zap-log-level, and otherwise the default of the development or production mode.
+func DynamicLevel(opts *crzap.Options) zap.AtomicLevel {
+	if level, ok := opts.Level.(zap.AtomicLevel); ok {
+		return level
+	}
+
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	if opts.Development {
+		level.SetLevel(zapcore.DebugLevel)
+	}
+	opts.Level = level
+	return level
+}
+
+// LevelWatcher applies the log level of a ConfigMap to Level.  Without the ConfigMap, or once its duration has passed,
+// the level the operator was started with applies.  It implements manager.Runnable.
+type LevelWatcher struct {
+	// Should not be cached, so that the ConfigMap can live in a namespace the operator does not watch
+	Reader    client.Reader
+	ConfigMap types.NamespacedName
+	Level     zap.AtomicLevel
+	// Level restored when the ConfigMap is removed or expires
+	Default zapcore.Level
+	// Time between reads of the ConfigMap
+	Interval time.Duration
+
+	// Version of the ConfigMap last applied and when it was first seen, which starts its duration
+	version string
+	seenAt  time.Time
+}
+
+func (w *LevelWatcher) Start(ctx context.Context) error {
+	if w.Interval <= 0 {
+		return fmt.Errorf("log level check interval must be positive, got %s", w.Interval)
+	}
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Check(ctx, time.Now()); err != nil {
+			log.Error(err, "Failed to read the log level", "configMap", w.ConfigMap.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection changes the level of every replica, not only of the leader.
+func (w *LevelWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Check reads the ConfigMap and applies the level that is in effect at now.  An invalid ConfigMap leaves the level
+// unchanged.
+func (w *LevelWatcher) Check(ctx context.Context, now time.Time) error {
+	configMap := &corev1.ConfigMap{}
+	err := w.Reader.Get(ctx, w.ConfigMap, configMap)
+	if errors.IsNotFound(err) {
+		w.version = ""
+		w.apply(w.Default)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	level, err := ParseLevel(configMap.Data[LevelKey])
+	if err != nil {
+		return err
+	}
+
+	if configMap.ResourceVersion != w.version {
+		w.version = configMap.ResourceVersion
+		w.seenAt = now
+	}
+
+	if value, ok := configMap.Data[DurationKey]; ok {
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", DurationKey, value, err)
+		}
+
+		if now.After(w.seenAt.Add(duration)) {
+			level = w.Default
+		}
+	}
+
+	w.apply(level)
+	return nil
+}
+
+func (w *LevelWatcher) apply(level zapcore.Level) {
+	if w.Level.Level() == level {
+		return
+	}
+
+	w.Level.SetLevel(level)
+	log.Info("Changed the log level", "level", levelName(level))
+}
+
+// ParseLevel parses a level the way --zap-log-level does: debug, info, error, or a positive verbosity of debug logs.
+func ParseLevel(value string) (zapcore.Level, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+
+	verbosity, err := strconv.Atoi(value)
+	if err != nil || verbosity <= 0 || verbosity > 127 {
+		return 0, fmt.Errorf("invalid log level %q, expected debug, info, error, or a positive verbosity", value)
+	}
+
+	return zapcore.Level(-verbosity), nil
+}
+
+func levelName(level zapcore.Level) string {
+	if level < zapcore.DebugLevel {
+		return strconv.Itoa(-int(level))
+	}
+
+	return level.String()
+}
@@ -0,0 +1,110 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package logging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestLogging(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Logging Suite")
+}
+
+var _ = Describe("Log level", func() {
+	key := types.NamespacedName{Namespace: "sm-operator-system", Name: "sm-operator-log-level"}
+
+	It("Parses the levels of --zap-log-level", func() {
+		for value, expected := range map[string]zapcore.Level{"debug": zapcore.DebugLevel, " Info ": zapcore.InfoLevel, "error": zapcore.ErrorLevel, "3": zapcore.Level(-3)} {
+			level, err := ParseLevel(value)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(level).Should(Equal(expected))
+		}
+
+		for _, value := range []string{"", "verbose", "0", "-2"} {
+			_, err := ParseLevel(value)
+			Expect(err).Should(HaveOccurred())
+		}
+	})
+
+	It("Keeps the level of the flags", func() {
+		flagLevel := zap.NewAtomicLevelAt(zapcore.ErrorLevel)
+		opts := &crzap.Options{Level: flagLevel}
+		Expect(DynamicLevel(opts)).Should(Equal(flagLevel))
+
+		opts = &crzap.Options{Development: true}
+		level := DynamicLevel(opts)
+		Expect(level.Level()).Should(Equal(zapcore.DebugLevel))
+		Expect(opts.Level).Should(Equal(level))
+
+		Expect(DynamicLevel(&crzap.Options{}).Level()).Should(Equal(zapcore.InfoLevel))
+	})
+
+	It("Raises the level from the ConfigMap until its duration passes", func() {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Data:       map[string]string{LevelKey: "debug", DurationKey: "30m"},
+		}
+		fakeClient := fake.NewClientBuilder().Build()
+		watcher := &LevelWatcher{Reader: fakeClient, ConfigMap: key, Level: zap.NewAtomicLevelAt(zapcore.InfoLevel), Default: zapcore.InfoLevel, Interval: time.Second}
+		ctx := context.Background()
+		now := time.Now()
+
+		Expect(watcher.Check(ctx, now)).Should(Succeed())
+		Expect(watcher.Level.Level()).Should(Equal(zapcore.InfoLevel))
+
+		Expect(fakeClient.Create(ctx, configMap)).Should(Succeed())
+		Expect(watcher.Check(ctx, now)).Should(Succeed())
+		Expect(watcher.Level.Level()).Should(Equal(zapcore.DebugLevel))
+
+		Expect(watcher.Check(ctx, now.Add(31*time.Minute))).Should(Succeed())
+		Expect(watcher.Level.Level()).Should(Equal(zapcore.InfoLevel))
+
+		// Editing the ConfigMap starts its duration again
+		configMap.Data[LevelKey] = "2"
+		Expect(fakeClient.Update(ctx, configMap)).Should(Succeed())
+		Expect(watcher.Check(ctx, now.Add(32*time.Minute))).Should(Succeed())
+		Expect(watcher.Level.Level()).Should(Equal(zapcore.Level(-2)))
+
+		configMap.Data[LevelKey] = "loud"
+		Expect(fakeClient.Update(ctx, configMap)).Should(Succeed())
+		Expect(watcher.Check(ctx, now.Add(33*time.Minute))).ShouldNot(Succeed())
+		Expect(watcher.Level.Level()).Should(Equal(zapcore.Level(-2)))
+
+		Expect(fakeClient.Delete(ctx, configMap)).Should(Succeed())
+		Expect(watcher.Check(ctx, now.Add(34*time.Minute))).Should(Succeed())
+		Expect(watcher.Level.Level()).Should(Equal(zapcore.InfoLevel))
+	})
+})
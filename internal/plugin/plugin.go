@@ -0,0 +1,267 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+// Package plugin implements the kubectl bitwarden plugin, which lists BitwardenSecrets with their sync status,
+// triggers syncs, and shows the keys a BitwardenSecret manages.  It only talks to the Kubernetes API and does not
+// need the Secrets Manager SDK.
+package plugin
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+	"github.com/bitwarden/sm-kubernetes/internal/injector"
+)
+
+// The annotations and condition of the operator read and written by the plugin.  They are repeated here so that the
+// plugin does not link the controller and its native SDK.
+const (
+	forceSyncAnnotation   = "k8s.bitwarden.com/force-sync"
+	managedKeysAnnotation = "k8s.bitwarden.com/managed-keys"
+	readyCondition        = "Ready"
+)
+
+const Usage = `Usage: kubectl bitwarden <command> [flags]
+
+Commands:
+  list [-n namespace | -A]                List BitwardenSecrets with their sync status
+  sync NAME [-n namespace] [--wait=30s]   Trigger an immediate full sync of a BitwardenSecret
+  keys NAME [-n namespace]                Show the keys a BitwardenSecret manages in its Kubernetes secret
+`
+
+// Run runs the plugin command given by args.  Namespaced commands default to namespace.
+func Run(ctx context.Context, k8sClient client.Client, namespace string, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("a command is required\n\n%s", Usage)
+	}
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&namespace, "namespace", namespace, "Namespace of the BitwardenSecrets.")
+	flags.StringVar(&namespace, "n", namespace, "Namespace of the BitwardenSecrets.")
+
+	switch args[0] {
+	case "list":
+		allNamespaces := false
+		flags.BoolVar(&allNamespaces, "all-namespaces", false, "List the BitwardenSecrets of every namespace.")
+		flags.BoolVar(&allNamespaces, "A", false, "List the BitwardenSecrets of every namespace.")
+		if _, err := parse(flags, args[1:], 0); err != nil {
+			return err
+		}
+		if allNamespaces {
+			namespace = ""
+		}
+
+		return List(ctx, k8sClient, namespace, out)
+	case "sync":
+		waitFor := flags.Duration("wait", 0, "How long to wait for the sync to complete.  Zero returns right away.")
+		names, err := parse(flags, args[1:], 1)
+		if err != nil {
+			return err
+		}
+
+		return Sync(ctx, k8sClient, types.NamespacedName{Namespace: namespace, Name: names[0]}, *waitFor, out)
+	case "keys":
+		names, err := parse(flags, args[1:], 1)
+		if err != nil {
+			return err
+		}
+
+		return Keys(ctx, k8sClient, types.NamespacedName{Namespace: namespace, Name: names[0]}, out)
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], Usage)
+	}
+}
+
+// parse parses flags placed before or after the positional arguments, the way kubectl accepts them, and checks that
+// there are exactly count positional arguments.
+func parse(flags *flag.FlagSet, args []string, count int) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	if len(positional) != count {
+		return nil, fmt.Errorf("%s expects %d argument(s), got %d\n\n%s", flags.Name(), count, len(positional), Usage)
+	}
+
+	return positional, nil
+}
+
+// List writes a table of the BitwardenSecrets in namespace, or of every namespace if it is empty, with their sync
+// status.
+func List(ctx context.Context, k8sClient client.Reader, namespace string, out io.Writer) error {
+	bwSecrets := &operatorsv1.BitwardenSecretList{}
+	if err := k8sClient.List(ctx, bwSecrets, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	sort.Slice(bwSecrets.Items, func(i, j int) bool {
+		a, b := bwSecrets.Items[i], bwSecrets.Items[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tSECRET\tREADY\tKEYS\tLAST SYNC\tMESSAGE")
+	for _, bwSecret := range bwSecrets.Items {
+		ready, message := "Unknown", ""
+		if condition := apimeta.FindStatusCondition(bwSecret.Status.Conditions, readyCondition); condition != nil {
+			ready = string(condition.Status)
+			if condition.Status != "True" {
+				message = condition.Message
+			}
+		}
+		if bwSecret.Spec.Paused {
+			message = "Paused"
+		}
+
+		lastSync := "Never"
+		if !bwSecret.Status.LastSuccessfulSyncTime.IsZero() {
+			lastSync = duration.HumanDuration(time.Since(bwSecret.Status.LastSuccessfulSyncTime.Time)) + " ago"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", bwSecret.Namespace, bwSecret.Name, bwSecret.Spec.SecretName, ready,
+			bwSecret.Status.SyncedKeyCount, lastSync, message)
+	}
+
+	return w.Flush()
+}
+
+// Sync triggers an immediate full sync of the BitwardenSecret by setting its force-sync annotation.  With a non-zero
+// timeout it waits until the operator reports that the sync completed.
+func Sync(ctx context.Context, k8sClient client.Client, name types.NamespacedName, timeout time.Duration, out io.Writer) error {
+	bwSecret := &operatorsv1.BitwardenSecret{}
+	if err := k8sClient.Get(ctx, name, bwSecret); err != nil {
+		return err
+	}
+
+	value := time.Now().UTC().Format(time.RFC3339Nano)
+	patch := client.MergeFrom(bwSecret.DeepCopy())
+	if bwSecret.Annotations == nil {
+		bwSecret.Annotations = map[string]string{}
+	}
+	bwSecret.Annotations[forceSyncAnnotation] = value
+	if err := k8sClient.Patch(ctx, bwSecret, patch); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "bitwardensecret/%s sync requested\n", name.Name)
+
+	if timeout <= 0 {
+		return nil
+	}
+
+	err := wait.PollUntilContextTimeout(ctx, time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		if err := k8sClient.Get(ctx, name, bwSecret); err != nil {
+			return false, err
+		}
+		return bwSecret.Status.LastForceSync == value, nil
+	})
+	if err != nil {
+		if condition := apimeta.FindStatusCondition(bwSecret.Status.Conditions, readyCondition); condition != nil && condition.Status != "True" {
+			return fmt.Errorf("bitwardensecret/%s did not sync within %s: %s", name.Name, timeout, condition.Message)
+		}
+		return fmt.Errorf("bitwardensecret/%s did not sync within %s: %w", name.Name, timeout, err)
+	}
+	fmt.Fprintf(out, "bitwardensecret/%s synced\n", name.Name)
+
+	return nil
+}
+
+// Keys writes a table of the keys the BitwardenSecret manages in its Kubernetes secret and the Secrets Manager
+// secrets they are mapped from.  Keys not mapped by the applied map, such as those of templates or of a BitwardenSecret
+// without a map, are shown without a source secret.  Values are never read.
+func Keys(ctx context.Context, k8sClient client.Reader, name types.NamespacedName, out io.Writer) error {
+	bwSecret := &operatorsv1.BitwardenSecret{}
+	if err := k8sClient.Get(ctx, name, bwSecret); err != nil {
+		return err
+	}
+
+	secretName, err := injector.InjectedSecretName(bwSecret)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: name.Namespace, Name: secretName}, secret); err != nil {
+		return err
+	}
+
+	keys := []string{}
+	if bwSecret.Spec.CreationPolicy == operatorsv1.CreationPolicyMerge {
+		// Keys written by anyone else are not managed by the BitwardenSecret
+		if value := secret.Annotations[managedKeysAnnotation]; value != "" {
+			keys = strings.Split(value, ",")
+		}
+	} else {
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	sources := map[string]string{}
+	for _, entry := range bwSecret.Status.AppliedSecretMap {
+		sources[entry.SecretKeyName] = entry.BwSecretId
+		for _, alias := range entry.Aliases {
+			sources[alias] = entry.BwSecretId
+		}
+	}
+	if bwSecret.Spec.Template != nil {
+		for key := range bwSecret.Spec.Template.Data {
+			sources[key] = "(template)"
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "KEY\tSOURCE\n")
+	for _, key := range keys {
+		source, ok := sources[key]
+		if !ok {
+			source = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", key, source)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,146 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+func TestPlugin(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Plugin Suite")
+}
+
+func newFakeClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).Should(Succeed())
+	Expect(operatorsv1.AddToScheme(scheme)).Should(Succeed())
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&operatorsv1.BitwardenSecret{}).WithObjects(objects...).Build()
+}
+
+var _ = Describe("kubectl plugin", func() {
+	It("Lists BitwardenSecrets with their sync status", func() {
+		k8sClient := newFakeClient(
+			&operatorsv1.BitwardenSecret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"},
+				Spec:       operatorsv1.BitwardenSecretSpec{SecretName: "app-secrets"},
+				Status: operatorsv1.BitwardenSecretStatus{
+					LastSuccessfulSyncTime: metav1.NewTime(time.Now().Add(-5 * time.Minute)),
+					SyncedKeyCount:         3,
+					Conditions:             []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "ReconciliationComplete"}},
+				},
+			},
+			&operatorsv1.BitwardenSecret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "db"},
+				Spec:       operatorsv1.BitwardenSecretSpec{SecretName: "db-secrets"},
+				Status: operatorsv1.BitwardenSecretStatus{
+					Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionFalse, Reason: "ReconciliationFailed", Message: "unauthorized"}},
+				},
+			},
+		)
+
+		out := &bytes.Buffer{}
+		Expect(Run(context.Background(), k8sClient, "default", []string{"list", "-A"}, out)).Should(Succeed())
+		lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+		Expect(lines).Should(HaveLen(3))
+		Expect(string(lines[0])).Should(MatchRegexp(`^NAMESPACE\s+NAME\s+SECRET\s+READY\s+KEYS\s+LAST SYNC\s+MESSAGE$`))
+		Expect(string(lines[1])).Should(MatchRegexp(`^default\s+app\s+app-secrets\s+True\s+3\s+5m ago\s*$`))
+		Expect(string(lines[2])).Should(MatchRegexp(`^payments\s+db\s+db-secrets\s+False\s+0\s+Never\s+unauthorized$`))
+
+		out.Reset()
+		Expect(Run(context.Background(), k8sClient, "default", []string{"list", "--namespace", "payments"}, out)).Should(Succeed())
+		Expect(out.String()).ShouldNot(ContainSubstring("app-secrets"))
+		Expect(out.String()).Should(ContainSubstring("db-secrets"))
+	})
+
+	It("Triggers a sync", func() {
+		k8sClient := newFakeClient(&operatorsv1.BitwardenSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "db"}})
+
+		out := &bytes.Buffer{}
+		Expect(Run(context.Background(), k8sClient, "default", []string{"sync", "db", "-n", "payments"}, out)).Should(Succeed())
+		Expect(out.String()).Should(Equal("bitwardensecret/db sync requested\n"))
+
+		bwSecret := &operatorsv1.BitwardenSecret{}
+		Expect(k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "payments", Name: "db"}, bwSecret)).Should(Succeed())
+		Expect(bwSecret.Annotations[forceSyncAnnotation]).ShouldNot(BeEmpty())
+	})
+
+	It("Reports a sync that does not complete in time", func() {
+		k8sClient := newFakeClient(&operatorsv1.BitwardenSecret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"},
+			Status: operatorsv1.BitwardenSecretStatus{
+				Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionFalse, Reason: "ReconciliationFailed", Message: "unauthorized"}},
+			},
+		})
+
+		err := Sync(context.Background(), k8sClient, types.NamespacedName{Namespace: "default", Name: "app"}, time.Millisecond, &bytes.Buffer{})
+		Expect(err).Should(MatchError(ContainSubstring("did not sync within 1ms: unauthorized")))
+	})
+
+	It("Shows the keys a BitwardenSecret manages", func() {
+		k8sClient := newFakeClient(
+			&operatorsv1.BitwardenSecret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"},
+				Spec: operatorsv1.BitwardenSecretSpec{
+					SecretName:     "app-secrets",
+					CreationPolicy: operatorsv1.CreationPolicyMerge,
+					Template:       &operatorsv1.SecretTemplate{Data: map[string]string{"DSN": "postgres://{{ .Data.PASSWORD }}@db"}},
+				},
+				Status: operatorsv1.BitwardenSecretStatus{
+					AppliedSecretMap: []operatorsv1.SecretMap{{BwSecretId: "a", SecretKeyName: "PASSWORD", Aliases: []string{"DB_PASSWORD"}}},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-secrets", Annotations: map[string]string{managedKeysAnnotation: "DB_PASSWORD,DSN,PASSWORD,other"}},
+				Data:       map[string][]byte{"PASSWORD": []byte("p"), "DB_PASSWORD": []byte("p"), "DSN": []byte("d"), "other": []byte("o"), "unmanaged": []byte("u")},
+			},
+		)
+
+		out := &bytes.Buffer{}
+		Expect(Run(context.Background(), k8sClient, "default", []string{"keys", "app"}, out)).Should(Succeed())
+		Expect(out.String()).Should(Equal("KEY           SOURCE\nDB_PASSWORD   a\nDSN           (template)\nPASSWORD      a\nother         -\n"))
+	})
+
+	It("Rejects unknown commands and missing arguments", func() {
+		k8sClient := newFakeClient()
+
+		Expect(Run(context.Background(), k8sClient, "default", []string{"rotate"}, &bytes.Buffer{})).Should(MatchError(ContainSubstring(`unknown command "rotate"`)))
+		Expect(Run(context.Background(), k8sClient, "default", []string{"sync"}, &bytes.Buffer{})).Should(MatchError(ContainSubstring("sync expects 1 argument(s), got 0")))
+		Expect(Run(context.Background(), k8sClient, "default", []string{"keys", "app", "other"}, &bytes.Buffer{})).Should(MatchError(ContainSubstring("keys expects 1 argument(s), got 2")))
+	})
+})
@@ -0,0 +1,154 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+// Package profiling periodically captures heap and CPU profiles to disk, so that memory growth can be diagnosed in
+// production without a custom build or an exposed pprof endpoint.
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var log = ctrl.Log.WithName("profiling")
+
+// Capturer writes a heap profile and a CPU profile to Dir every Interval.  It implements manager.Runnable.
+type Capturer struct {
+	// Directory the profiles are written to, typically a mounted volume
+	Dir string
+	// Time between captures
+	Interval time.Duration
+	// How long each CPU profile samples for.  Zero disables CPU profiles.
+	CPUDuration time.Duration
+	// Number of captures of each kind to keep.  Zero keeps all of them.
+	Keep int
+}
+
+func (c *Capturer) Start(ctx context.Context) error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("profile capture interval must be positive, got %s", c.Interval)
+	}
+
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.Capture(ctx); err != nil {
+				log.Error(err, "Failed to capture profiles", "dir", c.Dir)
+			}
+		}
+	}
+}
+
+// Capture writes one heap profile and, if enabled, one CPU profile, then removes the oldest captures.
+func (c *Capturer) Capture(ctx context.Context) error {
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	if err := c.writeHeapProfile(filepath.Join(c.Dir, fmt.Sprintf("heap-%s.pprof", stamp))); err != nil {
+		return err
+	}
+
+	if c.CPUDuration > 0 {
+		if err := c.writeCPUProfile(ctx, filepath.Join(c.Dir, fmt.Sprintf("cpu-%s.pprof", stamp))); err != nil {
+			return err
+		}
+	}
+
+	return c.prune()
+}
+
+func (c *Capturer) writeHeapProfile(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	runtime.GC()
+	return pprof.WriteHeapProfile(file)
+}
+
+func (c *Capturer) writeCPUProfile(ctx context.Context, path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Fails if a CPU profile is already running, for example one requested through the pprof endpoint
+	if err := pprof.StartCPUProfile(file); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(c.CPUDuration):
+	}
+	pprof.StopCPUProfile()
+
+	return nil
+}
+
+func (c *Capturer) prune() error {
+	if c.Keep <= 0 {
+		return nil
+	}
+
+	for _, kind := range []string{"heap-", "cpu-"} {
+		entries, err := os.ReadDir(c.Dir)
+		if err != nil {
+			return err
+		}
+
+		names := []string{}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), kind) && strings.HasSuffix(entry.Name(), ".pprof") {
+				names = append(names, entry.Name())
+			}
+		}
+
+		// Timestamps sort chronologically
+		sort.Strings(names)
+		for i := 0; i < len(names)-c.Keep; i++ {
+			if err := os.Remove(filepath.Join(c.Dir, names[i])); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
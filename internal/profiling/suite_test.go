@@ -0,0 +1,75 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package profiling
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestProfiling(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Profiling Suite")
+}
+
+var _ = Describe("Profile capture", func() {
+	It("Writes heap and CPU profiles", func() {
+		dir := GinkgoT().TempDir()
+		capturer := &Capturer{Dir: dir, Interval: time.Minute, CPUDuration: 10 * time.Millisecond}
+
+		Expect(capturer.Capture(context.Background())).Should(Succeed())
+
+		heap, err := filepath.Glob(filepath.Join(dir, "heap-*.pprof"))
+		Expect(err).Should(BeNil())
+		Expect(heap).Should(HaveLen(1))
+		cpu, err := filepath.Glob(filepath.Join(dir, "cpu-*.pprof"))
+		Expect(err).Should(BeNil())
+		Expect(cpu).Should(HaveLen(1))
+	})
+
+	It("Keeps only the newest captures", func() {
+		dir := GinkgoT().TempDir()
+		for _, name := range []string{"heap-20240101T000000Z.pprof", "heap-20240102T000000Z.pprof", "unrelated.txt"} {
+			Expect(os.WriteFile(filepath.Join(dir, name), []byte{}, 0600)).Should(Succeed())
+		}
+
+		capturer := &Capturer{Dir: dir, Interval: time.Minute, Keep: 2}
+		Expect(capturer.Capture(context.Background())).Should(Succeed())
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).Should(BeNil())
+		names := []string{}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		Expect(names).Should(HaveLen(3))
+		Expect(names).Should(ContainElement("unrelated.txt"))
+		Expect(names).Should(ContainElement("heap-20240102T000000Z.pprof"))
+		Expect(names).ShouldNot(ContainElement("heap-20240101T000000Z.pprof"))
+	})
+})
@@ -0,0 +1,85 @@
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTracing(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Tracing Suite")
+}
+
+var _ = Describe("Tracing", func() {
+	var recorder *tracetest.SpanRecorder
+
+	BeforeEach(func() {
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	})
+
+	It("Records a span for every Kubernetes write", func() {
+		k8sClient := WrapClient(fake.NewClientBuilder().WithScheme(scheme.Scheme).Build())
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-secrets"}}
+
+		ctx, parent := Start(context.Background(), "Reconcile")
+		Expect(k8sClient.Create(ctx, secret)).Should(Succeed())
+		Expect(k8sClient.Create(ctx, secret)).ShouldNot(Succeed())
+		parent.End()
+
+		spans := recorder.Ended()
+		Expect(spans).Should(HaveLen(3))
+		Expect(spans[0].Name()).Should(Equal("Create Secret"))
+		Expect(spans[0].Parent().SpanID()).Should(Equal(parent.SpanContext().SpanID()))
+		Expect(spans[0].Attributes()).Should(ContainElement(attribute.String("k8s.object.name", "app-secrets")))
+		Expect(spans[0].Status().Code).Should(Equal(codes.Unset))
+		Expect(spans[1].Status().Code).Should(Equal(codes.Error))
+	})
+
+	It("Marks the span of the context as failed", func() {
+		ctx, span := Start(context.Background(), "Reconcile")
+		RecordFailure(ctx, errors.New("unauthorized"), "Failed to authenticate")
+		span.End()
+
+		spans := recorder.Ended()
+		Expect(spans).Should(HaveLen(1))
+		Expect(spans[0].Status().Code).Should(Equal(codes.Error))
+		Expect(spans[0].Status().Description).Should(Equal("Failed to authenticate"))
+		Expect(spans[0].Events()).Should(HaveLen(1))
+	})
+})
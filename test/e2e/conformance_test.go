@@ -0,0 +1,142 @@
+//go:build e2e
+
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package e2e
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+var _ = Describe("Operator installation", func() {
+	It("Has the BitwardenSecret CRD installed", func() {
+		bwSecrets := &operatorsv1.BitwardenSecretList{}
+		Expect(k8sClient.List(ctx, bwSecrets)).To(Succeed(), "the BitwardenSecret CRD is not installed; run \"make install\"")
+	})
+
+	It("Has an available operator deployment", func() {
+		deployment := &appsv1.Deployment{}
+		err := k8sClient.Get(ctx, types.NamespacedName{Namespace: operatorNamespace, Name: operatorDeployment}, deployment)
+		Expect(err).NotTo(HaveOccurred(), "operator deployment %s/%s not found", operatorNamespace, operatorDeployment)
+		Expect(deployment.Status.AvailableReplicas).To(BeNumerically(">", 0), "operator deployment has no available replicas")
+	})
+})
+
+var _ = Describe("Operator RBAC", func() {
+	DescribeTable("Grants the operator service account the permissions it needs",
+		func(group string, resource string, verb string) {
+			review := &authorizationv1.SubjectAccessReview{
+				Spec: authorizationv1.SubjectAccessReviewSpec{
+					User: fmt.Sprintf("system:serviceaccount:%s:%s", operatorNamespace, operatorServiceAccount),
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Group:    group,
+						Resource: resource,
+						Verb:     verb,
+					},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, review)).To(Succeed())
+			Expect(review.Status.Allowed).To(BeTrue(), "%s on %s is denied: %s", verb, resource, review.Status.Reason)
+		},
+		Entry("watch BitwardenSecrets", "k8s.bitwarden.com", "bitwardensecrets", "watch"),
+		Entry("get BitwardenSecrets", "k8s.bitwarden.com", "bitwardensecrets", "get"),
+		Entry("update BitwardenSecret status", "k8s.bitwarden.com", "bitwardensecrets/status", "update"),
+		Entry("get secrets", "", "secrets", "get"),
+		Entry("create secrets", "", "secrets", "create"),
+		Entry("update secrets", "", "secrets", "update"),
+	)
+})
+
+var _ = Describe("Secret synchronization", Ordered, func() {
+	var namespace string
+
+	BeforeAll(func() {
+		namespace = fmt.Sprintf("bitwarden-e2e-%s", uuid.NewString()[:8])
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+	})
+
+	AfterAll(func() {
+		if namespace != "" {
+			Expect(k8sClient.Delete(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		}
+	})
+
+	It("Syncs secrets from Secrets Manager into a Kubernetes secret", func() {
+		authSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bw-auth-token", Namespace: namespace},
+			StringData: map[string]string{"token": accessToken},
+		}
+		Expect(k8sClient.Create(ctx, authSecret)).To(Succeed())
+
+		bwSecret := &operatorsv1.BitwardenSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "e2e", Namespace: namespace},
+			Spec: operatorsv1.BitwardenSecretSpec{
+				OrganizationId: organizationId,
+				SecretName:     "e2e-synced",
+				AuthToken: operatorsv1.AuthToken{
+					SecretName: authSecret.Name,
+					SecretKey:  "token",
+				},
+			},
+		}
+		if stub != nil {
+			bwSecret.Spec.ApiUrl = stub.URL + "/api"
+			bwSecret.Spec.IdentityUrl = stub.URL + "/identity"
+		}
+		Expect(k8sClient.Create(ctx, bwSecret)).To(Succeed())
+
+		By("waiting for the operator to report a successful sync")
+		Eventually(func(g Gomega) {
+			current := &operatorsv1.BitwardenSecret{}
+			g.Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: bwSecret.Name}, current)).To(Succeed())
+
+			lastFailure := "none"
+			if failed := apimeta.FindStatusCondition(current.Status.Conditions, "FailedSync"); failed != nil {
+				lastFailure = failed.Message
+			}
+			g.Expect(apimeta.IsStatusConditionTrue(current.Status.Conditions, "SuccessfulSync")).To(BeTrue(), "last failure: %s", lastFailure)
+		}, syncTimeout, "2s").Should(Succeed())
+
+		By("checking the synced secret")
+		synced := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: bwSecret.Spec.SecretName}, synced)).To(Succeed())
+		Expect(synced.Annotations).To(HaveKey("k8s.bitwarden.com/sync-time"))
+		Expect(synced.OwnerReferences).To(HaveLen(1))
+		Expect(synced.OwnerReferences[0].Name).To(Equal(bwSecret.Name))
+		if stub != nil {
+			Expect(synced.Data).To(HaveKeyWithValue(stub.SecretId, []byte(stub.SecretValue)))
+		}
+	})
+})
@@ -0,0 +1,126 @@
+//go:build e2e
+
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package e2e
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	operatorsv1 "github.com/bitwarden/sm-kubernetes/api/v1"
+)
+
+// The e2e conformance suite runs against the cluster in the current kubeconfig context and verifies that a deployed
+// operator works end to end.  It is excluded from normal builds by the e2e build tag; run it with "make test-e2e".
+//
+// Settings are read from the environment:
+//
+//	E2E_OPERATOR_NAMESPACE        namespace the operator is deployed to (default sm-operator-system)
+//	E2E_OPERATOR_DEPLOYMENT       name of the operator deployment (default sm-operator-controller-manager)
+//	E2E_OPERATOR_SERVICE_ACCOUNT  service account the operator runs as (default sm-operator-controller-manager)
+//	E2E_BW_ACCESS_TOKEN           machine account access token used for the sync checks
+//	E2E_BW_ORGANIZATION_ID        organization the machine account belongs to
+//	E2E_STUB_HOST                 host the operator reaches the stub backend at (default host.docker.internal)
+//	E2E_TIMEOUT                   how long to wait for the operator to sync (default 2m)
+//
+// The sync checks run against the Secrets Manager server the operator is configured for when both
+// E2E_BW_ACCESS_TOKEN and E2E_BW_ORGANIZATION_ID are set.  Otherwise they run against a stub backend started by the
+// suite, which the BitwardenSecrets point to with spec.apiUrl and spec.identityUrl.
+
+var k8sClient client.Client
+var ctx context.Context
+var cancel context.CancelFunc
+
+var operatorNamespace string
+var operatorDeployment string
+var operatorServiceAccount string
+var accessToken string
+var organizationId string
+var syncTimeout time.Duration
+
+// Stub backend of the sync checks, or nil when they run against a real server
+var stub *stubBackend
+
+func TestE2E(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Operator Conformance Suite")
+}
+
+var _ = BeforeSuite(func() {
+	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+
+	operatorNamespace = getEnv("E2E_OPERATOR_NAMESPACE", "sm-operator-system")
+	operatorDeployment = getEnv("E2E_OPERATOR_DEPLOYMENT", "sm-operator-controller-manager")
+	operatorServiceAccount = getEnv("E2E_OPERATOR_SERVICE_ACCOUNT", "sm-operator-controller-manager")
+	accessToken = os.Getenv("E2E_BW_ACCESS_TOKEN")
+	organizationId = os.Getenv("E2E_BW_ORGANIZATION_ID")
+
+	var err error
+	if accessToken == "" || organizationId == "" {
+		stub, err = newStubBackend(getEnv("E2E_STUB_HOST", "host.docker.internal"))
+		Expect(err).NotTo(HaveOccurred())
+		accessToken = stub.AccessToken
+		organizationId = stub.OrganizationId
+	}
+
+	syncTimeout, err = time.ParseDuration(getEnv("E2E_TIMEOUT", "2m"))
+	Expect(err).NotTo(HaveOccurred())
+
+	Expect(operatorsv1.AddToScheme(scheme.Scheme)).To(Succeed())
+
+	cfg, err := ctrl.GetConfig()
+	Expect(err).NotTo(HaveOccurred(), "no usable kubeconfig found")
+
+	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
+	Expect(err).NotTo(HaveOccurred())
+
+	ctx, cancel = context.WithCancel(context.Background())
+})
+
+var _ = AfterSuite(func() {
+	if cancel != nil {
+		cancel()
+	}
+	if stub != nil {
+		stub.Close()
+	}
+})
+
+func getEnv(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
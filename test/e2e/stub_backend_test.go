@@ -0,0 +1,183 @@
+//go:build e2e
+
+/*
+Source code in this repository is covered by one of two licenses: (i) the
+GNU General Public License (GPL) v3.0 (ii) the Bitwarden License v1.0. The
+default license throughout the repository is GPL v3.0 unless the header
+specifies another license. Bitwarden Licensed code is found only in the
+/bitwarden_license directory.
+
+GPL v3.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_GPL.txt
+
+Bitwarden License v1.0:
+https://github.com/bitwarden/server/blob/main/LICENSE_BITWARDEN.txt
+
+No grant of any rights in the trademarks, service marks, or logos of Bitwarden is
+made (except as may be necessary to comply with the notice requirements as
+applicable), and use of any Bitwarden trademarks must comply with Bitwarden
+Trademark Guidelines
+<https://github.com/bitwarden/server/blob/main/TRADEMARK_GUIDELINES.md>.
+*/
+
+package e2e
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/google/uuid"
+)
+
+// stubBackend is an in-process Secrets Manager server with one machine account, organization, and secret.  The sync
+// checks run against it unless E2E_BW_ACCESS_TOKEN and E2E_BW_ORGANIZATION_ID name a real machine account.  The
+// operator reaches it through spec.apiUrl and spec.identityUrl of the BitwardenSecrets, so it listens on all
+// interfaces and is advertised under E2E_STUB_HOST.
+type stubBackend struct {
+	server *httptest.Server
+	// Base URL of the stub as seen from the operator
+	URL            string
+	AccessToken    string
+	OrganizationId string
+	SecretId       string
+	SecretValue    string
+}
+
+// newStubBackend starts a stub backend advertised to the operator under host.
+func newStubBackend(host string) (*stubBackend, error) {
+	seed := make([]byte, 16)
+	rawOrgKey := make([]byte, 64)
+	for _, key := range [][]byte{seed, rawOrgKey} {
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	clientId := uuid.NewString()
+	bearer := uuid.NewString()
+	tokenKey := deriveShareableKey(seed, "accesstoken", "sm-access-token")
+
+	stub := &stubBackend{
+		AccessToken:    fmt.Sprintf("0.%s.client-secret:%s", clientId, base64.StdEncoding.EncodeToString(seed)),
+		OrganizationId: uuid.NewString(),
+		SecretId:       uuid.NewString(),
+		SecretValue:    uuid.NewString(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/identity/connect/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("client_id") != clientId || r.PostForm.Get("client_secret") != "client-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		payload, _ := json.Marshal(map[string]string{"encryptionKey": base64.StdEncoding.EncodeToString(rawOrgKey)})
+		writeJSON(w, map[string]interface{}{
+			"access_token":      bearer,
+			"expires_in":        3600,
+			"token_type":        "Bearer",
+			"encrypted_payload": encryptString(tokenKey, payload),
+		})
+	})
+	mux.HandleFunc(fmt.Sprintf("/api/organizations/%s/secrets/sync", stub.OrganizationId), func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+bearer {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"hasChanges": true,
+			"secrets": map[string]interface{}{
+				"data": []map[string]interface{}{{
+					"id":             stub.SecretId,
+					"organizationId": stub.OrganizationId,
+					"key":            encryptString(rawOrgKey, []byte("e2e-secret")),
+					"value":          encryptString(rawOrgKey, []byte(stub.SecretValue)),
+					"note":           encryptString(rawOrgKey, []byte("")),
+					"projects":       []map[string]string{},
+				}},
+			},
+		})
+	})
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, err
+	}
+
+	stub.server = httptest.NewUnstartedServer(mux)
+	stub.server.Listener.Close()
+	stub.server.Listener = listener
+	stub.server.Start()
+	stub.URL = fmt.Sprintf("http://%s", net.JoinHostPort(host, fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)))
+
+	return stub, nil
+}
+
+// Close stops the stub backend.
+func (s *stubBackend) Close() {
+	s.server.Close()
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// deriveShareableKey matches the key derivation of Bitwarden: HMAC-SHA256 keyed by "bitwarden-<name>" produces the
+// pseudo random key, which is expanded to an encryption and a MAC key with HKDF-SHA256.
+func deriveShareableKey(secret []byte, name string, info string) []byte {
+	mac := hmac.New(sha256.New, []byte("bitwarden-"+name))
+	mac.Write(secret)
+	prk := mac.Sum(nil)
+
+	out := []byte{}
+	var previous []byte
+	for counter := byte(1); len(out) < 64; counter++ {
+		mac := hmac.New(sha256.New, prk)
+		mac.Write(previous)
+		mac.Write([]byte(info))
+		mac.Write([]byte{counter})
+		previous = mac.Sum(nil)
+		out = append(out, previous...)
+	}
+
+	return out[:64]
+}
+
+// encryptString encrypts plain as a type 2 EncString (AES-256-CBC with HMAC-SHA256) under a 64 byte key.
+func encryptString(key []byte, plain []byte) string {
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		panic(err)
+	}
+
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	padded := append(append([]byte{}, plain...), make([]byte, padding)...)
+	for i := len(plain); i < len(padded); i++ {
+		padded[i] = byte(padding)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		panic(err)
+	}
+
+	data := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, padded)
+
+	mac := hmac.New(sha256.New, key[32:])
+	mac.Write(iv)
+	mac.Write(data)
+
+	return fmt.Sprintf("2.%s|%s|%s", base64.StdEncoding.EncodeToString(iv), base64.StdEncoding.EncodeToString(data), base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}